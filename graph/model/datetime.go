@@ -0,0 +1,57 @@
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/bamdadam/backend/src/apperror"
+)
+
+// DateTimeLayout is RFC 3339 with millisecond precision, the resolution dates
+// are stored with.
+const DateTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// MarshalDateTime emits a DateTime as an RFC 3339 timestamp in UTC.
+func MarshalDateTime(t time.Time) graphql.Marshaler {
+	return graphql.WriterFunc(func(w io.Writer) {
+		io.WriteString(w, strconv.Quote(FormatDateTime(t)))
+	})
+}
+
+// UnmarshalDateTime accepts an RFC 3339 timestamp or milliseconds since the
+// epoch, either as a number or a numeric string.
+func UnmarshalDateTime(v any) (time.Time, error) {
+	switch v := v.(type) {
+	case string:
+		return ParseDateTime(v)
+	case json.Number:
+		return ParseDateTime(v.String())
+	case int:
+		return time.UnixMilli(int64(v)), nil
+	case int64:
+		return time.UnixMilli(v), nil
+	case float64:
+		return time.UnixMilli(int64(v)), nil
+	default:
+		return time.Time{}, apperror.Validation("invalid DateTime: %v", v)
+	}
+}
+
+func FormatDateTime(t time.Time) string {
+	return t.UTC().Format(DateTimeLayout)
+}
+
+// ParseDateTime parses an RFC 3339 timestamp or milliseconds since the epoch.
+func ParseDateTime(s string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, apperror.Validation("invalid DateTime %q: expected RFC 3339 or epoch milliseconds", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"github.com/bamdadam/backend/src/apperror"
+)
+
+// NewFieldValue wraps a raw field value in the FieldValue member matching the
+// type of its field.
+func NewFieldValue(fieldType FieldType, value any) (FieldValue, error) {
+	switch fieldType {
+	case FieldTypeNumber:
+		if v, ok := value.(float64); ok {
+			return &NumberValue{Number: v}, nil
+		}
+	case FieldTypeDate:
+		if v, ok := value.(string); ok {
+			date, err := ParseDateTime(v)
+			if err != nil {
+				return nil, err
+			}
+			return &DateValue{Date: date}, nil
+		}
+	case FieldTypeBoolean:
+		if v, ok := value.(bool); ok {
+			return &BooleanValue{Boolean: v}, nil
+		}
+	case FieldTypeMultiSelect:
+		if v, ok := value.([]any); ok {
+			options := make([]string, 0, len(v))
+			for _, option := range v {
+				s, ok := option.(string)
+				if !ok {
+					return nil, apperror.Internal("invalid option %v in %s value", option, fieldType)
+				}
+				options = append(options, s)
+			}
+			return &MultiSelectValue{Options: options}, nil
+		}
+	default:
+		if v, ok := value.(string); ok {
+			switch fieldType {
+			case FieldTypeSelect:
+				return &SelectValue{Option: v}, nil
+			case FieldTypeURL:
+				return &URLValue{URL: v}, nil
+			case FieldTypeEmail:
+				return &EmailValue{Email: v}, nil
+			default:
+				return &TextValue{Text: v}, nil
+			}
+		}
+	}
+
+	return nil, apperror.Internal("value %v does not match field type %s", value, fieldType)
+}
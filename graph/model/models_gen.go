@@ -7,16 +7,111 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
 )
 
+type FieldValue interface {
+	IsFieldValue()
+}
+
+type AuditLogConnection struct {
+	Edges      []*AuditLogEdge `json:"edges"`
+	PageInfo   *PageInfo       `json:"pageInfo"`
+	TotalCount int32           `json:"totalCount"`
+}
+
+type AuditLogEdge struct {
+	Cursor string         `json:"cursor"`
+	Node   *AuditLogEntry `json:"node"`
+}
+
+type AuditLogEntry struct {
+	ID           string    `json:"id"`
+	UserURI      string    `json:"userUri"`
+	Action       string    `json:"action"`
+	TargetURI    string    `json:"targetUri"`
+	TenantURI    string    `json:"tenantUri"`
+	RequestID    *string   `json:"requestId,omitempty"`
+	ClientIP     *string   `json:"clientIp,omitempty"`
+	Diff         any       `json:"diff,omitempty"`
+	CreationDate time.Time `json:"creationDate"`
+}
+
+type AuditLogFilter struct {
+	UserURI   *string    `json:"userUri,omitempty"`
+	Action    *string    `json:"action,omitempty"`
+	TargetURI *string    `json:"targetUri,omitempty"`
+	From      *time.Time `json:"from,omitempty"`
+	To        *time.Time `json:"to,omitempty"`
+}
+
+type BooleanValue struct {
+	Boolean bool `json:"boolean"`
+}
+
+func (BooleanValue) IsFieldValue() {}
+
+type BulkElementError struct {
+	// The error code, or ABORTED for elements of an atomic batch rolled back because another element failed.
+	Code        string        `json:"code"`
+	Message     string        `json:"message"`
+	FieldErrors []*FieldError `json:"fieldErrors"`
+}
+
+type BulkElementResult struct {
+	URI string `json:"uri"`
+	// The element after the write, null if the write failed or deleted it.
+	Element *Element          `json:"element,omitempty"`
+	Error   *BulkElementError `json:"error,omitempty"`
+}
+
+type CreateElementInput struct {
+	// Generated when omitted.
+	URI         *string            `json:"uri,omitempty"`
+	TypeURI     string             `json:"typeUri"`
+	SpaceURI    string             `json:"spaceUri"`
+	Title       string             `json:"title"`
+	FieldValues []*FieldValueInput `json:"fieldValues,omitempty"`
+}
+
+type DateValue struct {
+	Date time.Time `json:"date"`
+}
+
+func (DateValue) IsFieldValue() {}
+
+type DeleteElementInput struct {
+	URI             string `json:"uri"`
+	ExpectedVersion *int32 `json:"expectedVersion,omitempty"`
+}
+
 type Element struct {
-	URI          string               `json:"uri"`
-	Title        string               `json:"title"`
-	Type         *Type                `json:"type"`
-	Space        *Space               `json:"space"`
-	CreationDate string               `json:"creationDate"`
-	Author       *User                `json:"author"`
-	FieldValues  []*ElementFieldValue `json:"fieldValues"`
+	URI          string                     `json:"uri"`
+	Title        string                     `json:"title"`
+	Type         *Type                      `json:"type"`
+	Space        *Space                     `json:"space"`
+	CreationDate time.Time                  `json:"creationDate"`
+	Author       *User                      `json:"author"`
+	FieldValues  []*ElementFieldValue       `json:"fieldValues"`
+	Version      int32                      `json:"version"`
+	Revisions    *ElementRevisionConnection `json:"revisions"`
+	Links        []*ElementLink             `json:"links"`
+	Backlinks    []*ElementLink             `json:"backlinks"`
+}
+
+type ElementChange struct {
+	// Identifies the change, which may be delivered more than once. Webhooks get it as the event ID.
+	ID          string                     `json:"id"`
+	Element     *Element                   `json:"element"`
+	Title       *TitleChange               `json:"title,omitempty"`
+	FieldValues []*ElementFieldValueChange `json:"fieldValues"`
+	Links       []*ElementLinkChange       `json:"links"`
+	// Whether the element was deleted, element then holds its last state.
+	Deleted   bool      `json:"deleted"`
+	Actor     *User     `json:"actor"`
+	Timestamp time.Time `json:"timestamp"`
 }
 
 type ElementConnection struct {
@@ -31,9 +126,67 @@ type ElementEdge struct {
 }
 
 type ElementFieldValue struct {
-	URI   string `json:"uri"`
-	Value any    `json:"value"`
-	Field *Field `json:"field"`
+	URI        string     `json:"uri"`
+	Value      any        `json:"value"`
+	TypedValue FieldValue `json:"typedValue"`
+	Field      *Field     `json:"field"`
+}
+
+type ElementFieldValueChange struct {
+	URI    string `json:"uri"`
+	Field  *Field `json:"field"`
+	Before any    `json:"before,omitempty"`
+	After  any    `json:"after,omitempty"`
+}
+
+type ElementFilter struct {
+	FieldValueFilter *FieldValueFilter `json:"fieldValueFilter,omitempty"`
+	RelationFilter   *RelationFilter   `json:"relationFilter,omitempty"`
+}
+
+type ElementLink struct {
+	Field   *Field   `json:"field"`
+	Element *Element `json:"element"`
+}
+
+type ElementLinkChange struct {
+	Field  *Field   `json:"field"`
+	Before []string `json:"before"`
+	After  []string `json:"after"`
+}
+
+// The title and field values of an element as they were after a write. Links set
+// through relation fields are not versioned.
+type ElementRevision struct {
+	ID           string               `json:"id"`
+	Title        string               `json:"title"`
+	FieldValues  []*ElementFieldValue `json:"fieldValues"`
+	Author       *User                `json:"author"`
+	CreationDate time.Time            `json:"creationDate"`
+}
+
+type ElementRevisionConnection struct {
+	Edges      []*ElementRevisionEdge `json:"edges"`
+	PageInfo   *PageInfo              `json:"pageInfo"`
+	TotalCount int32                  `json:"totalCount"`
+}
+
+type ElementRevisionEdge struct {
+	Cursor string           `json:"cursor"`
+	Node   *ElementRevision `json:"node"`
+}
+
+type EmailValue struct {
+	Email string `json:"email"`
+}
+
+func (EmailValue) IsFieldValue() {}
+
+// A link downloading an export without authentication headers.
+type ExportDownload struct {
+	// The path of the download, relative to the API.
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
 }
 
 type Field struct {
@@ -41,21 +194,105 @@ type Field struct {
 	Name         string    `json:"name"`
 	FieldType    FieldType `json:"fieldType"`
 	Type         *Type     `json:"type"`
-	CreationDate string    `json:"creationDate"`
+	CreationDate time.Time `json:"creationDate"`
 	Author       *User     `json:"author"`
 	Options      *string   `json:"options,omitempty"`
 	Required     bool      `json:"required"`
+	// Validation constraints applied to the values of the field, as JSON.
+	Constraints *string `json:"constraints,omitempty"`
+	// Type of the values computed by a FORMULA or ROLLUP field, null for other fields.
+	ResultType *FieldType `json:"resultType,omitempty"`
+}
+
+// A field value rejected by the constraints of its field.
+type FieldError struct {
+	FieldURI   string `json:"fieldUri"`
+	Constraint string `json:"constraint"`
+	Message    string `json:"message"`
 }
 
 type FieldValueFilter struct {
 	FieldURI  *string         `json:"fieldUri,omitempty"`
 	Value     *string         `json:"value,omitempty"`
 	ValueType *FieldValueType `json:"valueType,omitempty"`
+	// How field values compare to value, EQ by default.
+	Operator *FilterOperator `json:"operator,omitempty"`
+}
+
+type FieldValueInput struct {
+	FieldURI string `json:"fieldUri"`
+	// The value to set, in the representation of ElementFieldValue.value. Null removes the value.
+	Value any `json:"value,omitempty"`
+}
+
+type ImportColumnMapping struct {
+	Column string `json:"column"`
+	// The field the column holds values of, null to ignore the column.
+	FieldURI *string `json:"fieldUri,omitempty"`
 }
 
+type ImportElementsInput struct {
+	TypeURI  string `json:"typeUri"`
+	SpaceURI string `json:"spaceUri"`
+	// A CSV file with a header row.
+	File graphql.Upload `json:"file"`
+	// Columns mapped to fields explicitly, the others are matched to fields by name. A column named title holds the element titles.
+	Mapping []*ImportColumnMapping `json:"mapping,omitempty"`
+	// Separates the options of MULTI_SELECT values, ; by default.
+	MultiSelectDelimiter *string `json:"multiSelectDelimiter,omitempty"`
+	// Checks every row without importing any.
+	DryRun *bool `json:"dryRun,omitempty"`
+}
+
+type ImportElementsResult struct {
+	// The number of data rows in the file.
+	RowCount int32 `json:"rowCount"`
+	// The number of rows imported, or that would be on dry runs.
+	ImportedCount int32             `json:"importedCount"`
+	Errors        []*ImportRowError `json:"errors"`
+}
+
+// A row that could not be imported.
+type ImportRowError struct {
+	// The position of the row among the data rows, starting at 1.
+	Row   int32             `json:"row"`
+	Error *BulkElementError `json:"error"`
+}
+
+// A background job started by one of the start mutations.
+type Job struct {
+	ID     string    `json:"id"`
+	Kind   string    `json:"kind"`
+	Status JobStatus `json:"status"`
+	// The number of items processed so far, out of total once known.
+	Progress    int32  `json:"progress"`
+	Total       *int32 `json:"total,omitempty"`
+	Attempts    int32  `json:"attempts"`
+	MaxAttempts int32  `json:"maxAttempts"`
+	// Why the job failed, or why its last attempt did when it is queued for a retry.
+	Error *string `json:"error,omitempty"`
+	// What the job returns once it has succeeded, e.g. an ImportElementsResult.
+	Result     any        `json:"result,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+type MultiSelectValue struct {
+	Options []string `json:"options"`
+}
+
+func (MultiSelectValue) IsFieldValue() {}
+
 type Mutation struct {
 }
 
+type NumberValue struct {
+	Number float64 `json:"number"`
+}
+
+func (NumberValue) IsFieldValue() {}
+
 type PageInfo struct {
 	HasNextPage bool    `json:"hasNextPage"`
 	StartCursor *string `json:"startCursor,omitempty"`
@@ -65,11 +302,29 @@ type PageInfo struct {
 type Query struct {
 }
 
+type RelationFilter struct {
+	FieldURI         string            `json:"fieldUri"`
+	FieldValueFilter *FieldValueFilter `json:"fieldValueFilter"`
+}
+
+type SelectValue struct {
+	Option string `json:"option"`
+}
+
+func (SelectValue) IsFieldValue() {}
+
+type SetElementLinksInput struct {
+	URI             string   `json:"uri"`
+	FieldURI        string   `json:"fieldUri"`
+	TargetUris      []string `json:"targetUris"`
+	ExpectedVersion *int32   `json:"expectedVersion,omitempty"`
+}
+
 type Space struct {
-	URI          string  `json:"uri"`
-	Name         string  `json:"name"`
-	Tenant       *Tenant `json:"tenant"`
-	CreationDate string  `json:"creationDate"`
+	URI          string    `json:"uri"`
+	Name         string    `json:"name"`
+	Tenant       *Tenant   `json:"tenant"`
+	CreationDate time.Time `json:"creationDate"`
 }
 
 type Subscription struct {
@@ -79,28 +334,162 @@ type Tenant struct {
 	URI          string       `json:"uri"`
 	Name         string       `json:"name"`
 	Status       TenantStatus `json:"status"`
-	CreationDate string       `json:"creationDate"`
+	CreationDate time.Time    `json:"creationDate"`
+}
+
+type TextValue struct {
+	Text string `json:"text"`
+}
+
+func (TextValue) IsFieldValue() {}
+
+type TitleChange struct {
+	Before string `json:"before"`
+	After  string `json:"after"`
 }
 
 type Type struct {
-	URI          string `json:"uri"`
-	Name         string `json:"name"`
-	Space        *Space `json:"space"`
-	CreationDate string `json:"creationDate"`
-	Author       *User  `json:"author"`
+	URI          string    `json:"uri"`
+	Name         string    `json:"name"`
+	Space        *Space    `json:"space"`
+	CreationDate time.Time `json:"creationDate"`
+	Author       *User     `json:"author"`
+}
+
+type UpdateElementInput struct {
+	URI   string  `json:"uri"`
+	Title *string `json:"title,omitempty"`
+	// Values of the fields to change, the other fields are left as they are.
+	FieldValues     []*FieldValueInput `json:"fieldValues,omitempty"`
+	ExpectedVersion *int32             `json:"expectedVersion,omitempty"`
 }
 
 type UpdateElementTitleInput struct {
-	URI   string `json:"uri"`
-	Title string `json:"title"`
+	URI             string `json:"uri"`
+	Title           string `json:"title"`
+	ExpectedVersion *int32 `json:"expectedVersion,omitempty"`
+}
+
+type UpdateElementsWhereResult struct {
+	// The number of elements matching the filter, all of which were updated unless dryRun was set.
+	AffectedCount int32 `json:"affectedCount"`
+	// On dry runs, the first matching elements as they would be after the update.
+	Sample []*Element `json:"sample"`
+}
+
+type URLValue struct {
+	URL string `json:"url"`
 }
 
+func (URLValue) IsFieldValue() {}
+
 type User struct {
 	URI         string `json:"uri"`
 	Email       string `json:"email"`
 	DisplayName string `json:"displayName"`
 }
 
+// A URL called on element events of a space. Payloads are signed with its secret, which is never returned.
+type Webhook struct {
+	URI      string `json:"uri"`
+	SpaceURI string `json:"spaceUri"`
+	// Restricts the webhook to the elements of a type.
+	TypeURI      *string        `json:"typeUri,omitempty"`
+	URL          string         `json:"url"`
+	Events       []WebhookEvent `json:"events"`
+	AuthorURI    string         `json:"authorUri"`
+	CreationDate time.Time      `json:"creationDate"`
+}
+
+// A call of a webhook for an event.
+type WebhookDelivery struct {
+	ID         string `json:"id"`
+	WebhookURI string `json:"webhookUri"`
+	// Shared by the deliveries of the same event, sent in the X-Webhook-Event-ID header.
+	EventID string       `json:"eventId"`
+	Event   WebhookEvent `json:"event"`
+	// The JSON body sent.
+	Payload  any                   `json:"payload"`
+	Status   WebhookDeliveryStatus `json:"status"`
+	Attempts int32                 `json:"attempts"`
+	// When the delivery is next attempted, while it is pending.
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+	// The HTTP status of the response to the last attempt.
+	ResponseStatus *int32 `json:"responseStatus,omitempty"`
+	// The start of the body of the response to the last attempt.
+	ResponseBody *string `json:"responseBody,omitempty"`
+	// Why the last attempt failed.
+	Error        *string    `json:"error,omitempty"`
+	CreationDate time.Time  `json:"creationDate"`
+	DeliveredAt  *time.Time `json:"deliveredAt,omitempty"`
+}
+
+type WebhookDeliveryConnection struct {
+	Edges      []*WebhookDeliveryEdge `json:"edges"`
+	PageInfo   *PageInfo              `json:"pageInfo"`
+	TotalCount int32                  `json:"totalCount"`
+}
+
+type WebhookDeliveryEdge struct {
+	Cursor string           `json:"cursor"`
+	Node   *WebhookDelivery `json:"node"`
+}
+
+type ExportFormat string
+
+const (
+	ExportFormatCSV    ExportFormat = "CSV"
+	ExportFormatNdjson ExportFormat = "NDJSON"
+)
+
+var AllExportFormat = []ExportFormat{
+	ExportFormatCSV,
+	ExportFormatNdjson,
+}
+
+func (e ExportFormat) IsValid() bool {
+	switch e {
+	case ExportFormatCSV, ExportFormatNdjson:
+		return true
+	}
+	return false
+}
+
+func (e ExportFormat) String() string {
+	return string(e)
+}
+
+func (e *ExportFormat) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ExportFormat(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ExportFormat", str)
+	}
+	return nil
+}
+
+func (e ExportFormat) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *ExportFormat) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e ExportFormat) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
 type FieldType string
 
 const (
@@ -112,6 +501,9 @@ const (
 	FieldTypeMultiSelect FieldType = "MULTI_SELECT"
 	FieldTypeURL         FieldType = "URL"
 	FieldTypeEmail       FieldType = "EMAIL"
+	FieldTypeRelation    FieldType = "RELATION"
+	FieldTypeFormula     FieldType = "FORMULA"
+	FieldTypeRollup      FieldType = "ROLLUP"
 )
 
 var AllFieldType = []FieldType{
@@ -123,11 +515,14 @@ var AllFieldType = []FieldType{
 	FieldTypeMultiSelect,
 	FieldTypeURL,
 	FieldTypeEmail,
+	FieldTypeRelation,
+	FieldTypeFormula,
+	FieldTypeRollup,
 }
 
 func (e FieldType) IsValid() bool {
 	switch e {
-	case FieldTypeText, FieldTypeNumber, FieldTypeDate, FieldTypeBoolean, FieldTypeSelect, FieldTypeMultiSelect, FieldTypeURL, FieldTypeEmail:
+	case FieldTypeText, FieldTypeNumber, FieldTypeDate, FieldTypeBoolean, FieldTypeSelect, FieldTypeMultiSelect, FieldTypeURL, FieldTypeEmail, FieldTypeRelation, FieldTypeFormula, FieldTypeRollup:
 		return true
 	}
 	return false
@@ -229,6 +624,128 @@ func (e FieldValueType) MarshalJSON() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+type FilterOperator string
+
+const (
+	FilterOperatorEq  FilterOperator = "EQ"
+	FilterOperatorGt  FilterOperator = "GT"
+	FilterOperatorGte FilterOperator = "GTE"
+	FilterOperatorLt  FilterOperator = "LT"
+	FilterOperatorLte FilterOperator = "LTE"
+)
+
+var AllFilterOperator = []FilterOperator{
+	FilterOperatorEq,
+	FilterOperatorGt,
+	FilterOperatorGte,
+	FilterOperatorLt,
+	FilterOperatorLte,
+}
+
+func (e FilterOperator) IsValid() bool {
+	switch e {
+	case FilterOperatorEq, FilterOperatorGt, FilterOperatorGte, FilterOperatorLt, FilterOperatorLte:
+		return true
+	}
+	return false
+}
+
+func (e FilterOperator) String() string {
+	return string(e)
+}
+
+func (e *FilterOperator) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = FilterOperator(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid FilterOperator", str)
+	}
+	return nil
+}
+
+func (e FilterOperator) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *FilterOperator) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e FilterOperator) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type JobStatus string
+
+const (
+	JobStatusQueued    JobStatus = "QUEUED"
+	JobStatusRunning   JobStatus = "RUNNING"
+	JobStatusSucceeded JobStatus = "SUCCEEDED"
+	JobStatusFailed    JobStatus = "FAILED"
+	JobStatusCancelled JobStatus = "CANCELLED"
+)
+
+var AllJobStatus = []JobStatus{
+	JobStatusQueued,
+	JobStatusRunning,
+	JobStatusSucceeded,
+	JobStatusFailed,
+	JobStatusCancelled,
+}
+
+func (e JobStatus) IsValid() bool {
+	switch e {
+	case JobStatusQueued, JobStatusRunning, JobStatusSucceeded, JobStatusFailed, JobStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func (e JobStatus) String() string {
+	return string(e)
+}
+
+func (e *JobStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = JobStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid JobStatus", str)
+	}
+	return nil
+}
+
+func (e JobStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *JobStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e JobStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
 type TenantStatus string
 
 const (
@@ -283,3 +800,117 @@ func (e TenantStatus) MarshalJSON() ([]byte, error) {
 	e.MarshalGQL(&buf)
 	return buf.Bytes(), nil
 }
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "PENDING"
+	WebhookDeliveryStatusSucceeded WebhookDeliveryStatus = "SUCCEEDED"
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "FAILED"
+)
+
+var AllWebhookDeliveryStatus = []WebhookDeliveryStatus{
+	WebhookDeliveryStatusPending,
+	WebhookDeliveryStatusSucceeded,
+	WebhookDeliveryStatusFailed,
+}
+
+func (e WebhookDeliveryStatus) IsValid() bool {
+	switch e {
+	case WebhookDeliveryStatusPending, WebhookDeliveryStatusSucceeded, WebhookDeliveryStatusFailed:
+		return true
+	}
+	return false
+}
+
+func (e WebhookDeliveryStatus) String() string {
+	return string(e)
+}
+
+func (e *WebhookDeliveryStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = WebhookDeliveryStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid WebhookDeliveryStatus", str)
+	}
+	return nil
+}
+
+func (e WebhookDeliveryStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *WebhookDeliveryStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e WebhookDeliveryStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type WebhookEvent string
+
+const (
+	WebhookEventElementCreated WebhookEvent = "ELEMENT_CREATED"
+	WebhookEventElementUpdated WebhookEvent = "ELEMENT_UPDATED"
+	WebhookEventElementDeleted WebhookEvent = "ELEMENT_DELETED"
+)
+
+var AllWebhookEvent = []WebhookEvent{
+	WebhookEventElementCreated,
+	WebhookEventElementUpdated,
+	WebhookEventElementDeleted,
+}
+
+func (e WebhookEvent) IsValid() bool {
+	switch e {
+	case WebhookEventElementCreated, WebhookEventElementUpdated, WebhookEventElementDeleted:
+		return true
+	}
+	return false
+}
+
+func (e WebhookEvent) String() string {
+	return string(e)
+}
+
+func (e *WebhookEvent) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = WebhookEvent(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid WebhookEvent", str)
+	}
+	return nil
+}
+
+func (e WebhookEvent) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *WebhookEvent) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e WebhookEvent) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
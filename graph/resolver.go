@@ -11,5 +11,9 @@ import (
 
 type Resolver struct {
 	ElementService *service.ElementService
+	AuditService   *service.AuditService
+	ExportService  *service.ExportService
+	JobService     *service.JobService
+	WebhookService *service.WebhookService
 	ElementPubSub  *pubsub.ElementPubSub
 }
\ No newline at end of file
@@ -7,14 +7,141 @@ package graph
 
 import (
 	"context"
+	"time"
 
 	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
 	models "github.com/bamdadam/backend/src/model"
 )
 
+// Revisions is the resolver for the revisions field.
+func (r *elementResolver) Revisions(ctx context.Context, obj *model.Element, first *int32, after *string) (*model.ElementRevisionConnection, error) {
+	return r.ElementService.Revisions(ctx, obj.URI, first, after)
+}
+
+// Links is the resolver for the links field.
+func (r *elementResolver) Links(ctx context.Context, obj *model.Element, fieldURI *string) ([]*model.ElementLink, error) {
+	return r.ElementService.Links(ctx, obj.URI, fieldURI)
+}
+
+// Backlinks is the resolver for the backlinks field.
+func (r *elementResolver) Backlinks(ctx context.Context, obj *model.Element, fieldURI *string) ([]*model.ElementLink, error) {
+	return r.ElementService.Backlinks(ctx, obj.URI, fieldURI)
+}
+
+// TypedValue is the resolver for the typedValue field.
+func (r *elementFieldValueResolver) TypedValue(ctx context.Context, obj *model.ElementFieldValue) (model.FieldValue, error) {
+	fieldType := obj.Field.FieldType
+	if fieldType == model.FieldTypeFormula || fieldType == model.FieldTypeRollup {
+		resultType, err := r.ElementService.ResultType(ctx, obj.Field)
+		if err != nil {
+			return nil, err
+		}
+		fieldType = *resultType
+	}
+	return model.NewFieldValue(fieldType, obj.Value)
+}
+
+// ResultType is the resolver for the resultType field.
+func (r *fieldResolver) ResultType(ctx context.Context, obj *model.Field) (*model.FieldType, error) {
+	return r.ElementService.ResultType(ctx, obj)
+}
+
+// CreateElement is the resolver for the createElement field.
+func (r *mutationResolver) CreateElement(ctx context.Context, input model.CreateElementInput) (*model.Element, error) {
+	return r.ElementService.Create(ctx, input)
+}
+
+// UpdateElement is the resolver for the updateElement field.
+func (r *mutationResolver) UpdateElement(ctx context.Context, input model.UpdateElementInput) (*model.Element, error) {
+	return r.ElementService.Update(ctx, input)
+}
+
 // UpdateElementTitle is the resolver for the updateElementTitle field.
 func (r *mutationResolver) UpdateElementTitle(ctx context.Context, input model.UpdateElementTitleInput) (*model.Element, error) {
-	return r.ElementService.UpdateTitle(ctx, input.URI, input.Title)
+	return r.ElementService.UpdateTitle(ctx, input.URI, input.Title, input.ExpectedVersion)
+}
+
+// RevertElement is the resolver for the revertElement field.
+func (r *mutationResolver) RevertElement(ctx context.Context, uri string, revisionID string, expectedVersion *int32) (*model.Element, error) {
+	return r.ElementService.Revert(ctx, uri, revisionID, expectedVersion)
+}
+
+// SetElementLinks is the resolver for the setElementLinks field.
+func (r *mutationResolver) SetElementLinks(ctx context.Context, input model.SetElementLinksInput) (*model.Element, error) {
+	return r.ElementService.SetLinks(ctx, input.URI, input.FieldURI, input.TargetUris, input.ExpectedVersion)
+}
+
+// BulkUpdateElements is the resolver for the bulkUpdateElements field.
+func (r *mutationResolver) BulkUpdateElements(ctx context.Context, inputs []*model.UpdateElementInput, atomic *bool) ([]*model.BulkElementResult, error) {
+	return r.ElementService.BulkUpdate(ctx, inputs, atomic != nil && *atomic)
+}
+
+// BulkDeleteElements is the resolver for the bulkDeleteElements field.
+func (r *mutationResolver) BulkDeleteElements(ctx context.Context, inputs []*model.DeleteElementInput, uris []string, atomic *bool) ([]*model.BulkElementResult, error) {
+	switch {
+	case inputs != nil && uris != nil:
+		return nil, apperror.Validation("only one of inputs and uris can be given")
+	case inputs == nil && uris == nil:
+		return nil, apperror.Validation("inputs is required")
+	}
+	for _, uri := range uris {
+		inputs = append(inputs, &model.DeleteElementInput{URI: uri})
+	}
+	return r.ElementService.BulkDelete(ctx, inputs, atomic != nil && *atomic)
+}
+
+// UpdateElementsWhere is the resolver for the updateElementsWhere field.
+func (r *mutationResolver) UpdateElementsWhere(ctx context.Context, spaceURI string, typeURI string, filter *model.ElementFilter, set []*model.FieldValueInput, dryRun *bool) (*model.UpdateElementsWhereResult, error) {
+	return r.ElementService.UpdateWhere(ctx, spaceURI, typeURI, filter, set, dryRun != nil && *dryRun)
+}
+
+// ImportElements is the resolver for the importElements field.
+func (r *mutationResolver) ImportElements(ctx context.Context, input model.ImportElementsInput) (*model.ImportElementsResult, error) {
+	return r.ElementService.Import(ctx, input)
+}
+
+// CreateExportToken is the resolver for the createExportToken field.
+func (r *mutationResolver) CreateExportToken(ctx context.Context, typeURI string, spaceURI *string, format model.ExportFormat, filter *model.ElementFilter) (*model.ExportDownload, error) {
+	return r.ExportService.CreateToken(ctx, models.ExportParams{
+		TypeURI:  typeURI,
+		SpaceURI: spaceURI,
+		Format:   format,
+		Filter:   filter,
+	})
+}
+
+// StartImportElements is the resolver for the startImportElements field.
+func (r *mutationResolver) StartImportElements(ctx context.Context, input model.ImportElementsInput) (*model.Job, error) {
+	return r.ElementService.StartImport(ctx, input)
+}
+
+// StartUpdateElementsWhere is the resolver for the startUpdateElementsWhere field.
+func (r *mutationResolver) StartUpdateElementsWhere(ctx context.Context, spaceURI string, typeURI string, filter *model.ElementFilter, set []*model.FieldValueInput, dryRun *bool) (*model.Job, error) {
+	return r.ElementService.StartUpdateWhere(ctx, spaceURI, typeURI, filter, set, dryRun != nil && *dryRun)
+}
+
+// CancelJob is the resolver for the cancelJob field.
+func (r *mutationResolver) CancelJob(ctx context.Context, id string) (*model.Job, error) {
+	return r.JobService.Cancel(ctx, id)
+}
+
+// CreateWebhook is the resolver for the createWebhook field.
+func (r *mutationResolver) CreateWebhook(ctx context.Context, spaceURI string, typeURI *string, url string, events []model.WebhookEvent, secret string) (*model.Webhook, error) {
+	return r.WebhookService.Create(ctx, spaceURI, typeURI, url, events, secret)
+}
+
+// DeleteWebhook is the resolver for the deleteWebhook field.
+func (r *mutationResolver) DeleteWebhook(ctx context.Context, uri string) (bool, error) {
+	if err := r.WebhookService.Delete(ctx, uri); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RedeliverWebhookDelivery is the resolver for the redeliverWebhookDelivery field.
+func (r *mutationResolver) RedeliverWebhookDelivery(ctx context.Context, id string) (*model.WebhookDelivery, error) {
+	return r.WebhookService.Redeliver(ctx, id)
 }
 
 // Element is the resolver for the element field.
@@ -28,13 +155,15 @@ func (r *queryResolver) Elements(ctx context.Context,
 	after *string,
 	typeURI *string,
 	spaceURI *string,
-	fieldValueFilter *model.FieldValueFilter) (
+	fieldValueFilter *model.FieldValueFilter,
+	relationFilter *model.RelationFilter) (
 	*model.ElementConnection, error) {
 	params := models.ListParams{
 		After:            after,
 		TypeURI:          typeURI,
 		SpaceURI:         spaceURI,
 		FieldValueFilter: fieldValueFilter,
+		RelationFilter:   relationFilter,
 	}
 	if limit != nil {
 		params.Limit = *limit
@@ -42,11 +171,69 @@ func (r *queryResolver) Elements(ctx context.Context,
 	return r.ElementService.List(ctx, params)
 }
 
+// ElementAt is the resolver for the elementAt field.
+func (r *queryResolver) ElementAt(ctx context.Context, uri string, timestamp time.Time) (*model.Element, error) {
+	return r.ElementService.GetAt(ctx, uri, timestamp)
+}
+
+// AuditLog is the resolver for the auditLog field.
+func (r *queryResolver) AuditLog(ctx context.Context, tenantURI string, filter *model.AuditLogFilter, first *int32, after *string) (*model.AuditLogConnection, error) {
+	params := models.AuditLogParams{
+		TenantURI: tenantURI,
+		Filter:    filter,
+		After:     after,
+	}
+	if first != nil {
+		params.Limit = *first
+	}
+	return r.AuditService.List(ctx, params)
+}
+
+// Job is the resolver for the job field.
+func (r *queryResolver) Job(ctx context.Context, id string) (*model.Job, error) {
+	return r.JobService.Get(ctx, id)
+}
+
+// Webhooks is the resolver for the webhooks field.
+func (r *queryResolver) Webhooks(ctx context.Context, spaceURI string) ([]*model.Webhook, error) {
+	return r.WebhookService.List(ctx, spaceURI)
+}
+
+// WebhookDeliveries is the resolver for the webhookDeliveries field.
+func (r *queryResolver) WebhookDeliveries(ctx context.Context, webhookURI string, status *model.WebhookDeliveryStatus, first *int32, after *string) (*model.WebhookDeliveryConnection, error) {
+	var limit int32
+	if first != nil {
+		limit = *first
+	}
+	return r.WebhookService.Deliveries(ctx, webhookURI, status, limit, after)
+}
+
 // ElementUpdated is the resolver for the elementUpdated field.
 func (r *subscriptionResolver) ElementUpdated(ctx context.Context, uri string) (<-chan *model.Element, error) {
 	return r.ElementService.UpdateElementSubscribe(ctx, uri)
 }
 
+// ElementChanged is the resolver for the elementChanged field.
+func (r *subscriptionResolver) ElementChanged(ctx context.Context, uri string) (<-chan *model.ElementChange, error) {
+	return r.ElementService.ElementChangeSubscribe(ctx, uri)
+}
+
+// JobProgress is the resolver for the jobProgress field.
+func (r *subscriptionResolver) JobProgress(ctx context.Context, id string) (<-chan *model.Job, error) {
+	return r.JobService.Subscribe(ctx, id)
+}
+
+// Element returns ElementResolver implementation.
+func (r *Resolver) Element() ElementResolver { return &elementResolver{r} }
+
+// ElementFieldValue returns ElementFieldValueResolver implementation.
+func (r *Resolver) ElementFieldValue() ElementFieldValueResolver {
+	return &elementFieldValueResolver{r}
+}
+
+// Field returns FieldResolver implementation.
+func (r *Resolver) Field() FieldResolver { return &fieldResolver{r} }
+
 // Mutation returns MutationResolver implementation.
 func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 
@@ -56,6 +243,9 @@ func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 // Subscription returns SubscriptionResolver implementation.
 func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
 
+type elementResolver struct{ *Resolver }
+type elementFieldValueResolver struct{ *Resolver }
+type fieldResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
 type subscriptionResolver struct{ *Resolver }
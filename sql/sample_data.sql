@@ -26,16 +26,6 @@ INSERT INTO public.tenants (uri, name, creation_date, status) VALUES
     ('tenant:initech', 'Initech Solutions', 1700200000000, 'active')
 ON CONFLICT (uri) DO NOTHING;
 
--- =============================================================================
--- PERMISSION VERBS
--- =============================================================================
-INSERT INTO public.permission_verbs (uri, name, description) VALUES
-    ('verb:read', 'read', 'Read access to resources'),
-    ('verb:write', 'write', 'Write access to resources'),
-    ('verb:delete', 'delete', 'Delete access to resources'),
-    ('verb:admin', 'admin', 'Full administrative access')
-ON CONFLICT (uri) DO NOTHING;
-
 -- =============================================================================
 -- SPACES (6 sample spaces - 2 per tenant)
 -- =============================================================================
@@ -51,17 +41,17 @@ ON CONFLICT (uri) DO NOTHING;
 -- =============================================================================
 -- USER-TENANT ASSOCIATIONS
 -- =============================================================================
-INSERT INTO public.user_tenants (user_uri, tenant_uri) VALUES
-    ('user:alice', 'tenant:acme'),
-    ('user:bob', 'tenant:acme'),
-    ('user:charlie', 'tenant:acme'),
-    ('user:diana', 'tenant:globex'),
-    ('user:edward', 'tenant:globex'),
-    ('user:fiona', 'tenant:globex'),
-    ('user:george', 'tenant:initech'),
-    ('user:hannah', 'tenant:initech'),
-    ('user:ivan', 'tenant:initech'),
-    ('user:julia', 'tenant:initech')
+INSERT INTO public.user_tenants (user_uri, tenant_uri, role) VALUES
+    ('user:alice', 'tenant:acme', 'admin'),
+    ('user:bob', 'tenant:acme', 'member'),
+    ('user:charlie', 'tenant:acme', 'member'),
+    ('user:diana', 'tenant:globex', 'admin'),
+    ('user:edward', 'tenant:globex', 'member'),
+    ('user:fiona', 'tenant:globex', 'member'),
+    ('user:george', 'tenant:initech', 'admin'),
+    ('user:hannah', 'tenant:initech', 'member'),
+    ('user:ivan', 'tenant:initech', 'member'),
+    ('user:julia', 'tenant:initech', 'member')
 ON CONFLICT DO NOTHING;
 
 -- =============================================================================
@@ -84,6 +74,13 @@ INSERT INTO public.user_spaces (user_uri, space_uri) VALUES
     ('user:julia', 'space:initech-inventory')
 ON CONFLICT DO NOTHING;
 
+-- Every user may read and write the spaces they belong to
+INSERT INTO public.user_space_permissions (user_uri, space_uri, verb_uri)
+SELECT us.user_uri, us.space_uri, v.uri
+FROM public.user_spaces us
+CROSS JOIN (VALUES ('verb:read'), ('verb:write')) AS v(uri)
+ON CONFLICT DO NOTHING;
+
 -- =============================================================================
 -- TYPES (Different types for different use cases)
 -- =============================================================================
@@ -150,6 +147,10 @@ INSERT INTO public.fields (uri, name, field_type, type_uri, creation_date, autho
     ('field:prod-category', 'Category', 'select', 'type:product', 1700140005000, 'user:diana', '{"options": ["Electronics", "Clothing", "Home & Garden", "Sports", "Books", "Toys"]}', false),
     ('field:prod-url', 'Product URL', 'url', 'type:product', 1700140006000, 'user:diana', NULL, false);
 
+-- Product constraints
+UPDATE public.fields SET constraints = '{"unique": "type", "pattern": "^SKU-[0-9]{6}$"}' WHERE uri = 'field:prod-sku';
+UPDATE public.fields SET constraints = '{"min": 0}' WHERE uri IN ('field:prod-price', 'field:prod-stock');
+
 -- Customer fields
 INSERT INTO public.fields (uri, name, field_type, type_uri, creation_date, author, options, required) VALUES
     ('field:cust-company', 'Company Name', 'text', 'type:customer', 1700140100000, 'user:fiona', NULL, true),
@@ -164,9 +165,10 @@ INSERT INTO public.fields (uri, name, field_type, type_uri, creation_date, autho
 INSERT INTO public.fields (uri, name, field_type, type_uri, creation_date, author, options, required) VALUES
     ('field:ticket-title', 'Title', 'text', 'type:ticket', 1700240000000, 'user:george', NULL, true),
     ('field:ticket-desc', 'Description', 'text', 'type:ticket', 1700240001000, 'user:george', NULL, true),
-    ('field:ticket-priority', 'Priority', 'select', 'type:ticket', 1700240002000, 'user:george', '{"options": ["Low", "Medium", "High", "Urgent"]}', true),
-    ('field:ticket-status', 'Status', 'select', 'type:ticket', 1700240003000, 'user:george', '{"options": ["Open", "In Progress", "Waiting", "Resolved", "Closed"]}', true),
-    ('field:ticket-created', 'Created Date', 'date', 'type:ticket', 1700240004000, 'user:george', NULL, true);
+    ('field:ticket-priority', 'Priority', 'select', 'type:ticket', 1700240002000, 'user:george', '{"options": ["Low", "Medium", "High", "Urgent"], "default": "Medium"}', true),
+    ('field:ticket-status', 'Status', 'select', 'type:ticket', 1700240003000, 'user:george', '{"options": ["Open", "In Progress", "Waiting", "Resolved", "Closed"], "default": "Open"}', true),
+    ('field:ticket-created', 'Created Date', 'date', 'type:ticket', 1700240004000, 'user:george', '{"autogen": "today"}', true),
+    ('field:ticket-number', 'Ticket Number', 'text', 'type:ticket', 1700240005000, 'user:george', '{"autogen": "sequence", "prefix": "TICKET-", "digits": 4}', false);
 
 -- Bug Report fields
 INSERT INTO public.fields (uri, name, field_type, type_uri, creation_date, author, options, required) VALUES
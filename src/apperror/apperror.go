@@ -0,0 +1,87 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Code classifies an error for API clients. It is exposed as extensions.code
+// on GraphQL errors.
+type Code string
+
+const (
+	CodeNotFound        Code = "NOT_FOUND"
+	CodeForbidden       Code = "FORBIDDEN"
+	CodeValidation      Code = "VALIDATION"
+	CodeConflict        Code = "CONFLICT"
+	CodeUnauthenticated Code = "UNAUTHENTICATED"
+	CodeInternal        Code = "INTERNAL"
+)
+
+// Error is a domain error that is safe to show to clients. Errors that are not
+// an *Error anywhere in their chain are considered internal.
+type Error struct {
+	Code       Code
+	Message    string
+	Extensions map[string]any
+
+	err error
+}
+
+func newError(code Code, format string, args ...any) *Error {
+	err := fmt.Errorf(format, args...)
+	return &Error{Code: code, Message: err.Error(), err: err}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the error wrapped with %w when the Error was created, if any.
+func (e *Error) Unwrap() error {
+	return errors.Unwrap(e.err)
+}
+
+// WithExtension attaches additional data to be returned to clients alongside
+// the error code.
+func (e *Error) WithExtension(key string, value any) *Error {
+	if e.Extensions == nil {
+		e.Extensions = make(map[string]any)
+	}
+	e.Extensions[key] = value
+	return e
+}
+
+func NotFound(format string, args ...any) *Error {
+	return newError(CodeNotFound, format, args...)
+}
+
+func Forbidden(format string, args ...any) *Error {
+	return newError(CodeForbidden, format, args...)
+}
+
+func Validation(format string, args ...any) *Error {
+	return newError(CodeValidation, format, args...)
+}
+
+func Conflict(format string, args ...any) *Error {
+	return newError(CodeConflict, format, args...)
+}
+
+func Unauthenticated(format string, args ...any) *Error {
+	return newError(CodeUnauthenticated, format, args...)
+}
+
+func Internal(format string, args ...any) *Error {
+	return newError(CodeInternal, format, args...)
+}
+
+// CodeOf returns the code of the first *Error in err's chain, or CodeInternal
+// if there is none.
+func CodeOf(err error) Code {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return CodeInternal
+}
@@ -0,0 +1,240 @@
+package formula
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/bamdadam/backend/graph/model"
+)
+
+const day = 24 * time.Hour
+
+// Eval evaluates the expression. values returns the value of a referenced
+// field by its key: a float64 for NUMBER, a string for TEXT, a bool for
+// BOOLEAN and a time.Time for DATE fields, or nil if the field has no value.
+// The result is of the same representation, nil if it can't be computed,
+// e.g. when a referenced field has no value or on division by zero.
+func (e *Expr) Eval(values func(key string) any, now time.Time) any {
+	v := e.root.eval(&env{values: values, now: now})
+	if f, ok := v.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+		return nil
+	}
+	return v
+}
+
+type env struct {
+	values func(key string) any
+	now    time.Time
+}
+
+type node interface {
+	eval(env *env) any
+}
+
+type literal struct {
+	value any
+}
+
+func (n literal) eval(*env) any {
+	return n.value
+}
+
+type fieldRef struct {
+	key string
+}
+
+func (n fieldRef) eval(env *env) any {
+	return env.values(n.key)
+}
+
+// unary and binary apply fn to the values of their operands, or evaluate to
+// nil if any of them is nil.
+type unary struct {
+	x  node
+	fn func(x any) any
+}
+
+func (n unary) eval(env *env) any {
+	x := n.x.eval(env)
+	if x == nil {
+		return nil
+	}
+	return n.fn(x)
+}
+
+type binary struct {
+	x, y node
+	fn   func(x, y any) any
+}
+
+func (n binary) eval(env *env) any {
+	x := n.x.eval(env)
+	if x == nil {
+		return nil
+	}
+	y := n.y.eval(env)
+	if y == nil {
+		return nil
+	}
+	return n.fn(x, y)
+}
+
+// logical is && or ||. They short-circuit and follow three-valued logic, e.g.
+// false && nil is false while true && nil is nil.
+type logical struct {
+	x, y node
+	and  bool
+}
+
+func (n logical) eval(env *env) any {
+	x := n.x.eval(env)
+	if x != nil && x.(bool) != n.and {
+		return x
+	}
+	y := n.y.eval(env)
+	if y != nil && y.(bool) != n.and {
+		return y
+	}
+	if x == nil || y == nil {
+		return nil
+	}
+	return n.and
+}
+
+type call struct {
+	fn   *function
+	args []node
+}
+
+func (n call) eval(env *env) any {
+	args := make([]any, len(n.args))
+	for i, arg := range n.args {
+		args[i] = arg.eval(env)
+		if args[i] == nil && !n.fn.nullable {
+			return nil
+		}
+	}
+	return n.fn.eval(args, env.now)
+}
+
+func logicalOp(op token, x, y typed) (typed, error) {
+	if x.t != model.FieldTypeBoolean || y.t != model.FieldTypeBoolean {
+		return typed{}, errorf(op.pos, "operator %s requires boolean operands, got %s and %s", op.text, typeName(x.t), typeName(y.t))
+	}
+	return typed{n: logical{x: x.n, y: y.n, and: op.text == "&&"}, t: model.FieldTypeBoolean}, nil
+}
+
+func comparisonOp(op token, x, y typed) (typed, error) {
+	if x.t != y.t {
+		return typed{}, errorf(op.pos, "can't compare %s with %s", typeName(x.t), typeName(y.t))
+	}
+	ordered := op.text != "==" && op.text != "!="
+	if ordered && x.t == model.FieldTypeBoolean {
+		return typed{}, errorf(op.pos, "operator %s is not defined on boolean", op.text)
+	}
+
+	fn := func(a, b any) any {
+		c := compare(a, b)
+		switch op.text {
+		case "==":
+			return c == 0
+		case "!=":
+			return c != 0
+		case "<":
+			return c < 0
+		case "<=":
+			return c <= 0
+		case ">":
+			return c > 0
+		default:
+			return c >= 0
+		}
+	}
+	return typed{n: binary{x: x.n, y: y.n, fn: fn}, t: model.FieldTypeBoolean}, nil
+}
+
+// compare orders two values of the same type, booleans only by equality.
+func compare(a, b any) int {
+	switch a := a.(type) {
+	case float64:
+		b := b.(float64)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(a, b.(string))
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	default:
+		if a == b {
+			return 0
+		}
+		return 1
+	}
+}
+
+// arithmeticOp type checks +, -, *, / and %. Besides numbers, + concatenates
+// text and dates can be shifted by a number of days, or subtracted from each
+// other for the days between them.
+func arithmeticOp(op token, x, y typed) (typed, error) {
+	var t model.FieldType
+	var fn func(a, b any) any
+	switch {
+	case x.t == num && y.t == num:
+		t, fn = num, numberOp(op.text)
+	case op.text == "+" && x.t == text && y.t == text:
+		t, fn = text, func(a, b any) any { return a.(string) + b.(string) }
+	case op.text == "+" && x.t == date && y.t == num:
+		t, fn = date, func(a, b any) any { return addDays(a.(time.Time), b.(float64)) }
+	case op.text == "+" && x.t == num && y.t == date:
+		t, fn = date, func(a, b any) any { return addDays(b.(time.Time), a.(float64)) }
+	case op.text == "-" && x.t == date && y.t == num:
+		t, fn = date, func(a, b any) any { return addDays(a.(time.Time), -b.(float64)) }
+	case op.text == "-" && x.t == date && y.t == date:
+		t, fn = num, func(a, b any) any { return float64(a.(time.Time).Sub(b.(time.Time))) / float64(day) }
+	default:
+		return typed{}, errorf(op.pos, "operator %s is not defined on %s and %s", op.text, typeName(x.t), typeName(y.t))
+	}
+	return typed{n: binary{x: x.n, y: y.n, fn: fn}, t: t}, nil
+}
+
+func numberOp(op string) func(a, b any) any {
+	return func(a, b any) any {
+		x, y := a.(float64), b.(float64)
+		switch op {
+		case "+":
+			return x + y
+		case "-":
+			return x - y
+		case "*":
+			return x * y
+		}
+		if y == 0 {
+			return nil
+		}
+		if op == "/" {
+			return x / y
+		}
+		return math.Mod(x, y)
+	}
+}
+
+func addDays(t time.Time, days float64) time.Time {
+	return t.Add(time.Duration(days * float64(day)))
+}
+
+func unaryOp(op token, x typed) (typed, error) {
+	switch {
+	case op.text == "-" && x.t == model.FieldTypeNumber:
+		return typed{n: unary{x: x.n, fn: func(v any) any { return -v.(float64) }}, t: x.t}, nil
+	case op.text == "!" && x.t == model.FieldTypeBoolean:
+		return typed{n: unary{x: x.n, fn: func(v any) any { return !v.(bool) }}, t: x.t}, nil
+	default:
+		return typed{}, errorf(op.pos, "operator %s is not defined on %s", op.text, typeName(x.t))
+	}
+}
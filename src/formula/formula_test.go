@@ -0,0 +1,313 @@
+package formula_test
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/formula"
+	models "github.com/bamdadam/backend/src/model"
+)
+
+// fields are the fields expressions of the tests reference, by name.
+var fields = map[string]model.FieldType{
+	"price":    model.FieldTypeNumber,
+	"cost":     model.FieldTypeNumber,
+	"title":    model.FieldTypeText,
+	"due_date": model.FieldTypeDate,
+	"start":    model.FieldTypeDate,
+	"done":     model.FieldTypeBoolean,
+}
+
+func resolve(name string) (formula.Ref, error) {
+	t, ok := fields[name]
+	if !ok {
+		return formula.Ref{}, fmt.Errorf("unknown field %s", name)
+	}
+	return formula.Ref{Key: name, Type: t}, nil
+}
+
+var now = time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+
+// eval compiles and evaluates an expression against values, failing the test
+// on compile errors.
+func eval(t *testing.T, expr string, values map[string]any) any {
+	t.Helper()
+	e, err := formula.Compile(expr, resolve)
+	if err != nil {
+		t.Fatalf("Failed to compile %q: %v", expr, err)
+	}
+	return e.Eval(func(key string) any { return values[key] }, now)
+}
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestEval(t *testing.T) {
+	values := map[string]any{
+		"price":    10.0,
+		"cost":     4.0,
+		"title":    "Task",
+		"due_date": date("2024-03-20T00:00:00Z"),
+		"start":    date("2024-03-10T00:00:00Z"),
+		"done":     false,
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		want any
+	}{
+		{"multiplication before addition", "1 + 2 * 3", 7.0},
+		{"parentheses", "(1 + 2) * 3", 9.0},
+		{"left associative subtraction", "10 - 4 - 3", 3.0},
+		{"left associative division", "12 / 3 / 2", 2.0},
+		{"unary minus", "-price + cost", -6.0},
+		{"modulo", "price % cost", 2.0},
+		{"comparison before and", "price > cost && cost > 5", false},
+		{"and before or", "true || false && false", true},
+		{"not", "!done", true},
+		{"text concatenation", "title + '!'", "Task!"},
+		{"fields", "price - cost", 6.0},
+		{"function", "round(price / 3, 2)", 3.33},
+		{"if", "if(done, 'yes', 'no')", "no"},
+		{"days added to a date", "due_date + 2", date("2024-03-22T00:00:00Z")},
+		{"days added before a date", "1.5 + due_date", date("2024-03-21T12:00:00Z")},
+		{"days subtracted from a date", "due_date - 5", date("2024-03-15T00:00:00Z")},
+		{"days between dates", "due_date - start", 10.0},
+		{"date comparison", "due_date > now()", true},
+		{"today", "today()", date("2024-03-15T00:00:00Z")},
+		{"date parts", "year(due_date) * 100 + month(due_date)", 202403.0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := eval(t, tt.expr, values)
+			if want, ok := tt.want.(time.Time); ok {
+				if got, ok := got.(time.Time); !ok || !got.Equal(want) {
+					t.Errorf("%s = %v, want %v", tt.expr, got, want)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("%s = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalNull(t *testing.T) {
+	// price has no value
+	values := map[string]any{"cost": 4.0, "done": true}
+
+	tests := []struct {
+		name string
+		expr string
+		want any
+	}{
+		{"arithmetic", "price + 1", nil},
+		{"nested", "round(price * 2) + cost", nil},
+		{"comparison", "price > cost", nil},
+		{"unary", "-price", nil},
+		{"function", "abs(price)", nil},
+		{"coalesce", "coalesce(price, cost)", 4.0},
+		{"missing condition is false", "if(price > 1, 1, 2)", 2.0},
+		{"false and null", "!done && price > 1", false},
+		{"true or null", "done || price > 1", true},
+		{"true and null", "done && price > 1", nil},
+		{"division by zero", "cost / 0", nil},
+		{"modulo by zero", "cost % 0", nil},
+		{"division by zero in an expression", "cost / (cost - 4) + 1", nil},
+		{"zero divided", "0 / cost", 0.0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := eval(t, tt.expr, values); got != tt.want {
+				t.Errorf("%s = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		// want is part of the error message, pos the 1-based position it
+		// is reported at.
+		want string
+		pos  int
+	}{
+		{"price + title", "operator + is not defined on number and text", 7},
+		{"price && done", "operator && requires boolean operands", 7},
+		{"price == title", "can't compare number with text", 7},
+		{"done < true", "operator < is not defined on boolean", 6},
+		{"due_date * 2", "operator * is not defined on date and number", 10},
+		{"due_date + due_date", "operator + is not defined on date and date", 10},
+		{"-title", "operator - is not defined on text", 1},
+		{"!price", "operator ! is not defined on number", 1},
+		{"price < cost < 3", `unexpected "<"`, 14},
+		{"if(price, 1, 2)", "condition must be boolean", 1},
+		{"if(done, 1, 'a')", "branches must be of the same type", 1},
+		{"abs(title)", "argument 1 must be number, got text", 1},
+		{"len()", "expected 1 arguments, got 0", 1},
+		{"nope(1)", "unknown function nope", 1},
+		{"price + other", "unknown field other", 9},
+		{"price +", "unexpected end of expression", 8},
+		{"(price", `expected ")"`, 7},
+		{"price cost", `unexpected "cost"`, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := formula.Compile(tt.expr, resolve)
+			var ferr *formula.Error
+			if !errors.As(err, &ferr) {
+				t.Fatalf("Expected a formula error, got %v", err)
+			}
+			if !strings.Contains(ferr.Msg, tt.want) {
+				t.Errorf("Expected %q in %q", tt.want, ferr.Msg)
+			}
+			if ferr.Pos+1 != tt.pos {
+				t.Errorf("Expected the error at position %d, got %d", tt.pos, ferr.Pos+1)
+			}
+		})
+	}
+}
+
+func field(uri, name string, t model.FieldType, expr string) *models.FieldDefinition {
+	f := &models.FieldDefinition{URI: uri, Name: name, FieldType: t}
+	if expr != "" {
+		options := fmt.Sprintf(`{"expression": %q}`, expr)
+		f.Options = &options
+	}
+	return f
+}
+
+func TestCompileTypeCycles(t *testing.T) {
+	number := field("field:n", "N", model.FieldTypeNumber, "")
+
+	tests := []struct {
+		name   string
+		fields []*models.FieldDefinition
+		// cycles holds the cycle path each field is expected to fail with,
+		// nil for fields failing otherwise.
+		cycles map[string][]string
+		// valid holds the formula fields expected to compile.
+		valid []string
+	}{
+		{
+			name:   "self reference",
+			fields: []*models.FieldDefinition{number, field("field:a", "A", model.FieldTypeFormula, "a + 1")},
+			cycles: map[string][]string{"field:a": {"A", "A"}},
+		},
+		{
+			name: "two formulas",
+			fields: []*models.FieldDefinition{
+				number,
+				field("field:a", "A", model.FieldTypeFormula, "b + n"),
+				field("field:b", "B", model.FieldTypeFormula, "a * 2"),
+			},
+			cycles: map[string][]string{"field:a": {"A", "B", "A"}, "field:b": {"A", "B", "A"}},
+		},
+		{
+			name: "formula referencing a cycle",
+			fields: []*models.FieldDefinition{
+				number,
+				field("field:c", "C", model.FieldTypeFormula, "a + 1"),
+				field("field:a", "A", model.FieldTypeFormula, "b + n"),
+				field("field:b", "B", model.FieldTypeFormula, "a * 2"),
+				field("field:d", "D", model.FieldTypeFormula, "n + 1"),
+			},
+			cycles: map[string][]string{"field:a": {"A", "B", "A"}, "field:b": {"A", "B", "A"}, "field:c": nil},
+			valid:  []string{"field:d"},
+		},
+		{
+			name: "cycle deeper than the first formula",
+			fields: []*models.FieldDefinition{
+				number,
+				field("field:a", "A", model.FieldTypeFormula, "b"),
+				field("field:b", "B", model.FieldTypeFormula, "c"),
+				field("field:c", "C", model.FieldTypeFormula, "b"),
+			},
+			cycles: map[string][]string{"field:b": {"B", "C", "B"}, "field:c": {"B", "C", "B"}, "field:a": nil},
+		},
+		{
+			name: "formulas sharing a dependency",
+			fields: []*models.FieldDefinition{
+				number,
+				field("field:a", "A", model.FieldTypeFormula, "b + c"),
+				field("field:b", "B", model.FieldTypeFormula, "c * 2"),
+				field("field:c", "C", model.FieldTypeFormula, "n + 1"),
+			},
+			valid: []string{"field:a", "field:b", "field:c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := formula.CompileType(tt.fields)
+			for uri, path := range tt.cycles {
+				err := set.Err(uri)
+				if err == nil {
+					t.Errorf("Expected %s to be invalid", uri)
+					continue
+				}
+				var cycle *formula.CycleError
+				isCycle := errors.As(err, &cycle)
+				switch {
+				case path == nil && isCycle:
+					t.Errorf("Expected %s to fail on its dependency, got %v", uri, err)
+				case path != nil && (!isCycle || !slices.Equal(cycle.Path, path)):
+					t.Errorf("Expected %s to fail with cycle %v, got %v", uri, path, err)
+				}
+				if _, ok := set.ResultType(uri); ok {
+					t.Errorf("Expected %s to have no result type", uri)
+				}
+			}
+			for _, uri := range tt.valid {
+				if err := set.Err(uri); err != nil {
+					t.Errorf("Expected %s to compile, got %v", uri, err)
+				}
+				if typ, ok := set.ResultType(uri); !ok || typ != model.FieldTypeNumber {
+					t.Errorf("Expected %s to compute numbers, got %v", uri, typ)
+				}
+			}
+		})
+	}
+}
+
+func TestCompileTypeEval(t *testing.T) {
+	set := formula.CompileType([]*models.FieldDefinition{
+		field("field:due", "Due Date", model.FieldTypeDate, ""),
+		field("field:price", "Price", model.FieldTypeNumber, ""),
+		// formulas may come before the formulas they reference
+		field("field:late", "Late", model.FieldTypeFormula, "remind_on < now()"),
+		field("field:remind", "Remind On", model.FieldTypeFormula, "due_date - 1"),
+		field("field:broken", "Broken", model.FieldTypeFormula, "price +"),
+	})
+	if err := set.Err("field:broken"); err == nil || !strings.Contains(err.Error(), "Broken") {
+		t.Errorf("Expected the error to name the broken field, got %v", err)
+	}
+
+	results := set.Eval(map[string]any{"field:due": "2024-03-16T00:00:00Z", "field:price": 1.0}, now)
+	want := map[string]any{"field:remind": "2024-03-15T00:00:00.000Z", "field:late": true}
+	if len(results) != len(want) {
+		t.Errorf("Expected %v, got %v", want, results)
+	}
+	for uri, v := range want {
+		if results[uri] != v {
+			t.Errorf("Expected %s = %v, got %v", uri, v, results[uri])
+		}
+	}
+
+	// formulas depending on a missing value are left out
+	if results := set.Eval(map[string]any{"field:price": 1.0}, now); len(results) != 0 {
+		t.Errorf("Expected no results without a due date, got %v", results)
+	}
+}
@@ -0,0 +1,194 @@
+package formula
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bamdadam/backend/graph/model"
+)
+
+type function struct {
+	// check validates the argument types and returns the result type.
+	check func(args []model.FieldType) (model.FieldType, error)
+	eval  func(args []any, now time.Time) any
+	// nullable functions are called with nil arguments, the others evaluate
+	// to nil if any argument is nil.
+	nullable bool
+}
+
+var (
+	num     = model.FieldTypeNumber
+	text    = model.FieldTypeText
+	date    = model.FieldTypeDate
+	boolean = model.FieldTypeBoolean
+)
+
+var functions = map[string]*function{
+	"now": {
+		check: signature(date),
+		eval:  func(_ []any, now time.Time) any { return now },
+	},
+	"today": {
+		check: signature(date),
+		eval:  func(_ []any, now time.Time) any { return now.UTC().Truncate(day) },
+	},
+	"if": {
+		check: func(args []model.FieldType) (model.FieldType, error) {
+			if len(args) != 3 {
+				return "", fmt.Errorf("expected 3 arguments, got %d", len(args))
+			}
+			if args[0] != boolean {
+				return "", fmt.Errorf("condition must be boolean, got %s", typeName(args[0]))
+			}
+			if args[1] != args[2] {
+				return "", fmt.Errorf("branches must be of the same type, got %s and %s", typeName(args[1]), typeName(args[2]))
+			}
+			return args[1], nil
+		},
+		// a missing condition counts as false
+		eval: func(args []any, _ time.Time) any {
+			if cond, _ := args[0].(bool); cond {
+				return args[1]
+			}
+			return args[2]
+		},
+		nullable: true,
+	},
+	"coalesce": {
+		check: func(args []model.FieldType) (model.FieldType, error) {
+			if len(args) == 0 {
+				return "", fmt.Errorf("expected at least 1 argument")
+			}
+			for _, t := range args[1:] {
+				if t != args[0] {
+					return "", fmt.Errorf("arguments must be of the same type, got %s and %s", typeName(args[0]), typeName(t))
+				}
+			}
+			return args[0], nil
+		},
+		eval: func(args []any, _ time.Time) any {
+			for _, arg := range args {
+				if arg != nil {
+					return arg
+				}
+			}
+			return nil
+		},
+		nullable: true,
+	},
+	"abs":   numberFunc(math.Abs),
+	"floor": numberFunc(math.Floor),
+	"ceil":  numberFunc(math.Ceil),
+	"round": {
+		check: func(args []model.FieldType) (model.FieldType, error) {
+			if len(args) == 2 {
+				return signature(num, num, num)(args)
+			}
+			return signature(num, num)(args)
+		},
+		eval: func(args []any, _ time.Time) any {
+			scale := 1.0
+			if len(args) == 2 {
+				scale = math.Pow(10, math.Trunc(args[1].(float64)))
+			}
+			return math.Round(args[0].(float64)*scale) / scale
+		},
+	},
+	"min": numberAggregate(math.Min),
+	"max": numberAggregate(math.Max),
+	"len": {
+		check: signature(num, text),
+		eval:  func(args []any, _ time.Time) any { return float64(utf8.RuneCountInString(args[0].(string))) },
+	},
+	"lower": textFunc(strings.ToLower),
+	"upper": textFunc(strings.ToUpper),
+	"trim":  textFunc(strings.TrimSpace),
+	"year":  dateFunc(func(t time.Time) int { return t.Year() }),
+	"month": dateFunc(func(t time.Time) int { return int(t.Month()) }),
+	"day":   dateFunc(func(t time.Time) int { return t.Day() }),
+	"text": {
+		check: func(args []model.FieldType) (model.FieldType, error) {
+			if len(args) != 1 {
+				return "", fmt.Errorf("expected 1 argument, got %d", len(args))
+			}
+			return text, nil
+		},
+		eval: func(args []any, _ time.Time) any {
+			switch v := args[0].(type) {
+			case float64:
+				return strconv.FormatFloat(v, 'f', -1, 64)
+			case bool:
+				return strconv.FormatBool(v)
+			case time.Time:
+				return model.FormatDateTime(v)
+			default:
+				return v
+			}
+		},
+	},
+}
+
+// signature checks for a fixed list of argument types.
+func signature(result model.FieldType, params ...model.FieldType) func([]model.FieldType) (model.FieldType, error) {
+	return func(args []model.FieldType) (model.FieldType, error) {
+		if len(args) != len(params) {
+			return "", fmt.Errorf("expected %d arguments, got %d", len(params), len(args))
+		}
+		for i, t := range args {
+			if t != params[i] {
+				return "", fmt.Errorf("argument %d must be %s, got %s", i+1, typeName(params[i]), typeName(t))
+			}
+		}
+		return result, nil
+	}
+}
+
+func numberFunc(fn func(float64) float64) *function {
+	return &function{
+		check: signature(num, num),
+		eval:  func(args []any, _ time.Time) any { return fn(args[0].(float64)) },
+	}
+}
+
+// numberAggregate reduces one or more numbers with fn.
+func numberAggregate(fn func(a, b float64) float64) *function {
+	return &function{
+		check: func(args []model.FieldType) (model.FieldType, error) {
+			if len(args) == 0 {
+				return "", fmt.Errorf("expected at least 1 argument")
+			}
+			for i, t := range args {
+				if t != num {
+					return "", fmt.Errorf("argument %d must be number, got %s", i+1, typeName(t))
+				}
+			}
+			return num, nil
+		},
+		eval: func(args []any, _ time.Time) any {
+			result := args[0].(float64)
+			for _, arg := range args[1:] {
+				result = fn(result, arg.(float64))
+			}
+			return result
+		},
+	}
+}
+
+func textFunc(fn func(string) string) *function {
+	return &function{
+		check: signature(text, text),
+		eval:  func(args []any, _ time.Time) any { return fn(args[0].(string)) },
+	}
+}
+
+// dateFunc extracts a component of a date in UTC.
+func dateFunc(fn func(time.Time) int) *function {
+	return &function{
+		check: signature(num, date),
+		eval:  func(args []any, _ time.Time) any { return float64(fn(args[0].(time.Time).UTC())) },
+	}
+}
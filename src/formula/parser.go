@@ -0,0 +1,307 @@
+package formula
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bamdadam/backend/graph/model"
+)
+
+// Error is a syntax or type error in an expression.
+type Error struct {
+	// Pos is the byte offset in the expression the error was found at.
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos+1)
+}
+
+func errorf(pos int, format string, args ...any) *Error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Ref is a field referenced by an identifier in an expression.
+type Ref struct {
+	// Key identifies the field when the expression is evaluated.
+	Key  string
+	Type model.FieldType
+}
+
+// Resolver looks up the field an identifier refers to.
+type Resolver func(name string) (Ref, error)
+
+// Expr is a parsed and type checked expression.
+type Expr struct {
+	// Type is the type of the values the expression evaluates to, one of
+	// TEXT, NUMBER, DATE or BOOLEAN.
+	Type model.FieldType
+	// Refs holds the keys of the referenced fields.
+	Refs []string
+
+	root node
+}
+
+// Compile parses an expression and checks its types against the fields it
+// references.
+func Compile(expr string, resolve Resolver) (*Expr, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, resolve: resolve}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, errorf(tok.pos, "unexpected %q", tok.text)
+	}
+
+	return &Expr{Type: root.t, Refs: p.refs, root: root.n}, nil
+}
+
+// typed is a node along with the type it evaluates to.
+type typed struct {
+	n node
+	t model.FieldType
+}
+
+type parser struct {
+	tokens  []token
+	next    int
+	resolve Resolver
+	refs    []string
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) advance() token {
+	tok := p.tokens[p.next]
+	if tok.kind != tokenEOF {
+		p.next++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is one of the given punctuation.
+func (p *parser) accept(puncts ...string) (token, bool) {
+	tok := p.peek()
+	if tok.kind == tokenPunct {
+		for _, punct := range puncts {
+			if tok.text == punct {
+				return p.advance(), true
+			}
+		}
+	}
+	return tok, false
+}
+
+func (p *parser) expect(punct string) error {
+	if tok, ok := p.accept(punct); !ok {
+		return errorf(tok.pos, "expected %q", punct)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (typed, error) {
+	x, err := p.parseAnd()
+	if err != nil {
+		return typed{}, err
+	}
+	for {
+		op, ok := p.accept("||")
+		if !ok {
+			return x, nil
+		}
+		y, err := p.parseAnd()
+		if err != nil {
+			return typed{}, err
+		}
+		if x, err = logicalOp(op, x, y); err != nil {
+			return typed{}, err
+		}
+	}
+}
+
+func (p *parser) parseAnd() (typed, error) {
+	x, err := p.parseComparison()
+	if err != nil {
+		return typed{}, err
+	}
+	for {
+		op, ok := p.accept("&&")
+		if !ok {
+			return x, nil
+		}
+		y, err := p.parseComparison()
+		if err != nil {
+			return typed{}, err
+		}
+		if x, err = logicalOp(op, x, y); err != nil {
+			return typed{}, err
+		}
+	}
+}
+
+// parseComparison parses a single comparison, chains like a < b < c are
+// rejected by the types as a comparison yields a BOOLEAN.
+func (p *parser) parseComparison() (typed, error) {
+	x, err := p.parseAdditive()
+	if err != nil {
+		return typed{}, err
+	}
+	op, ok := p.accept("==", "!=", "<", "<=", ">", ">=")
+	if !ok {
+		return x, nil
+	}
+	y, err := p.parseAdditive()
+	if err != nil {
+		return typed{}, err
+	}
+	return comparisonOp(op, x, y)
+}
+
+func (p *parser) parseAdditive() (typed, error) {
+	x, err := p.parseMultiplicative()
+	if err != nil {
+		return typed{}, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return x, nil
+		}
+		y, err := p.parseMultiplicative()
+		if err != nil {
+			return typed{}, err
+		}
+		if x, err = arithmeticOp(op, x, y); err != nil {
+			return typed{}, err
+		}
+	}
+}
+
+func (p *parser) parseMultiplicative() (typed, error) {
+	x, err := p.parseUnary()
+	if err != nil {
+		return typed{}, err
+	}
+	for {
+		op, ok := p.accept("*", "/", "%")
+		if !ok {
+			return x, nil
+		}
+		y, err := p.parseUnary()
+		if err != nil {
+			return typed{}, err
+		}
+		if x, err = arithmeticOp(op, x, y); err != nil {
+			return typed{}, err
+		}
+	}
+}
+
+func (p *parser) parseUnary() (typed, error) {
+	op, ok := p.accept("-", "!")
+	if !ok {
+		return p.parsePrimary()
+	}
+	x, err := p.parseUnary()
+	if err != nil {
+		return typed{}, err
+	}
+	return unaryOp(op, x)
+}
+
+func (p *parser) parsePrimary() (typed, error) {
+	tok := p.advance()
+	switch tok.kind {
+	case tokenNumber:
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return typed{}, errorf(tok.pos, "invalid number %q", tok.text)
+		}
+		return typed{n: literal{v}, t: model.FieldTypeNumber}, nil
+	case tokenString:
+		return typed{n: literal{tok.text}, t: model.FieldTypeText}, nil
+	case tokenIdent:
+		switch strings.ToLower(tok.text) {
+		case "true":
+			return typed{n: literal{true}, t: model.FieldTypeBoolean}, nil
+		case "false":
+			return typed{n: literal{false}, t: model.FieldTypeBoolean}, nil
+		}
+		if _, ok := p.accept("("); ok {
+			return p.parseCall(tok)
+		}
+		ref, err := p.resolve(tok.text)
+		if err != nil {
+			return typed{}, &Error{Pos: tok.pos, Msg: err.Error()}
+		}
+		p.refs = append(p.refs, ref.Key)
+		return typed{n: fieldRef{ref.Key}, t: ref.Type}, nil
+	case tokenPunct:
+		if tok.text == "(" {
+			x, err := p.parseOr()
+			if err != nil {
+				return typed{}, err
+			}
+			if err := p.expect(")"); err != nil {
+				return typed{}, err
+			}
+			return x, nil
+		}
+		return typed{}, errorf(tok.pos, "unexpected %q", tok.text)
+	default:
+		return typed{}, errorf(tok.pos, "unexpected end of expression")
+	}
+}
+
+// parseCall parses the arguments of a function call, the opening parenthesis
+// has already been consumed.
+func (p *parser) parseCall(name token) (typed, error) {
+	fn, ok := functions[strings.ToLower(name.text)]
+	if !ok {
+		return typed{}, errorf(name.pos, "unknown function %s", name.text)
+	}
+
+	var args []typed
+	if _, ok := p.accept(")"); !ok {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return typed{}, err
+			}
+			args = append(args, arg)
+			if _, ok := p.accept(","); !ok {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return typed{}, err
+		}
+	}
+
+	types := make([]model.FieldType, len(args))
+	nodes := make([]node, len(args))
+	for i, arg := range args {
+		types[i], nodes[i] = arg.t, arg.n
+	}
+	t, err := fn.check(types)
+	if err != nil {
+		return typed{}, errorf(name.pos, "%s: %s", strings.ToLower(name.text), err)
+	}
+
+	return typed{n: call{fn: fn, args: nodes}, t: t}, nil
+}
+
+// typeName is how types appear in error messages.
+func typeName(t model.FieldType) string {
+	return strings.ToLower(string(t))
+}
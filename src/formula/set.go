@@ -0,0 +1,301 @@
+package formula
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/bamdadam/backend/graph/model"
+	models "github.com/bamdadam/backend/src/model"
+)
+
+// Set holds the compiled FORMULA fields of a type.
+type Set struct {
+	// fields are ordered so that formulas come after the formulas they
+	// reference.
+	fields []*formulaField
+	types  map[string]model.FieldType
+	// errs holds why the formulas that can't be computed are invalid.
+	errs map[string]error
+	// stored holds the types of the other fields, to convert their values
+	// for evaluation.
+	stored map[string]model.FieldType
+}
+
+type formulaField struct {
+	def  *models.FieldDefinition
+	expr *Expr
+}
+
+// CycleError is returned for formulas referencing themselves, directly or
+// through other formulas.
+type CycleError struct {
+	// Path holds the names of the fields in the cycle, starting and ending
+	// with the same field.
+	Path []string
+
+	uris []string
+}
+
+func (e *CycleError) Error() string {
+	return "formula fields form a cycle: " + strings.Join(e.Path, " -> ")
+}
+
+// Ident is the identifier a field is referenced by in expressions: its name
+// in lower case with words joined by underscores, e.g. due_date for
+// "Due Date".
+func Ident(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r))
+	})
+	return strings.Join(words, "_")
+}
+
+// CompileType compiles the FORMULA fields among the fields of a type.
+// Formulas may reference TEXT, NUMBER, DATE, BOOLEAN, SELECT, URL and EMAIL
+// fields as well as other formulas. Invalid formulas, and the formulas
+// referencing them, are left out of the set with their error.
+func CompileType(fields []*models.FieldDefinition) *Set {
+	byIdent := make(map[string][]*models.FieldDefinition)
+	for _, f := range fields {
+		id := Ident(f.Name)
+		byIdent[id] = append(byIdent[id], f)
+	}
+
+	lookup := func(name string) (*models.FieldDefinition, error) {
+		matches := byIdent[Ident(name)]
+		switch len(matches) {
+		case 0:
+			return nil, fmt.Errorf("unknown field %s", name)
+		case 1:
+			return matches[0], nil
+		default:
+			return nil, fmt.Errorf("field name %s is ambiguous", name)
+		}
+	}
+
+	c := &compiler{
+		lookup: lookup,
+		state:  make(map[string]int),
+		set: &Set{
+			types:  make(map[string]model.FieldType),
+			errs:   make(map[string]error),
+			stored: make(map[string]model.FieldType),
+		},
+	}
+	for _, f := range fields {
+		if f.FieldType != model.FieldTypeFormula {
+			c.set.stored[f.URI] = f.FieldType
+			continue
+		}
+		c.visit(f, nil)
+	}
+	return c.set
+}
+
+const (
+	unvisited = iota
+	visiting
+	visited
+)
+
+// compiler compiles formulas depth first, so the formulas a formula
+// references are compiled and their types known before it is.
+type compiler struct {
+	lookup func(name string) (*models.FieldDefinition, error)
+	state  map[string]int
+	set    *Set
+}
+
+// visit compiles a formula field after the formulas it references and
+// returns its error if it is invalid. path holds the formulas currently being
+// visited, to report cycles.
+func (c *compiler) visit(f *models.FieldDefinition, path []*models.FieldDefinition) error {
+	switch c.state[f.URI] {
+	case visited:
+		return c.set.errs[f.URI]
+	case visiting:
+		cycle := &CycleError{}
+		for i := len(path) - 1; i >= 0; i-- {
+			cycle.Path = append([]string{path[i].Name}, cycle.Path...)
+			cycle.uris = append(cycle.uris, path[i].URI)
+			if path[i].URI == f.URI {
+				break
+			}
+		}
+		cycle.Path = append(cycle.Path, f.Name)
+		return cycle
+	}
+	c.state[f.URI] = visiting
+
+	err := c.compile(f, append(path, f))
+	c.state[f.URI] = visited
+	if err != nil {
+		c.set.errs[f.URI] = err
+	}
+	return err
+}
+
+// compile compiles a formula field whose state is visiting.
+func (c *compiler) compile(f *models.FieldDefinition, path []*models.FieldDefinition) error {
+	opts, err := models.ParseFormulaOptions(f.Options)
+	if err != nil {
+		return fmt.Errorf("invalid formula of field %s: %w", f.Name, err)
+	}
+
+	deps, err := identifiers(opts.Expression)
+	if err != nil {
+		return fmt.Errorf("invalid formula of field %s: %w", f.Name, err)
+	}
+	for _, name := range deps {
+		dep, err := c.lookup(name)
+		if err != nil {
+			// reported with its position when compiling below
+			continue
+		}
+		if dep.FieldType != model.FieldTypeFormula {
+			continue
+		}
+		if err := c.visit(dep, path); err != nil {
+			// the fields of a cycle share its error, the formulas
+			// referencing them are invalid on their own
+			if cycle, ok := err.(*CycleError); ok && slices.Contains(cycle.uris, f.URI) {
+				return err
+			}
+			return fmt.Errorf("invalid formula of field %s: field %s can't be computed", f.Name, dep.Name)
+		}
+	}
+
+	expr, err := Compile(opts.Expression, func(name string) (Ref, error) {
+		dep, err := c.lookup(name)
+		if err != nil {
+			return Ref{}, err
+		}
+		t, err := c.refType(dep)
+		if err != nil {
+			return Ref{}, err
+		}
+		return Ref{Key: dep.URI, Type: t}, nil
+	})
+	if err != nil {
+		return fmt.Errorf("invalid formula of field %s: %w", f.Name, err)
+	}
+
+	c.set.fields = append(c.set.fields, &formulaField{def: f, expr: expr})
+	c.set.types[f.URI] = expr.Type
+	return nil
+}
+
+// refType is the type a field has in expressions.
+func (c *compiler) refType(f *models.FieldDefinition) (model.FieldType, error) {
+	switch f.FieldType {
+	case model.FieldTypeNumber, model.FieldTypeDate, model.FieldTypeBoolean:
+		return f.FieldType, nil
+	case model.FieldTypeText, model.FieldTypeSelect, model.FieldTypeURL, model.FieldTypeEmail:
+		return model.FieldTypeText, nil
+	case model.FieldTypeFormula:
+		return c.set.types[f.URI], nil
+	default:
+		return "", fmt.Errorf("%s fields like %s can't be used in formulas", typeName(f.FieldType), f.Name)
+	}
+}
+
+// identifiers returns the names of the fields an expression references,
+// without parsing it.
+func identifiers(expr string) ([]string, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for i, tok := range tokens {
+		if tok.kind != tokenIdent {
+			continue
+		}
+		if next := tokens[i+1]; next.kind == tokenPunct && next.text == "(" {
+			continue
+		}
+		if lower := strings.ToLower(tok.text); lower == "true" || lower == "false" {
+			continue
+		}
+		names = append(names, tok.text)
+	}
+	return names, nil
+}
+
+// ResultType returns the type of the values of a formula field.
+func (s *Set) ResultType(fieldURI string) (model.FieldType, bool) {
+	t, ok := s.types[fieldURI]
+	return t, ok
+}
+
+// Err returns why a formula field can't be computed, nil if its formula is
+// valid.
+func (s *Set) Err(fieldURI string) error {
+	return s.errs[fieldURI]
+}
+
+// Empty tells whether the type has no formula fields.
+func (s *Set) Empty() bool {
+	return len(s.fields) == 0
+}
+
+// Eval computes the formula fields of an element from the values of its other
+// fields. Values are keyed by field URI and use the representation field
+// values are loaded with, dates being DateTime strings. Formulas that can't be
+// computed, e.g. because a field they reference has no value, are left out.
+func (s *Set) Eval(values map[string]any, now time.Time) map[string]any {
+	// inputs holds the values in the representation expressions work with
+	inputs := make(map[string]any, len(values))
+	for uri, v := range values {
+		inputs[uri] = input(s.stored[uri], v)
+	}
+	lookup := func(key string) any { return inputs[key] }
+
+	results := make(map[string]any, len(s.fields))
+	for _, f := range s.fields {
+		v := f.expr.Eval(lookup, now)
+		if v == nil {
+			continue
+		}
+		inputs[f.def.URI] = v
+		if t, ok := v.(time.Time); ok {
+			v = model.FormatDateTime(t)
+		}
+		results[f.def.URI] = v
+	}
+	return results
+}
+
+// input converts a stored field value for evaluation, values that don't
+// match the type of their field are treated as missing.
+func input(t model.FieldType, v any) any {
+	switch t {
+	case model.FieldTypeDate:
+		s, ok := v.(string)
+		if !ok {
+			return nil
+		}
+		date, err := model.ParseDateTime(s)
+		if err != nil {
+			return nil
+		}
+		return date
+	case model.FieldTypeNumber:
+		if f, ok := v.(float64); ok {
+			return f
+		}
+	case model.FieldTypeBoolean:
+		if b, ok := v.(bool); ok {
+			return b
+		}
+	case model.FieldTypeText, model.FieldTypeSelect, model.FieldTypeURL, model.FieldTypeEmail:
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return nil
+}
@@ -2,33 +2,88 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/bamdadam/backend/src/model"
 )
 
 const AuthHeader string = "X-User-ID"
 
+var ErrMissingUserID = errors.New("X-User-ID header is required")
+
+// Auth authenticates plain HTTP requests, which covers both POST queries and
+// Server-Sent Events subscriptions. WebSocket upgrades are let through since
+// browsers can't set headers on them; they are authenticated by WebsocketInit.
 func Auth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, err := Authenticate(r.Context(), r.Header.Get(AuthHeader))
 		if isWebSocketUpgrade(r) {
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r.WithContext(ctx))
 			return
 		}
 
-		userID := r.Header.Get(AuthHeader)
-		if userID == "" {
+		if err != nil {
 			http.Error(w, `{"error":"X-User-ID header is required"}`, http.StatusUnauthorized)
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), model.UserIDKey, userID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// DefaultUser runs requests that don't identify their user as userID, for
+// local development without a client setting X-User-ID.
+func DefaultUser(userID string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(AuthHeader) == "" {
+			r.Header.Set(AuthHeader, userID)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// WebsocketInit authenticates graphql-ws and graphql-transport-ws connections
+// using the X-User-ID key of the connection_init payload, falling back to the
+// header of the upgrade request for clients that are able to set one.
+func WebsocketInit(ctx context.Context, initPayload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+	if userID := initPayload.GetString(AuthHeader); userID != "" {
+		ctx, err := Authenticate(ctx, userID)
+		return ctx, nil, err
+	}
+
+	if _, ok := ctx.Value(model.UserIDKey).(string); ok {
+		return ctx, nil, nil
+	}
+
+	return ctx, nil, errors.New("missing X-User-ID in websocket connection_init payload")
+}
+
+// Authenticate validates the user identifier presented by a client and makes
+// it available to resolvers through the context.
+func Authenticate(ctx context.Context, userID string) (context.Context, error) {
+	if userID == "" {
+		return ctx, ErrMissingUserID
+	}
+	return context.WithValue(ctx, model.UserIDKey, userID), nil
+}
+
 func isWebSocketUpgrade(r *http.Request) bool {
-	return strings.EqualFold(r.Header.Get("Connection"), "Upgrade") &&
+	return headerContainsToken(r.Header, "Connection", "Upgrade") &&
 		strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
 }
+
+// headerContainsToken reports whether a comma separated header such as
+// "Connection: keep-alive, Upgrade" contains the given token.
+func headerContainsToken(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORS lets browsers on the allowed origins call the API, "*" allowing any
+// origin. Preflight requests are answered here without reaching next.
+func CORS(allowedOrigins []string, maxAge time.Duration, next http.Handler) http.Handler {
+	allowHeaders := strings.Join([]string{"Content-Type", AuthHeader, RequestIDHeader}, ", ")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !OriginAllowed(allowedOrigins, origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Set("Access-Control-Expose-Headers", RequestIDHeader)
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+			h.Set("Access-Control-Allow-Headers", allowHeaders)
+			if maxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(int(maxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// OriginAllowed reports whether origin is one of the allowed origins.
+func OriginAllowed(allowedOrigins []string, origin string) bool {
+	return slices.Contains(allowedOrigins, "*") || slices.ContainsFunc(allowedOrigins, func(allowed string) bool {
+		return strings.EqualFold(allowed, origin)
+	})
+}
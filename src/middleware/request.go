@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/bamdadam/backend/src/model"
+)
+
+const RequestIDHeader string = "X-Request-ID"
+
+// RequestInfo tags every request with a request ID and the client IP address
+// so they can be recorded in the audit log and in error logs. An X-Request-ID
+// sent by the client or a proxy in front of us is kept, otherwise one is
+// generated. The ID is echoed back in the response headers. X-Forwarded-For is
+// only read from the reverse proxies of trustedProxies.
+func RequestInfo(trustedProxies []netip.Prefix, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		ctx := context.WithValue(r.Context(), model.RequestIDKey, requestID)
+		ctx = context.WithValue(ctx, model.ClientIPKey, clientIP(r, trustedProxies))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// clientIP returns the originating address of a request. Each proxy appends
+// the address it was called from to X-Forwarded-For, so when the peer is a
+// trusted proxy the hops are walked from the right, and the first one that
+// isn't a trusted proxy is the client. Anything left of it may have been
+// forged by the client.
+func clientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !trusted(trustedProxies, addr.Unmap()) {
+		return host
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	client := addr.Unmap()
+	for _, hop := range slices.Backward(hops) {
+		hopAddr, err := netip.ParseAddr(strings.TrimSpace(hop))
+		if err != nil {
+			// the last trusted proxy is the closest we know of
+			break
+		}
+		client = hopAddr.Unmap()
+		if !trusted(trustedProxies, client) {
+			break
+		}
+	}
+	return client.String()
+}
+
+func trusted(proxies []netip.Prefix, addr netip.Addr) bool {
+	return slices.ContainsFunc(proxies, func(p netip.Prefix) bool { return p.Contains(addr) })
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
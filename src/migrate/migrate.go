@@ -0,0 +1,311 @@
+// Package migrate applies the versioned migrations of the database schema.
+//
+// Migrations are embedded from migrations/, in pairs of files named
+// <version>_<name>.up.sql and <version>_<name>.down.sql. Each is applied in its
+// own transaction along with its row in schema_migrations, and runs hold an
+// advisory lock so that replicas starting at once don't apply them twice.
+//
+// Migrations up to BaselineVersion are the former container init scripts,
+// kept as they were. Later changes to their tables are made by new migrations.
+package migrate
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var files embed.FS
+
+// lockID is the key of the advisory lock held while migrating.
+const lockID int64 = 727052419
+
+// BaselineVersion is the last migration of the schema the former container
+// init scripts created. Databases holding that schema without any migration
+// recorded are adopted: the migrations up to it are recorded as applied
+// without being run.
+const BaselineVersion int64 = 11
+
+// baselineTable is the last table created by the baseline migrations.
+const baselineTable = "public.element_field_values"
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is a version of the schema.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is the state of a migration in the database.
+type Status struct {
+	Version int64
+	Name    string
+	// Applied is false for pending migrations.
+	Applied     bool
+	AppliedDate int64
+	// Unknown is true for migrations applied to the database but missing
+	// from this build, usually applied by a newer one.
+	Unknown bool
+}
+
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []*Migration
+}
+
+func New(db *pgxpool.Pool) (*Migrator, error) {
+	migrations, err := load(files)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// load reads the migrations of fsys, sorted by version.
+func load(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version %q", match[1])
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", m.Name, match[2], version)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+	slices.SortFunc(migrations, func(a, b *Migration) int {
+		return int(a.Version - b.Version)
+	})
+	return migrations, nil
+}
+
+// Up applies the pending migrations up to version target, or all of them if
+// target is 0, and returns those it applied.
+func (m *Migrator) Up(ctx context.Context, target int64) ([]*Migration, error) {
+	var applied []*Migration
+	err := m.locked(ctx, func(conn *pgxpool.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if len(versions) == 0 {
+			if err := m.adoptBaseline(ctx, conn, versions); err != nil {
+				return err
+			}
+		}
+
+		for _, migration := range m.migrations {
+			if target > 0 && migration.Version > target {
+				break
+			}
+			if _, ok := versions[migration.Version]; ok {
+				continue
+			}
+
+			err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, migration.Up); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, name, applied_date) VALUES ($1, $2, $3)`,
+					migration.Version, migration.Name, time.Now().UnixMilli())
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations, and returns those it
+// reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]*Migration, error) {
+	var reverted []*Migration
+	err := m.locked(ctx, func(conn *pgxpool.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := versions[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s can't be reverted, it has no down file", migration.Version, migration.Name)
+			}
+
+			err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, migration.Down); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to revert migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status returns the state of every migration, whether in this build or
+// applied to the database, sorted by version.
+func (m *Migrator) Status(ctx context.Context) ([]*Status, error) {
+	var statuses []*Status
+	err := m.locked(ctx, func(conn *pgxpool.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := &Status{Version: migration.Version, Name: migration.Name}
+			if s, ok := versions[migration.Version]; ok {
+				status.Applied = true
+				status.AppliedDate = s.AppliedDate
+				delete(versions, migration.Version)
+			}
+			statuses = append(statuses, status)
+		}
+		for _, s := range versions {
+			statuses = append(statuses, s)
+		}
+		return nil
+	})
+	slices.SortFunc(statuses, func(a, b *Status) int {
+		return int(a.Version - b.Version)
+	})
+	return statuses, err
+}
+
+// adoptBaseline records the migrations up to BaselineVersion as applied, adding
+// them to versions, when the database already holds the baseline schema.
+func (m *Migrator) adoptBaseline(ctx context.Context, conn *pgxpool.Conn, versions map[int64]*Status) error {
+	var exists bool
+	if err := conn.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, baselineTable).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check for the baseline schema: %w", err)
+	}
+	if !exists {
+		return nil
+	}
+
+	now := time.Now().UnixMilli()
+	return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		for _, migration := range m.migrations {
+			if migration.Version > BaselineVersion {
+				break
+			}
+			_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, name, applied_date) VALUES ($1, $2, $3)`,
+				migration.Version, migration.Name, now)
+			if err != nil {
+				return fmt.Errorf("failed to adopt migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			versions[migration.Version] = &Status{Version: migration.Version, Name: migration.Name, Applied: true, AppliedDate: now}
+		}
+		return nil
+	})
+}
+
+// locked runs fn on a connection holding the migration lock, creating the
+// schema_migrations table if needed.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+
+	query := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_date BIGINT NOT NULL
+		)
+	`
+	if _, err := conn.Exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// appliedVersions returns the migrations applied to the database by version,
+// flagged as unknown until matched with those of the build.
+func appliedVersions(ctx context.Context, conn *pgxpool.Conn) (map[int64]*Status, error) {
+	rows, err := conn.Query(ctx, `SELECT version, name, applied_date FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	versions := make(map[int64]*Status)
+	for rows.Next() {
+		s := &Status{Applied: true, Unknown: true}
+		if err := rows.Scan(&s.Version, &s.Name, &s.AppliedDate); err != nil {
+			return nil, fmt.Errorf("failed to scan migration: %w", err)
+		}
+		versions[s.Version] = s
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating migrations: %w", err)
+	}
+	return versions, nil
+}
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS public.element_revisions (
+    id BIGSERIAL PRIMARY KEY,
+    element_uri TEXT NOT NULL REFERENCES public.elements(uri) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    field_values JSONB NOT NULL,
+    author TEXT NOT NULL REFERENCES public.users(uri),
+    creation_date BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_element_revisions_element ON public.element_revisions(element_uri, creation_date);
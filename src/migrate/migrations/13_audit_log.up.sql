@@ -0,0 +1,31 @@
+-- Entries deliberately carry no foreign keys, they have to outlive the users,
+-- tenants and elements they refer to.
+CREATE TABLE IF NOT EXISTS public.audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    user_uri TEXT NOT NULL,
+    action TEXT NOT NULL,
+    target_uri TEXT NOT NULL,
+    tenant_uri TEXT NOT NULL,
+    request_id TEXT,
+    client_ip TEXT,
+    diff JSONB,
+    creation_date BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_tenant ON public.audit_log(tenant_uri, id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_creation_date ON public.audit_log(creation_date);
+
+-- The audit log is append-only. Rows can't be updated, and can only be deleted
+-- by the retention purge, which sets audit.purge for its transaction.
+CREATE OR REPLACE FUNCTION public.audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' AND current_setting('audit.purge', true) = 'on' THEN
+        RETURN OLD;
+    END IF;
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE ON public.audit_log
+    FOR EACH ROW EXECUTE FUNCTION public.audit_log_append_only();
@@ -0,0 +1,12 @@
+-- Values of relation fields. A relation field's options hold the URI of the
+-- type it links to and whether it links to a single or multiple elements.
+CREATE TABLE IF NOT EXISTS public.element_links (
+    source_element_uri TEXT NOT NULL REFERENCES public.elements(uri) ON DELETE CASCADE,
+    field_uri TEXT NOT NULL REFERENCES public.fields(uri) ON DELETE CASCADE,
+    target_element_uri TEXT NOT NULL REFERENCES public.elements(uri) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    creation_date BIGINT NOT NULL,
+    PRIMARY KEY (source_element_uri, field_uri, target_element_uri)
+);
+
+CREATE INDEX IF NOT EXISTS idx_element_links_target ON public.element_links(target_element_uri, field_uri);
@@ -0,0 +1,6 @@
+-- Last number handed out by auto-increment fields. Incremented within the
+-- transaction creating the element, so numbers never collide.
+CREATE TABLE IF NOT EXISTS public.field_sequences (
+    field_uri TEXT PRIMARY KEY REFERENCES public.fields(uri) ON DELETE CASCADE,
+    value BIGINT NOT NULL
+);
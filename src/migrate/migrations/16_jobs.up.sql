@@ -0,0 +1,27 @@
+-- Queue of background jobs. Workers claim queued jobs with FOR UPDATE SKIP
+-- LOCKED and keep locked_until in the future while they run them, so that the
+-- jobs of a worker that died are claimed again once its lease expires.
+CREATE TABLE IF NOT EXISTS public.jobs (
+    id TEXT PRIMARY KEY,
+    kind TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    user_uri TEXT NOT NULL REFERENCES public.users(uri) ON DELETE CASCADE,
+    -- queued, running, succeeded, failed or cancelled
+    status TEXT NOT NULL DEFAULT 'queued',
+    progress INTEGER NOT NULL DEFAULT 0,
+    total INTEGER,
+    result JSONB,
+    error TEXT,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL,
+    cancel_requested BOOLEAN NOT NULL DEFAULT false,
+    run_after BIGINT NOT NULL,
+    locked_until BIGINT,
+    creation_date BIGINT NOT NULL,
+    started_date BIGINT,
+    finished_date BIGINT,
+    updated_date BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_queued ON public.jobs(run_after) WHERE status = 'queued';
+CREATE INDEX IF NOT EXISTS idx_jobs_running ON public.jobs(locked_until) WHERE status = 'running';
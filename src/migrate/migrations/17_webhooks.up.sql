@@ -0,0 +1,41 @@
+-- Webhooks called on element events of a space, optionally restricted to a
+-- type. The secret signs the payloads sent to url.
+CREATE TABLE IF NOT EXISTS public.webhooks (
+    uri TEXT PRIMARY KEY,
+    space_uri TEXT NOT NULL REFERENCES public.spaces(uri) ON DELETE CASCADE,
+    type_uri TEXT REFERENCES public.types(uri) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    -- element.created, element.updated or element.deleted
+    events TEXT[] NOT NULL,
+    secret TEXT NOT NULL,
+    author TEXT NOT NULL REFERENCES public.users(uri),
+    creation_date BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_space ON public.webhooks(space_uri);
+
+-- Outbox of webhook calls, written in the same transaction as the element
+-- mutation causing them. Workers claim pending deliveries with FOR UPDATE SKIP
+-- LOCKED and keep locked_until in the future while they send them.
+CREATE TABLE IF NOT EXISTS public.webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    webhook_uri TEXT NOT NULL REFERENCES public.webhooks(uri) ON DELETE CASCADE,
+    -- shared by the deliveries of the same event, for receivers to deduplicate
+    event_id TEXT NOT NULL,
+    event TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    -- pending, succeeded or failed
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt BIGINT NOT NULL,
+    locked_until BIGINT,
+    response_status INTEGER,
+    response_body TEXT,
+    error TEXT,
+    creation_date BIGINT NOT NULL,
+    delivered_date BIGINT,
+    updated_date BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending ON public.webhook_deliveries(next_attempt) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON public.webhook_deliveries(webhook_uri, id);
@@ -0,0 +1,20 @@
+-- Events written in the same transaction as the mutation causing them, and
+-- relayed to subscribers and webhooks once it has committed. tx_id is the
+-- transaction that wrote the event, which relays compare to database
+-- snapshots to find the events committed since they last looked.
+CREATE TABLE IF NOT EXISTS public.outbox (
+    id BIGSERIAL PRIMARY KEY,
+    -- sent along with the event, for consumers to deduplicate
+    event_id TEXT NOT NULL UNIQUE,
+    topic TEXT NOT NULL,
+    aggregate_uri TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    tx_id xid8 NOT NULL DEFAULT pg_current_xact_id(),
+    creation_date BIGINT NOT NULL,
+    -- when the event was handed to webhooks
+    dispatched_date BIGINT
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_undispatched ON public.outbox(id) WHERE dispatched_date IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_aggregate ON public.outbox(aggregate_uri, tx_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_dispatched ON public.outbox(dispatched_date);
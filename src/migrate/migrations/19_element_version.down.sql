@@ -0,0 +1 @@
+ALTER TABLE public.elements DROP COLUMN IF EXISTS version;
@@ -0,0 +1,3 @@
+-- Version of elements, incremented by every write for optimistic concurrency
+-- control.
+ALTER TABLE public.elements ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1 @@
+ALTER TABLE public.user_tenants DROP COLUMN IF EXISTS role;
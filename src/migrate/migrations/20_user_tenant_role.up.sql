@@ -0,0 +1,2 @@
+-- Role of users in their tenants, admins can read the audit log.
+ALTER TABLE public.user_tenants ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'member' CHECK (role IN ('member', 'admin'));
@@ -0,0 +1,4 @@
+-- Fails while fields of the removed types are left.
+ALTER TABLE public.fields
+    DROP CONSTRAINT IF EXISTS fields_field_type_check,
+    ADD CONSTRAINT fields_field_type_check CHECK (field_type IN ('text', 'number', 'date', 'boolean', 'select', 'multi_select', 'url', 'email'));
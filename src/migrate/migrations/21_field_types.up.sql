@@ -0,0 +1,4 @@
+-- Relation, formula and rollup fields.
+ALTER TABLE public.fields
+    DROP CONSTRAINT IF EXISTS fields_field_type_check,
+    ADD CONSTRAINT fields_field_type_check CHECK (field_type IN ('text', 'number', 'date', 'boolean', 'select', 'multi_select', 'url', 'email', 'relation', 'formula', 'rollup'));
@@ -0,0 +1 @@
+ALTER TABLE public.fields DROP COLUMN IF EXISTS constraints;
@@ -0,0 +1,2 @@
+-- Validation constraints and default values of fields.
+ALTER TABLE public.fields ADD COLUMN IF NOT EXISTS constraints JSONB DEFAULT NULL;
@@ -0,0 +1,4 @@
+-- Verbs still granted are kept.
+DELETE FROM public.permission_verbs v
+WHERE v.uri IN ('verb:read', 'verb:write', 'verb:delete', 'verb:admin')
+    AND NOT EXISTS (SELECT 1 FROM public.user_space_permissions p WHERE p.verb_uri = v.uri);
@@ -0,0 +1,7 @@
+-- The verbs checked by the API
+INSERT INTO public.permission_verbs (uri, name, description) VALUES
+    ('verb:read', 'read', 'Read access to resources'),
+    ('verb:write', 'write', 'Write access to resources'),
+    ('verb:delete', 'delete', 'Delete access to resources'),
+    ('verb:admin', 'admin', 'Full administrative access')
+ON CONFLICT (uri) DO NOTHING;
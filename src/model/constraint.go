@@ -0,0 +1,181 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bamdadam/backend/graph/model"
+)
+
+const (
+	UniqueType  = "type"
+	UniqueSpace = "space"
+)
+
+// FieldConstraints is the validation configuration stored in the constraints
+// of a field. Each constraint only applies to some field types.
+type FieldConstraints struct {
+	// Unique rejects values another element of the field's type already
+	// has, among all of them or only the ones in the same space.
+	Unique string `json:"unique,omitempty"`
+	// Min and Max bound NUMBER values.
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+	// MinLength, MaxLength and Pattern apply to TEXT, URL and EMAIL values.
+	// Lengths are counted in characters, Pattern is a regular expression
+	// matching anywhere in the value unless anchored.
+	MinLength *int   `json:"minLength,omitempty"`
+	MaxLength *int   `json:"maxLength,omitempty"`
+	Pattern   string `json:"pattern,omitempty"`
+	// MinDate and MaxDate bound DATE values, as DateTime strings.
+	MinDate string `json:"minDate,omitempty"`
+	MaxDate string `json:"maxDate,omitempty"`
+	// EmailDomains restricts EMAIL values to addresses at these domains.
+	EmailDomains []string `json:"emailDomains,omitempty"`
+
+	pattern          *regexp.Regexp
+	minDate, maxDate *time.Time
+}
+
+// ParseFieldConstraints parses the constraints of a field of the given type,
+// rejecting constraints that don't apply to it. Fields without constraints
+// get an empty FieldConstraints.
+func ParseFieldConstraints(fieldType model.FieldType, constraints *string) (*FieldConstraints, error) {
+	var c FieldConstraints
+	if constraints == nil {
+		return &c, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader([]byte(*constraints)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("failed to parse field constraints: %w", err)
+	}
+
+	textual := fieldType == model.FieldTypeText || fieldType == model.FieldTypeURL || fieldType == model.FieldTypeEmail
+	applies := []struct {
+		name string
+		set  bool
+		ok   bool
+	}{
+		{"unique", c.Unique != "", textual || fieldType == model.FieldTypeNumber || fieldType == model.FieldTypeDate || fieldType == model.FieldTypeSelect},
+		{"min", c.Min != nil, fieldType == model.FieldTypeNumber},
+		{"max", c.Max != nil, fieldType == model.FieldTypeNumber},
+		{"minLength", c.MinLength != nil, textual},
+		{"maxLength", c.MaxLength != nil, textual},
+		{"pattern", c.Pattern != "", textual},
+		{"minDate", c.MinDate != "", fieldType == model.FieldTypeDate},
+		{"maxDate", c.MaxDate != "", fieldType == model.FieldTypeDate},
+		{"emailDomains", c.EmailDomains != nil, fieldType == model.FieldTypeEmail},
+	}
+	for _, a := range applies {
+		if a.set && !a.ok {
+			return nil, fmt.Errorf("constraint %s does not apply to %s fields", a.name, strings.ToLower(string(fieldType)))
+		}
+	}
+
+	if c.Unique != "" && c.Unique != UniqueType && c.Unique != UniqueSpace {
+		return nil, fmt.Errorf("unknown unique scope %q", c.Unique)
+	}
+
+	if c.Pattern != "" {
+		re, err := regexp.Compile(c.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		c.pattern = re
+	}
+
+	for _, d := range []struct {
+		value string
+		dst   **time.Time
+	}{{c.MinDate, &c.minDate}, {c.MaxDate, &c.maxDate}} {
+		if d.value == "" {
+			continue
+		}
+		t, err := model.ParseDateTime(d.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date bound %q: %w", d.value, err)
+		}
+		*d.dst = &t
+	}
+
+	return &c, nil
+}
+
+// Check validates a value of the field against the constraints, except for
+// Unique which needs the other elements. Values are a string for TEXT, URL
+// and EMAIL, a float64 for NUMBER and a time.Time for DATE fields.
+func (c *FieldConstraints) Check(fieldURI string, value any) []model.FieldError {
+	var errs []model.FieldError
+	fail := func(constraint, format string, args ...any) {
+		errs = append(errs, model.FieldError{FieldURI: fieldURI, Constraint: constraint, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch v := value.(type) {
+	case float64:
+		if c.Min != nil && v < *c.Min {
+			fail("min", "must be at least %s", formatNumber(*c.Min))
+		}
+		if c.Max != nil && v > *c.Max {
+			fail("max", "must be at most %s", formatNumber(*c.Max))
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if c.MinLength != nil && length < *c.MinLength {
+			fail("minLength", "must be at least %d characters long", *c.MinLength)
+		}
+		if c.MaxLength != nil && length > *c.MaxLength {
+			fail("maxLength", "must be at most %d characters long", *c.MaxLength)
+		}
+		if c.pattern != nil && !c.pattern.MatchString(v) {
+			fail("pattern", "must match %s", c.Pattern)
+		}
+		if c.EmailDomains != nil {
+			_, domain, _ := strings.Cut(v, "@")
+			if !slices.ContainsFunc(c.EmailDomains, func(d string) bool { return strings.EqualFold(d, domain) }) {
+				fail("emailDomains", "must be an address at %s", strings.Join(c.EmailDomains, ", "))
+			}
+		}
+	case time.Time:
+		if c.minDate != nil && v.Before(*c.minDate) {
+			fail("minDate", "must not be before %s", c.MinDate)
+		}
+		if c.maxDate != nil && v.After(*c.maxDate) {
+			fail("maxDate", "must not be after %s", c.MaxDate)
+		}
+	}
+	return errs
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// ParseSelectOptions parses the options of a SELECT or MULTI_SELECT field,
+// stored either as a list or as an object holding the list under options.
+func ParseSelectOptions(options *string) ([]string, error) {
+	if options == nil {
+		return nil, nil
+	}
+
+	var opts []string
+	if err := json.Unmarshal([]byte(*options), &opts); err == nil {
+		return opts, nil
+	}
+
+	var wrapped struct {
+		Options []string `json:"options"`
+	}
+	if err := json.Unmarshal([]byte(*options), &wrapped); err != nil {
+		return nil, fmt.Errorf("failed to parse select options: %w", err)
+	}
+	return wrapped.Options, nil
+}
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bamdadam/backend/graph/model"
+)
+
+const (
+	AutogenToday       = "today"
+	AutogenNow         = "now"
+	AutogenCurrentUser = "currentUser"
+	AutogenSequence    = "sequence"
+)
+
+// FieldDefaults is the configuration stored in the options of a field to
+// fill in its value when an element is created without one. SELECT and
+// MULTI_SELECT fields hold it next to their list of options, e.g.
+// {"options": ["Low", "High"], "default": "Low"}.
+type FieldDefaults struct {
+	// Default is a fixed value, in the representation of field value inputs.
+	Default any `json:"default,omitempty"`
+	// Autogen generates the value instead: today or now for DATE fields,
+	// currentUser for TEXT fields (the user's URI) and EMAIL fields (the
+	// user's email), or sequence for TEXT and NUMBER fields.
+	Autogen string `json:"autogen,omitempty"`
+	// Prefix and Digits format the numbers of TEXT sequences, e.g. TICKET-
+	// and 4 for TICKET-0042.
+	Prefix string `json:"prefix,omitempty"`
+	Digits int    `json:"digits,omitempty"`
+}
+
+// ParseFieldDefaults parses the defaults in the options of a field of the
+// given type. Fields without defaults, including SELECT fields whose options
+// are a plain list, get an empty FieldDefaults.
+func ParseFieldDefaults(fieldType model.FieldType, options *string) (*FieldDefaults, error) {
+	var d FieldDefaults
+	if options == nil || strings.HasPrefix(strings.TrimSpace(*options), "[") {
+		return &d, nil
+	}
+	if err := json.Unmarshal([]byte(*options), &d); err != nil {
+		return nil, fmt.Errorf("failed to parse field defaults: %w", err)
+	}
+
+	if d.Empty() {
+		return &d, nil
+	}
+	switch fieldType {
+	case model.FieldTypeRelation, model.FieldTypeFormula, model.FieldTypeRollup:
+		return nil, fmt.Errorf("%s fields can't have defaults", strings.ToLower(string(fieldType)))
+	}
+
+	if d.Default != nil && d.Autogen != "" {
+		return nil, fmt.Errorf("default and autogen are mutually exclusive")
+	}
+
+	ok := true
+	switch d.Autogen {
+	case "":
+	case AutogenToday, AutogenNow:
+		ok = fieldType == model.FieldTypeDate
+	case AutogenCurrentUser:
+		ok = fieldType == model.FieldTypeText || fieldType == model.FieldTypeEmail
+	case AutogenSequence:
+		ok = fieldType == model.FieldTypeText || fieldType == model.FieldTypeNumber
+	default:
+		return nil, fmt.Errorf("unknown autogen %q", d.Autogen)
+	}
+	if !ok {
+		return nil, fmt.Errorf("autogen %s does not apply to %s fields", d.Autogen, strings.ToLower(string(fieldType)))
+	}
+
+	if d.Digits < 0 {
+		return nil, fmt.Errorf("digits must not be negative")
+	}
+	return &d, nil
+}
+
+// Empty tells whether the field has neither a default nor an autogen.
+func (d *FieldDefaults) Empty() bool {
+	return d.Default == nil && d.Autogen == ""
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// FormulaOptions is the configuration stored in the options of a FORMULA
+// field.
+type FormulaOptions struct {
+	Expression string `json:"expression"`
+}
+
+// ParseFormulaOptions parses the options of a FORMULA field.
+func ParseFormulaOptions(options *string) (*FormulaOptions, error) {
+	var opts FormulaOptions
+	if options != nil {
+		if err := json.Unmarshal([]byte(*options), &opts); err != nil {
+			return nil, fmt.Errorf("failed to parse formula options: %w", err)
+		}
+	}
+	if opts.Expression == "" {
+		return nil, fmt.Errorf("formula options are missing expression")
+	}
+	return &opts, nil
+}
@@ -1,10 +1,26 @@
 package model
 
-import "github.com/bamdadam/backend/graph/model"
+import (
+	"time"
+
+	"github.com/bamdadam/backend/graph/model"
+)
 
 type contextKey string
 
-const UserIDKey contextKey = "userID"
+// Permission verbs granted to users on spaces.
+const (
+	VerbRead   = "verb:read"
+	VerbWrite  = "verb:write"
+	VerbDelete = "verb:delete"
+	VerbAdmin  = "verb:admin"
+)
+
+const (
+	UserIDKey    contextKey = "userID"
+	RequestIDKey contextKey = "requestID"
+	ClientIPKey  contextKey = "clientIP"
+)
 
 type ListParams struct {
 	Limit            int32
@@ -12,6 +28,33 @@ type ListParams struct {
 	TypeURI          *string
 	SpaceURI         *string
 	FieldValueFilter *model.FieldValueFilter
+	RelationFilter   *model.RelationFilter
+	Conditions       []FieldCondition
+}
+
+type FilterOperator string
+
+const (
+	FilterEq       FilterOperator = "eq"
+	FilterGt       FilterOperator = "gt"
+	FilterGte      FilterOperator = "gte"
+	FilterLt       FilterOperator = "lt"
+	FilterLte      FilterOperator = "lte"
+	FilterIn       FilterOperator = "in"
+	FilterContains FilterOperator = "contains"
+)
+
+// FieldCondition restricts a list to elements with a value for FieldURI that
+// compares to Value under Operator. Dates are compared as epoch milliseconds.
+//
+// Conditions on RELATION fields match elements linked through the field to an
+// accessible element that satisfies all of Related.
+type FieldCondition struct {
+	FieldURI  string
+	FieldType model.FieldType
+	Operator  FilterOperator
+	Value     any
+	Related   []FieldCondition
 }
 
 type LoadRelationParams struct {
@@ -24,3 +67,173 @@ type ElemWithRelation struct {
 	*model.Element
 	LoadRelationParams
 }
+
+// ExportParams selects the elements of an export and its format.
+type ExportParams struct {
+	TypeURI  string               `json:"typeUri"`
+	SpaceURI *string              `json:"spaceUri,omitempty"`
+	Format   model.ExportFormat   `json:"format"`
+	Filter   *model.ElementFilter `json:"filter,omitempty"`
+}
+
+// ExportRow is an element as exported, with the values of the exported fields
+// by field URI. Values are in the representation of ElementFieldValue.value,
+// relation fields hold the URIs of the linked elements.
+type ExportRow struct {
+	URI          string
+	Title        string
+	SpaceURI     string
+	AuthorURI    string
+	CreationDate time.Time
+	Version      int32
+	Values       map[string]any
+}
+
+// Statuses of background jobs.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Job is a background job of the jobs table. Payload and Result are JSON.
+type Job struct {
+	ID              string
+	Kind            string
+	Payload         []byte
+	UserURI         string
+	Status          string
+	Progress        int32
+	Total           *int32
+	Result          []byte
+	Error           *string
+	Attempts        int32
+	MaxAttempts     int32
+	CancelRequested bool
+	RunAfter        int64
+	CreationDate    int64
+	StartedDate     *int64
+	FinishedDate    *int64
+	UpdatedDate     int64
+}
+
+// Events webhooks are called on.
+const (
+	WebhookElementCreated = "element.created"
+	WebhookElementUpdated = "element.updated"
+	WebhookElementDeleted = "element.deleted"
+)
+
+// Statuses of webhook deliveries.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+type Webhook struct {
+	URI          string
+	SpaceURI     string
+	TypeURI      *string
+	URL          string
+	Events       []string
+	Secret       string
+	AuthorURI    string
+	CreationDate int64
+}
+
+// WebhookDelivery is a call of a webhook for an event. Payload is the JSON
+// body sent, URL and Secret those of the webhook.
+type WebhookDelivery struct {
+	ID             int64
+	WebhookURI     string
+	EventID        string
+	Event          string
+	Payload        []byte
+	Status         string
+	Attempts       int32
+	NextAttempt    int64
+	ResponseStatus *int32
+	ResponseBody   *string
+	Error          *string
+	CreationDate   int64
+	DeliveredDate  *int64
+	URL            string
+	Secret         string
+}
+
+// WebhookEvent is an element event as sent to the webhooks subscribed to it.
+type WebhookEvent struct {
+	ID       string
+	Event    string
+	SpaceURI string
+	TypeURI  string
+	Payload  []byte
+}
+
+// Topics of outbox events.
+const (
+	OutboxElementChanged = "element.changed"
+)
+
+// OutboxEvent is an event of the outbox. TxID is the transaction that wrote
+// it.
+type OutboxEvent struct {
+	ID           int64
+	EventID      string
+	Topic        string
+	AggregateURI string
+	Payload      []byte
+	TxID         string
+	CreationDate int64
+}
+
+type RevisionWithAuthor struct {
+	*model.ElementRevision
+	AuthorURI string
+}
+
+type AuditEntry struct {
+	UserURI      string
+	Action       string
+	TargetURI    string
+	TenantURI    string
+	RequestID    *string
+	ClientIP     *string
+	Diff         any
+	CreationDate int64
+}
+
+type AuditLogParams struct {
+	TenantURI string
+	Filter    *model.AuditLogFilter
+	Limit     int32
+	After     *string
+}
+
+// TypeDefinition is a type together with the definitions of its fields, in
+// the order they were created.
+type TypeDefinition struct {
+	URI      string
+	Name     string
+	SpaceURI string
+	Fields   []*FieldDefinition
+}
+
+type FieldDefinition struct {
+	URI         string
+	Name        string
+	FieldType   model.FieldType
+	Options     *string
+	Required    bool
+	Constraints *string
+}
+
+// ElementLink is a link from an element to another one through a relation
+// field, ElementURI is the element on the other end.
+type ElementLink struct {
+	FieldURI   string
+	ElementURI string
+}
\ No newline at end of file
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	CardinalitySingle   = "single"
+	CardinalityMultiple = "multiple"
+)
+
+// RelationOptions is the configuration stored in the options of a RELATION
+// field.
+type RelationOptions struct {
+	TargetTypeURI string `json:"targetTypeUri"`
+	Cardinality   string `json:"cardinality"`
+}
+
+// ParseRelationOptions parses the options of a RELATION field. Cardinality
+// defaults to single.
+func ParseRelationOptions(options *string) (*RelationOptions, error) {
+	var opts RelationOptions
+	if options != nil {
+		if err := json.Unmarshal([]byte(*options), &opts); err != nil {
+			return nil, fmt.Errorf("failed to parse relation options: %w", err)
+		}
+	}
+	if opts.TargetTypeURI == "" {
+		return nil, fmt.Errorf("relation options are missing targetTypeUri")
+	}
+
+	switch opts.Cardinality {
+	case "":
+		opts.Cardinality = CardinalitySingle
+	case CardinalitySingle, CardinalityMultiple:
+	default:
+		return nil, fmt.Errorf("unknown cardinality %q", opts.Cardinality)
+	}
+	return &opts, nil
+}
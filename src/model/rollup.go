@@ -0,0 +1,88 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/bamdadam/backend/graph/model"
+)
+
+const (
+	RollupSum    = "sum"
+	RollupCount  = "count"
+	RollupMin    = "min"
+	RollupMax    = "max"
+	RollupAvg    = "avg"
+	RollupConcat = "concat"
+)
+
+// RollupOptions is the configuration stored in the options of a ROLLUP field.
+// The rollup aggregates the values of the target field over the elements
+// linked through the relation field, which either belongs to the rollup's
+// type or links to it.
+type RollupOptions struct {
+	RelationFieldURI string `json:"relationFieldUri"`
+	TargetFieldURI   string `json:"targetFieldUri"`
+	Function         string `json:"function"`
+}
+
+// ParseRollupOptions parses the options of a ROLLUP field. The target field
+// may only be left out for count, which counts the linked elements.
+func ParseRollupOptions(options *string) (*RollupOptions, error) {
+	var opts RollupOptions
+	if options != nil {
+		if err := json.Unmarshal([]byte(*options), &opts); err != nil {
+			return nil, fmt.Errorf("failed to parse rollup options: %w", err)
+		}
+	}
+	if opts.RelationFieldURI == "" {
+		return nil, fmt.Errorf("rollup options are missing relationFieldUri")
+	}
+
+	switch opts.Function {
+	case RollupCount:
+	case RollupSum, RollupMin, RollupMax, RollupAvg, RollupConcat:
+		if opts.TargetFieldURI == "" {
+			return nil, fmt.Errorf("rollup options are missing targetFieldUri")
+		}
+	default:
+		return nil, fmt.Errorf("unknown rollup function %q", opts.Function)
+	}
+	return &opts, nil
+}
+
+// ResultType returns the type of the values of the rollup, given the type of
+// its target field, or an error if the function can't aggregate that type.
+func (o *RollupOptions) ResultType(targetType model.FieldType) (model.FieldType, error) {
+	switch o.Function {
+	case RollupCount:
+		return model.FieldTypeNumber, nil
+	case RollupSum, RollupAvg:
+		if targetType == model.FieldTypeNumber {
+			return model.FieldTypeNumber, nil
+		}
+	case RollupMin, RollupMax:
+		if targetType == model.FieldTypeNumber || targetType == model.FieldTypeDate {
+			return targetType, nil
+		}
+	case RollupConcat:
+		switch targetType {
+		case model.FieldTypeText, model.FieldTypeSelect, model.FieldTypeURL, model.FieldTypeEmail:
+			return model.FieldTypeText, nil
+		}
+	}
+	return "", fmt.Errorf("rollup function %s can't aggregate %s fields", o.Function, targetType)
+}
+
+// Rollup is a ROLLUP field along with what it aggregates.
+type Rollup struct {
+	FieldURI string
+	// TypeURI is the type of the elements owning the rollup values.
+	TypeURI string
+	RollupOptions
+	// Forward is set if the relation field belongs to the rollup's type, the
+	// rollup then aggregates over the elements its owner links to rather
+	// than the ones linking to it.
+	Forward    bool
+	ResultType model.FieldType
+}
@@ -8,28 +8,28 @@ import (
 
 type ElementPubSub struct {
 	mu   sync.RWMutex
-	subs map[string]map[chan *model.Element]struct{}
+	subs map[string]map[chan *model.ElementChange]struct{}
 }
 
 func NewElementPubSub() *ElementPubSub {
 	return &ElementPubSub{
-		subs: make(map[string]map[chan *model.Element]struct{}),
+		subs: make(map[string]map[chan *model.ElementChange]struct{}),
 	}
 }
 
-func (p *ElementPubSub) Subscribe(uri string) chan *model.Element {
+func (p *ElementPubSub) Subscribe(uri string) chan *model.ElementChange {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	ch := make(chan *model.Element, 1)
+	ch := make(chan *model.ElementChange, 1)
 	if p.subs[uri] == nil {
-		p.subs[uri] = make(map[chan *model.Element]struct{})
+		p.subs[uri] = make(map[chan *model.ElementChange]struct{})
 	}
 	p.subs[uri][ch] = struct{}{}
 	return ch
 }
 
-func (p *ElementPubSub) Unsubscribe(uri string, ch chan *model.Element) {
+func (p *ElementPubSub) Unsubscribe(uri string, ch chan *model.ElementChange) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
@@ -42,14 +42,26 @@ func (p *ElementPubSub) Unsubscribe(uri string, ch chan *model.Element) {
 	close(ch)
 }
 
-func (p *ElementPubSub) Publish(elem *model.Element) {
+func (p *ElementPubSub) Publish(change *model.ElementChange) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	for ch := range p.subs[elem.URI] {
+	for ch := range p.subs[change.Element.URI] {
 		select {
-		case ch <- elem:
+		case ch <- change:
 		default:
 		}
 	}
+}
+
+// URIs returns the elements with subscribers.
+func (p *ElementPubSub) URIs() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	uris := make([]string, 0, len(p.subs))
+	for uri := range p.subs {
+		uris = append(uris, uri)
+	}
+	return uris
 }
\ No newline at end of file
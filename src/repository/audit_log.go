@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
+	models "github.com/bamdadam/backend/src/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AuditLogRepository interface {
+	Create(ctx context.Context, entry *models.AuditEntry) error
+	List(ctx context.Context, params models.AuditLogParams) ([]*model.AuditLogEntry, error)
+	DeleteBefore(ctx context.Context, cutoff int64) (int64, error)
+}
+
+type auditLogRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAuditLogRepository(db *pgxpool.Pool) AuditLogRepository {
+	return &auditLogRepository{db: db}
+}
+
+// Create appends an entry to the audit log. It runs in the transaction carried
+// by ctx, if any, so the entry is only kept if the audited write commits.
+func (r *auditLogRepository) Create(ctx context.Context, entry *models.AuditEntry) error {
+	query := `
+		INSERT INTO audit_log (user_uri, action, target_uri, tenant_uri, request_id, client_ip, diff, creation_date)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	var diff []byte
+	if entry.Diff != nil {
+		var err error
+		diff, err = json.Marshal(entry.Diff)
+		if err != nil {
+			return fmt.Errorf("failed to marshal audit diff: %w", err)
+		}
+	}
+
+	_, err := conn(ctx, r.db).Exec(ctx, query,
+		entry.UserURI, entry.Action, entry.TargetURI, entry.TenantURI,
+		entry.RequestID, entry.ClientIP, diff, entry.CreationDate,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create audit log entry: %w", err)
+	}
+	return nil
+}
+
+// List returns the audit log of a tenant, newest first, using the entry ID as
+// cursor. Like the other list queries it fetches one extra row so the caller
+// can tell whether there is a next page.
+func (r *auditLogRepository) List(ctx context.Context, params models.AuditLogParams) ([]*model.AuditLogEntry, error) {
+	query, args, err := r.buildListQuery(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build audit log query: %w", err)
+	}
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit log: %w", err)
+	}
+
+	var id, creationDate int64
+	var userURI, action, targetURI, tenantURI string
+	var requestID, clientIP *string
+	var diff []byte
+	var entries []*model.AuditLogEntry
+
+	_, err = pgx.ForEachRow(rows,
+		[]any{&id, &userURI, &action, &targetURI, &tenantURI, &requestID, &clientIP, &diff, &creationDate},
+		func() error {
+			entry := &model.AuditLogEntry{
+				ID:           strconv.FormatInt(id, 10),
+				UserURI:      userURI,
+				Action:       action,
+				TargetURI:    targetURI,
+				TenantURI:    tenantURI,
+				RequestID:    requestID,
+				ClientIP:     clientIP,
+				CreationDate: time.UnixMilli(creationDate),
+			}
+			if diff != nil {
+				if err := json.Unmarshal(diff, &entry.Diff); err != nil {
+					return fmt.Errorf("failed to unmarshal audit diff: %w", err)
+				}
+			}
+			entries = append(entries, entry)
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("error iterating audit log: %w", err)
+	}
+
+	return entries, nil
+}
+
+// DeleteBefore purges entries created before cutoff and returns how many were
+// removed. The append-only trigger on audit_log only lets deletes through in a
+// transaction that sets audit.purge.
+func (r *auditLogRepository) DeleteBefore(ctx context.Context, cutoff int64) (int64, error) {
+	var deleted int64
+
+	err := RunInTx(ctx, r.db, func(ctx context.Context) error {
+		if _, err := conn(ctx, r.db).Exec(ctx, `SET LOCAL audit.purge = 'on'`); err != nil {
+			return fmt.Errorf("failed to enable audit purge: %w", err)
+		}
+
+		result, err := conn(ctx, r.db).Exec(ctx, `DELETE FROM audit_log WHERE creation_date < $1`, cutoff)
+		if err != nil {
+			return fmt.Errorf("failed to purge audit log: %w", err)
+		}
+
+		deleted = result.RowsAffected()
+		return nil
+	})
+
+	return deleted, err
+}
+
+func (r *auditLogRepository) buildListQuery(params models.AuditLogParams) (string, []any, error) {
+	query := `
+		SELECT id, user_uri, action, target_uri, tenant_uri, request_id, client_ip, diff, creation_date
+		FROM audit_log
+	`
+	conditions := []string{"tenant_uri = $1"}
+	args := []any{params.TenantURI}
+
+	addCondition := func(format string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	if f := params.Filter; f != nil {
+		if f.UserURI != nil {
+			addCondition("user_uri = $%d", *f.UserURI)
+		}
+		if f.Action != nil {
+			addCondition("action = $%d", *f.Action)
+		}
+		if f.TargetURI != nil {
+			addCondition("target_uri = $%d", *f.TargetURI)
+		}
+		if f.From != nil {
+			addCondition("creation_date >= $%d", f.From.UnixMilli())
+		}
+		if f.To != nil {
+			addCondition("creation_date < $%d", f.To.UnixMilli())
+		}
+	}
+
+	if params.After != nil {
+		after, err := strconv.ParseInt(*params.After, 10, 64)
+		if err != nil {
+			return "", nil, apperror.Validation("invalid cursor: %w", err)
+		}
+		addCondition("id < $%d", after)
+	}
+
+	query += " WHERE " + strings.Join(conditions, " AND ")
+
+	args = append(args, params.Limit+1)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", len(args))
+
+	return query, args, nil
+}
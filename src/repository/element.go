@@ -2,12 +2,18 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
 	models "github.com/bamdadam/backend/src/model"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -15,6 +21,11 @@ type ElementRepository interface {
 	GetByURI(ctx context.Context, uri string, userSpaces []string) (*model.Element, *models.LoadRelationParams, error)
 	List(ctx context.Context, params models.ListParams, userSpaces []string) ([]*models.ElemWithRelation, error)
 	UpdateTitle(ctx context.Context, uri, title string, userSpaces []string) (*model.Element, *models.LoadRelationParams, error)
+	BumpVersion(ctx context.Context, uri string, expectedVersion *int32, userSpaces []string) (bool, error)
+	GetSpaceURI(ctx context.Context, uri string) (string, error)
+	Create(ctx context.Context, uri, title, typeURI, spaceURI, authorURI string, creationDate int64) error
+	Delete(ctx context.Context, uri string, userSpaces []string) error
+	Export(ctx context.Context, params models.ListParams, fields []*models.FieldDefinition, userSpaces []string, fn func(row *models.ExportRow) error) error
 }
 
 type elementRepository struct {
@@ -29,7 +40,7 @@ func NewElementRepository(db *pgxpool.Pool) ElementRepository {
 // Returns an error if the element is not found or not in an accessible space.
 func (r *elementRepository) GetByURI(ctx context.Context, uri string, userSpaces []string) (*model.Element, *models.LoadRelationParams, error) {
 	query := `
-		SELECT uri, title, type_uri, space_uri, creation_date, author
+		SELECT uri, title, type_uri, space_uri, creation_date, author, version
 		FROM elements WHERE uri = $1 AND space_uri = ANY($2)
 	`
 
@@ -37,14 +48,17 @@ func (r *elementRepository) GetByURI(ctx context.Context, uri string, userSpaces
 	var typeURI, spaceURI, authorURI string
 	var creationDate int64
 
-	err := r.db.QueryRow(ctx, query, uri, userSpaces).Scan(
-		&elem.URI, &elem.Title, &typeURI, &spaceURI, &creationDate, &authorURI,
+	err := conn(ctx, r.db).QueryRow(ctx, query, uri, userSpaces).Scan(
+		&elem.URI, &elem.Title, &typeURI, &spaceURI, &creationDate, &authorURI, &elem.Version,
 	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, apperror.NotFound("element not found: %s", uri)
+	}
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get element: %w", err)
 	}
 
-	elem.CreationDate = strconv.FormatInt(creationDate, 10)
+	elem.CreationDate = time.UnixMilli(creationDate)
 
 	relationParams := models.LoadRelationParams{
 		TypeURI:   typeURI,
@@ -63,13 +77,14 @@ func (r *elementRepository) List(ctx context.Context, params models.ListParams,
 		return nil, fmt.Errorf("failed to build list query elements: %w", err)
 	}
 
-	rows, err := r.db.Query(ctx, query, args...)
+	rows, err := conn(ctx, r.db).Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list elements: %w", err)
 	}
 
 	var uri, title, typeURI, spaceURI, authorURI string
 	var creationDate int64
+	var version int32
 	var elements []*models.ElemWithRelation
 
 	_, err = pgx.ForEachRow(rows,
@@ -79,13 +94,15 @@ func (r *elementRepository) List(ctx context.Context, params models.ListParams,
 			&typeURI,
 			&spaceURI,
 			&creationDate,
-			&authorURI},
+			&authorURI,
+			&version},
 		func() error {
 			elem := &models.ElemWithRelation{
 				Element: &model.Element{
 					URI:          uri,
 					Title:        title,
-					CreationDate: strconv.FormatInt(creationDate, 10),
+					CreationDate: time.UnixMilli(creationDate),
+					Version:      version,
 				},
 				LoadRelationParams: models.LoadRelationParams{
 					TypeURI:   typeURI,
@@ -105,23 +122,201 @@ func (r *elementRepository) List(ctx context.Context, params models.ListParams,
 }
 
 func (r *elementRepository) UpdateTitle(ctx context.Context, uri, title string, userSpaces []string) (*model.Element, *models.LoadRelationParams, error) {
-	result, err := r.db.Exec(ctx, `UPDATE elements SET title = $1 WHERE uri = $2 AND space_uri = ANY($3)`, title, uri, userSpaces)
+	result, err := conn(ctx, r.db).Exec(ctx, `UPDATE elements SET title = $1 WHERE uri = $2 AND space_uri = ANY($3)`, title, uri, userSpaces)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to update element title: %w", err)
 	}
 
 	if result.RowsAffected() == 0 {
-		return nil, nil, fmt.Errorf("element not found: %s", uri)
+		return nil, nil, apperror.NotFound("element not found: %s", uri)
 	}
 
 	return r.GetByURI(ctx, uri, userSpaces)
 }
 
+// GetSpaceURI returns the space of an element regardless of who may access it.
+func (r *elementRepository) GetSpaceURI(ctx context.Context, uri string) (string, error) {
+	var spaceURI string
+	err := conn(ctx, r.db).QueryRow(ctx, `SELECT space_uri FROM elements WHERE uri = $1`, uri).Scan(&spaceURI)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", apperror.NotFound("element not found: %s", uri)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get element space: %w", err)
+	}
+	return spaceURI, nil
+}
+
+// uniqueViolation is the SQLSTATE of unique constraint violations.
+const uniqueViolation = "23505"
+
+// Create inserts a new element, returning a CONFLICT error if an element with
+// the same URI exists.
+func (r *elementRepository) Create(ctx context.Context, uri, title, typeURI, spaceURI, authorURI string, creationDate int64) error {
+	_, err := conn(ctx, r.db).Exec(ctx, `
+		INSERT INTO elements (uri, title, type_uri, space_uri, creation_date, author)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, uri, title, typeURI, spaceURI, creationDate, authorURI)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return apperror.Conflict("element already exists: %s", uri)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create element: %w", err)
+	}
+	return nil
+}
+
+// Delete removes an element along with its field values, links and
+// revisions.
+func (r *elementRepository) Delete(ctx context.Context, uri string, userSpaces []string) error {
+	result, err := conn(ctx, r.db).Exec(ctx, `DELETE FROM elements WHERE uri = $1 AND space_uri = ANY($2)`, uri, userSpaces)
+	if err != nil {
+		return fmt.Errorf("failed to delete element: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return apperror.NotFound("element not found: %s", uri)
+	}
+	return nil
+}
+
+// BumpVersion increments the version of an element, locking its row until the
+// surrounding transaction ends. When expectedVersion is set the element is only
+// bumped if its version still matches. It returns false if no row was updated,
+// either because the element is not accessible or because of a version mismatch.
+func (r *elementRepository) BumpVersion(ctx context.Context, uri string, expectedVersion *int32, userSpaces []string) (bool, error) {
+	result, err := conn(ctx, r.db).Exec(ctx, `
+		UPDATE elements SET version = version + 1
+		WHERE uri = $1 AND space_uri = ANY($2) AND ($3::integer IS NULL OR version = $3)
+	`, uri, userSpaces, expectedVersion)
+	if err != nil {
+		return false, fmt.Errorf("failed to bump element version: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// exportBatchSize is the number of rows Export fetches from its cursor at once.
+const exportBatchSize = 500
+
+// Export streams the elements matching params, without limit, together with
+// the values of fields. Rows are fetched in batches from a server-side cursor
+// and handed to fn in the order of their URI. Links to elements outside
+// userSpaces are left out of relation fields.
+func (r *elementRepository) Export(ctx context.Context, params models.ListParams, fields []*models.FieldDefinition, userSpaces []string, fn func(row *models.ExportRow) error) error {
+	params.Limit = 0
+	listQuery, args, err := r.buildListQuery(params, userSpaces)
+	if err != nil {
+		return fmt.Errorf("failed to build export query: %w", err)
+	}
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	columns := []string{"l.uri", "l.title", "l.space_uri", "l.author", "l.creation_date", "l.version"}
+	var joins []string
+	for i, f := range fields {
+		if f.FieldType == model.FieldTypeRelation {
+			columns = append(columns, fmt.Sprintf(`(SELECT array_agg(k.target_element_uri ORDER BY k.position)
+				FROM element_links k JOIN elements t ON t.uri = k.target_element_uri
+				WHERE k.source_element_uri = l.uri AND k.field_uri = %s AND t.space_uri = ANY(%s))`, arg(f.URI), arg(userSpaces)))
+			continue
+		}
+		v := fmt.Sprintf("v%d", i)
+		columns = append(columns, v+".value_text", v+".value_number", v+".value_date", v+".value_boolean", v+".value_json")
+		joins = append(joins, fmt.Sprintf("LEFT JOIN element_field_values %s ON %s.element_uri = l.uri AND %s.field_uri = %s", v, v, v, arg(f.URI)))
+	}
+
+	query := fmt.Sprintf("DECLARE element_export NO SCROLL CURSOR FOR SELECT %s FROM (%s) l %s ORDER BY l.uri",
+		strings.Join(columns, ", "), listQuery, strings.Join(joins, " "))
+
+	return RunInTx(ctx, r.db, func(ctx context.Context) error {
+		db := conn(ctx, r.db)
+		if _, err := db.Exec(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to open export cursor: %w", err)
+		}
+
+		for {
+			rows, err := db.Query(ctx, fmt.Sprintf("FETCH %d FROM element_export", exportBatchSize))
+			if err != nil {
+				return fmt.Errorf("failed to fetch exported elements: %w", err)
+			}
+
+			n := 0
+			for rows.Next() {
+				n++
+				row, err := scanExportRow(rows, fields)
+				if err == nil {
+					err = fn(row)
+				}
+				if err != nil {
+					rows.Close()
+					return err
+				}
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("error iterating exported elements: %w", err)
+			}
+			if n < exportBatchSize {
+				return nil
+			}
+		}
+	})
+}
+
+func scanExportRow(rows pgx.Rows, fields []*models.FieldDefinition) (*models.ExportRow, error) {
+	var row models.ExportRow
+	var creationDate int64
+	dest := []any{&row.URI, &row.Title, &row.SpaceURI, &row.AuthorURI, &creationDate, &row.Version}
+
+	type storedValue struct {
+		text, json *string
+		number     *float64
+		date       *int64
+		boolean    *bool
+	}
+	stored := make([]storedValue, len(fields))
+	links := make([][]string, len(fields))
+	for i, f := range fields {
+		if f.FieldType == model.FieldTypeRelation {
+			dest = append(dest, &links[i])
+			continue
+		}
+		v := &stored[i]
+		dest = append(dest, &v.text, &v.number, &v.date, &v.boolean, &v.json)
+	}
+
+	if err := rows.Scan(dest...); err != nil {
+		return nil, fmt.Errorf("failed to scan exported element: %w", err)
+	}
+
+	row.CreationDate = time.UnixMilli(creationDate)
+	row.Values = make(map[string]any, len(fields))
+	for i, f := range fields {
+		if f.FieldType == model.FieldTypeRelation {
+			if links[i] != nil {
+				row.Values[f.URI] = links[i]
+			}
+			continue
+		}
+		v := stored[i]
+		if value := extractValue(v.text, v.number, v.date, v.boolean, v.json); value != nil {
+			row.Values[f.URI] = value
+		}
+	}
+	return &row, nil
+}
+
 // buildListQuery constructs a dynamic SQL query for listing elements based on the provided filter parameters.
 // Supports filtering by type URI, space URI, field values, user spaces, and cursor-based pagination.
 // Returns the query string, positional arguments, and any error encountered during query construction.
 func (r *elementRepository) buildListQuery(params models.ListParams, userSpaces []string) (string, []interface{}, error) {
-	query := `SELECT e.uri, e.title, e.type_uri, e.space_uri, e.creation_date, e.author FROM elements e`
+	query := `SELECT e.uri, e.title, e.type_uri, e.space_uri, e.creation_date, e.author, e.version FROM elements e`
 	var conditions []string
 	var args []interface{}
 	argIdx := 1
@@ -138,7 +333,15 @@ func (r *elementRepository) buildListQuery(params models.ListParams, userSpaces
 			if err != nil {
 				return "", nil, err
 			}
-			query += fmt.Sprintf(` AND efv.%s = $%d`, col, argIdx)
+			op := "="
+			if params.FieldValueFilter.Operator != nil {
+				var ok bool
+				op, ok = comparisonOperators[models.FilterOperator(strings.ToLower(string(*params.FieldValueFilter.Operator)))]
+				if !ok {
+					return "", nil, apperror.Validation("unknown filter operator: %s", *params.FieldValueFilter.Operator)
+				}
+			}
+			query += fmt.Sprintf(` AND efv.%s %s $%d`, col, op, argIdx)
 			args = append(args, val)
 			argIdx++
 		}
@@ -161,6 +364,15 @@ func (r *elementRepository) buildListQuery(params models.ListParams, userSpaces
 		argIdx++
 	}
 
+	for _, c := range params.Conditions {
+		cond, err := compileFieldCondition(c, "e", &args, userSpaces)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, cond)
+		argIdx = len(args) + 1
+	}
+
 	if params.After != nil {
 		conditions = append(conditions, fmt.Sprintf("e.uri > $%d", argIdx))
 		args = append(args, *params.After)
@@ -177,12 +389,101 @@ func (r *elementRepository) buildListQuery(params models.ListParams, userSpaces
 		}
 	}
 
-	query += fmt.Sprintf(" ORDER BY e.uri ASC LIMIT $%d", argIdx)
-	args = append(args, params.Limit+1)
+	query += " ORDER BY e.uri ASC"
+	if params.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", argIdx)
+		args = append(args, params.Limit+1)
+	}
 
 	return query, args, nil
 }
 
+// valueColumns maps each field type to the element_field_values column its
+// values are stored in.
+var valueColumns = map[model.FieldType]string{
+	model.FieldTypeText:        "value_text",
+	model.FieldTypeNumber:      "value_number",
+	model.FieldTypeDate:        "value_date",
+	model.FieldTypeBoolean:     "value_boolean",
+	model.FieldTypeSelect:      "value_text",
+	model.FieldTypeMultiSelect: "value_json",
+	model.FieldTypeURL:         "value_text",
+	model.FieldTypeEmail:       "value_text",
+}
+
+var comparisonOperators = map[models.FilterOperator]string{
+	models.FilterEq:  "=",
+	models.FilterGt:  ">",
+	models.FilterGte: ">=",
+	models.FilterLt:  "<",
+	models.FilterLte: "<=",
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// compileFieldCondition turns a FieldCondition on the element aliased as alias
+// into an EXISTS clause, appending its arguments to args. Elements reached
+// through relation fields must be in userSpaces.
+func compileFieldCondition(c models.FieldCondition, alias string, args *[]any, userSpaces []string) (string, error) {
+	arg := func(v any) string {
+		*args = append(*args, v)
+		return fmt.Sprintf("$%d", len(*args))
+	}
+	// aliases of nested subqueries are numbered by the arguments bound so
+	// far, which is unique within a query
+	suffix := strconv.Itoa(len(*args))
+
+	if c.FieldType == model.FieldTypeRelation {
+		l, t := "l"+suffix, "t"+suffix
+		clause := fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM element_links %s JOIN elements %s ON %s.uri = %s.target_element_uri WHERE %s.source_element_uri = %s.uri AND %s.field_uri = %s AND %s.space_uri = ANY(%s)",
+			l, t, t, l, l, alias, l, arg(c.FieldURI), t, arg(userSpaces),
+		)
+		for _, related := range c.Related {
+			cond, err := compileFieldCondition(related, t, args, userSpaces)
+			if err != nil {
+				return "", err
+			}
+			clause += " AND " + cond
+		}
+		return clause + ")", nil
+	}
+
+	col, ok := valueColumns[c.FieldType]
+	if !ok {
+		return "", apperror.Validation("field %s of type %s cannot be filtered", c.FieldURI, c.FieldType)
+	}
+
+	v := "v" + suffix
+	clause := fmt.Sprintf("EXISTS (SELECT 1 FROM element_field_values %s WHERE %s.element_uri = %s.uri AND %s.field_uri = %s AND ", v, v, alias, v, arg(c.FieldURI))
+	col = v + "." + col
+
+	switch {
+	case c.Operator == models.FilterIn:
+		clause += fmt.Sprintf("%s = ANY(%s)", col, arg(c.Value))
+	case c.Operator == models.FilterContains && c.FieldType == model.FieldTypeMultiSelect:
+		raw, err := json.Marshal([]any{c.Value})
+		if err != nil {
+			return "", fmt.Errorf("failed to encode filter value: %w", err)
+		}
+		clause += fmt.Sprintf("%s @> %s::jsonb", col, arg(string(raw)))
+	case c.Operator == models.FilterContains:
+		str, ok := c.Value.(string)
+		if !ok {
+			return "", apperror.Validation("contains filter on field %s requires a string", c.FieldURI)
+		}
+		clause += fmt.Sprintf("%s ILIKE %s", col, arg("%"+likeEscaper.Replace(str)+"%"))
+	default:
+		op, ok := comparisonOperators[c.Operator]
+		if !ok {
+			return "", apperror.Validation("unknown filter operator: %s", c.Operator)
+		}
+		clause += fmt.Sprintf("%s %s %s", col, op, arg(c.Value))
+	}
+
+	return clause + ")", nil
+}
+
 // getFilterColumnAndValue maps a FieldValueFilter to the appropriate database column name and
 // parsed value based on the filter's value type. Handles TEXT, NUMBER, DATE, BOOLEAN, and JSON types.
 // Returns the column name, converted value, and any parsing error.
@@ -193,24 +494,24 @@ func (r *elementRepository) getFilterColumnAndValue(filter *model.FieldValueFilt
 	case model.FieldValueTypeNumber:
 		num, err := strconv.ParseFloat(*filter.Value, 64)
 		if err != nil {
-			return "", nil, fmt.Errorf("invalid number value: %w", err)
+			return "", nil, apperror.Validation("invalid number value: %w", err)
 		}
 		return "value_number", num, nil
 	case model.FieldValueTypeDate:
-		date, err := strconv.ParseInt(*filter.Value, 10, 64)
+		date, err := model.ParseDateTime(*filter.Value)
 		if err != nil {
-			return "", nil, fmt.Errorf("invalid date value: %w", err)
+			return "", nil, err
 		}
-		return "value_date", date, nil
+		return "value_date", date.UnixMilli(), nil
 	case model.FieldValueTypeBoolean:
 		b, err := strconv.ParseBool(*filter.Value)
 		if err != nil {
-			return "", nil, fmt.Errorf("invalid boolean value: %w", err)
+			return "", nil, apperror.Validation("invalid boolean value: %w", err)
 		}
 		return "value_boolean", b, nil
 	case model.FieldValueTypeJSON:
 		return "value_json", *filter.Value, nil
 	default:
-		return "", nil, fmt.Errorf("unknown value type: %s", *filter.ValueType)
+		return "", nil, apperror.Validation("unknown value type: %s", *filter.ValueType)
 	}
 }
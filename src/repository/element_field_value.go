@@ -4,13 +4,19 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type ElementFieldValueRepository interface {
 	GetByElementURI(ctx context.Context, elementURI string) ([]*model.ElementFieldValue, error)
+	GetByRevisionID(ctx context.Context, elementURI, revisionID string) ([]*model.ElementFieldValue, error)
+	Set(ctx context.Context, elementURI, fieldURI string, fieldType model.FieldType, value any, updatedDate int64) error
+	HasDuplicate(ctx context.Context, fieldURI string, fieldType model.FieldType, value any, elementURI string, spaceURI *string) (bool, error)
 }
 
 type elementFieldValueRepository struct {
@@ -29,10 +35,111 @@ func (r *elementFieldValueRepository) GetByElementURI(ctx context.Context, eleme
 		WHERE element_uri = $1
 	`
 
-	rows, err := r.db.Query(ctx, query, elementURI)
+	rows, err := conn(ctx, r.db).Query(ctx, query, elementURI)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get element field values: %w", err)
 	}
+
+	return r.scanFieldValues(ctx, rows, elementURI)
+}
+
+// GetByRevisionID returns the field values of an element as they were captured
+// in the given revision. Values of fields deleted since then are left out.
+func (r *elementFieldValueRepository) GetByRevisionID(ctx context.Context, elementURI, revisionID string) ([]*model.ElementFieldValue, error) {
+	query := `
+		SELECT fv.uri, fv.field_uri, fv.value_text, fv.value_number, fv.value_date, fv.value_boolean, fv.value_json
+		FROM element_revisions er,
+			jsonb_populate_recordset(NULL::public.element_field_values, er.field_values) fv
+		WHERE er.id = $1 AND er.element_uri = $2 AND fv.field_uri IN (SELECT uri FROM fields)
+	`
+
+	id, err := parseRevisionID(revisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, id, elementURI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revision field values: %w", err)
+	}
+
+	return r.scanFieldValues(ctx, rows, elementURI)
+}
+
+// Set writes the value of a field of an element, or removes it if value is
+// nil. Values are stored in the column of the field's type, dates given as a
+// time.Time.
+func (r *elementFieldValueRepository) Set(ctx context.Context, elementURI, fieldURI string, fieldType model.FieldType, value any, updatedDate int64) error {
+	if value == nil {
+		_, err := conn(ctx, r.db).Exec(ctx, `DELETE FROM element_field_values WHERE element_uri = $1 AND field_uri = $2`, elementURI, fieldURI)
+		if err != nil {
+			return fmt.Errorf("failed to delete element field value: %w", err)
+		}
+		return nil
+	}
+
+	col, ok := valueColumns[fieldType]
+	if !ok {
+		return apperror.Validation("values of %s fields can't be set", fieldType)
+	}
+
+	// all value columns are overwritten, clearing the ones left over in case
+	// the field changed its type
+	query := fmt.Sprintf(`
+		INSERT INTO element_field_values (uri, element_uri, field_uri, %s, creation_date, updated_date)
+		VALUES ('efv:' || $1 || ':' || $2, $1, $2, $3, $4, $4)
+		ON CONFLICT (element_uri, field_uri) DO UPDATE
+		SET value_text = EXCLUDED.value_text, value_number = EXCLUDED.value_number, value_date = EXCLUDED.value_date,
+			value_boolean = EXCLUDED.value_boolean, value_json = EXCLUDED.value_json, updated_date = EXCLUDED.updated_date
+	`, col)
+
+	if _, err := conn(ctx, r.db).Exec(ctx, query, elementURI, fieldURI, storedValue(value), updatedDate); err != nil {
+		return fmt.Errorf("failed to set element field value: %w", err)
+	}
+	return nil
+}
+
+// HasDuplicate tells whether an element other than elementURI has the given
+// value for a field, among the elements of spaceURI if set. It first takes a
+// transaction level lock on the field and value, so that concurrent writes of
+// the same value are checked one after the other.
+func (r *elementFieldValueRepository) HasDuplicate(ctx context.Context, fieldURI string, fieldType model.FieldType, value any, elementURI string, spaceURI *string) (bool, error) {
+	col, ok := valueColumns[fieldType]
+	if !ok {
+		return false, apperror.Validation("values of %s fields can't be compared", fieldType)
+	}
+	value = storedValue(value)
+
+	db := conn(ctx, r.db)
+	if _, err := db.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`, fmt.Sprintf("%s=%v", fieldURI, value)); err != nil {
+		return false, fmt.Errorf("failed to lock field value: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT EXISTS (
+			SELECT 1 FROM element_field_values v
+			JOIN elements e ON e.uri = v.element_uri
+			WHERE v.field_uri = $1 AND v.%s = $2 AND v.element_uri <> $3
+				AND ($4::text IS NULL OR e.space_uri = $4)
+		)
+	`, col)
+
+	var exists bool
+	if err := db.QueryRow(ctx, query, fieldURI, value, elementURI, spaceURI).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check for duplicate field value: %w", err)
+	}
+	return exists, nil
+}
+
+// storedValue converts a value to its representation in element_field_values.
+func storedValue(value any) any {
+	if t, ok := value.(time.Time); ok {
+		return t.UnixMilli()
+	}
+	return value
+}
+
+func (r *elementFieldValueRepository) scanFieldValues(ctx context.Context, rows pgx.Rows, elementURI string) ([]*model.ElementFieldValue, error) {
 	defer rows.Close()
 
 	var fieldValues []*model.ElementFieldValue
@@ -56,7 +163,7 @@ func (r *elementFieldValueRepository) GetByElementURI(ctx context.Context, eleme
 		}
 		fv.Field = field
 
-		fv.Value = r.extractValue(valueText, valueNumber, valueDate, valueBool, valueJSON)
+		fv.Value = extractValue(valueText, valueNumber, valueDate, valueBool, valueJSON)
 		if fv.Value == nil {
 			return nil, fmt.Errorf("field value is nil, possible data corruption for element: %s, field: %s", elementURI, fv.Field.URI)
 		}
@@ -73,7 +180,7 @@ func (r *elementFieldValueRepository) GetByElementURI(ctx context.Context, eleme
 // extractValue checks which of the value fields in the database has a value
 // and extracts that value, this function assumes the data at the database level
 // is always correct and only one value type is present.
-func (r *elementFieldValueRepository) extractValue(
+func extractValue(
 	valueText *string,
 	valueNumber *float64,
 	valueDate *int64,
@@ -87,7 +194,7 @@ func (r *elementFieldValueRepository) extractValue(
 		return *valueNumber
 	}
 	if valueDate != nil {
-		return *valueDate
+		return model.FormatDateTime(time.UnixMilli(*valueDate))
 	}
 	if valueBool != nil {
 		return *valueBool
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	models "github.com/bamdadam/backend/src/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ElementLinkRepository interface {
+	ListLinks(ctx context.Context, elementURI string, fieldURI *string, userSpaces []string) ([]*models.ElementLink, error)
+	ListBacklinks(ctx context.Context, elementURI string, fieldURI *string, userSpaces []string) ([]*models.ElementLink, error)
+	ListAllLinks(ctx context.Context, elementURI string) ([]*models.ElementLink, error)
+	Set(ctx context.Context, elementURI, fieldURI string, targetURIs []string, creationDate int64) error
+}
+
+type elementLinkRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewElementLinkRepository(db *pgxpool.Pool) ElementLinkRepository {
+	return &elementLinkRepository{db: db}
+}
+
+// ListLinks returns the elements an element links to, optionally only through
+// a single field. Linked elements outside of userSpaces are left out.
+func (r *elementLinkRepository) ListLinks(ctx context.Context, elementURI string, fieldURI *string, userSpaces []string) ([]*models.ElementLink, error) {
+	query := `
+		SELECT l.field_uri, l.target_element_uri
+		FROM element_links l
+		JOIN elements t ON t.uri = l.target_element_uri
+		WHERE l.source_element_uri = $1 AND ($2::text IS NULL OR l.field_uri = $2) AND t.space_uri = ANY($3)
+		ORDER BY l.field_uri, l.position
+	`
+	return r.list(ctx, query, elementURI, fieldURI, userSpaces)
+}
+
+// ListBacklinks returns the elements linking to an element, optionally only
+// through a single field. Linking elements outside of userSpaces are left out.
+func (r *elementLinkRepository) ListBacklinks(ctx context.Context, elementURI string, fieldURI *string, userSpaces []string) ([]*models.ElementLink, error) {
+	query := `
+		SELECT l.field_uri, l.source_element_uri
+		FROM element_links l
+		JOIN elements s ON s.uri = l.source_element_uri
+		WHERE l.target_element_uri = $1 AND ($2::text IS NULL OR l.field_uri = $2) AND s.space_uri = ANY($3)
+		ORDER BY l.field_uri, l.source_element_uri
+	`
+	return r.list(ctx, query, elementURI, fieldURI, userSpaces)
+}
+
+// ListAllLinks returns the elements an element links to and is linked from,
+// whatever their space. It is meant for keeping the rollups of the elements on
+// the other end up to date, not for showing to users.
+func (r *elementLinkRepository) ListAllLinks(ctx context.Context, elementURI string) ([]*models.ElementLink, error) {
+	query := `
+		SELECT field_uri, target_element_uri FROM element_links WHERE source_element_uri = $1
+		UNION ALL
+		SELECT field_uri, source_element_uri FROM element_links WHERE target_element_uri = $1
+	`
+	return r.list(ctx, query, elementURI)
+}
+
+func (r *elementLinkRepository) list(ctx context.Context, query string, args ...any) ([]*models.ElementLink, error) {
+	rows, err := conn(ctx, r.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list element links: %w", err)
+	}
+
+	var links []*models.ElementLink
+	var link models.ElementLink
+	_, err = pgx.ForEachRow(rows, []any{&link.FieldURI, &link.ElementURI}, func() error {
+		l := link
+		links = append(links, &l)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan element links: %w", err)
+	}
+
+	return links, nil
+}
+
+// Set replaces the elements linked through a field, keeping the order of
+// targetURIs.
+func (r *elementLinkRepository) Set(ctx context.Context, elementURI, fieldURI string, targetURIs []string, creationDate int64) error {
+	return RunInTx(ctx, r.db, func(ctx context.Context) error {
+		db := conn(ctx, r.db)
+
+		_, err := db.Exec(ctx, `DELETE FROM element_links WHERE source_element_uri = $1 AND field_uri = $2`, elementURI, fieldURI)
+		if err != nil {
+			return fmt.Errorf("failed to clear element links: %w", err)
+		}
+
+		_, err = db.Exec(ctx, `
+			INSERT INTO element_links (source_element_uri, field_uri, target_element_uri, position, creation_date)
+			SELECT $1, $2, target, position, $4
+			FROM unnest($3::text[]) WITH ORDINALITY AS t(target, position)
+		`, elementURI, fieldURI, targetURIs, creationDate)
+		if err != nil {
+			return fmt.Errorf("failed to insert element links: %w", err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
+	models "github.com/bamdadam/backend/src/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// fieldValuesSnapshot aggregates the current field values of element e into the
+// JSONB array stored in element_revisions.field_values. Links of relation
+// fields live in element_links and are not part of revisions.
+const fieldValuesSnapshot = `
+	COALESCE((
+		SELECT jsonb_agg(to_jsonb(efv) ORDER BY efv.field_uri)
+		FROM element_field_values efv WHERE efv.element_uri = e.uri
+	), '[]'::jsonb)
+`
+
+type ElementRevisionRepository interface {
+	CreateBaseline(ctx context.Context, elementURI string) error
+	Create(ctx context.Context, elementURI, authorURI string, creationDate int64) error
+	List(ctx context.Context, elementURI string, limit int32, after *string) ([]*models.RevisionWithAuthor, error)
+	GetAt(ctx context.Context, elementURI string, timestamp int64) (*models.RevisionWithAuthor, error)
+	Get(ctx context.Context, elementURI, revisionID string) (*models.RevisionWithAuthor, error)
+}
+
+type elementRevisionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewElementRevisionRepository(db *pgxpool.Pool) ElementRevisionRepository {
+	return &elementRevisionRepository{db: db}
+}
+
+// CreateBaseline captures the state of an element that has never been written
+// through the API, stamped with the element's own creation date and author, so
+// point-in-time reads before the first recorded write still resolve.
+// It does nothing if the element already has revisions.
+func (r *elementRevisionRepository) CreateBaseline(ctx context.Context, elementURI string) error {
+	query := `
+		INSERT INTO element_revisions (element_uri, title, field_values, author, creation_date)
+		SELECT e.uri, e.title, ` + fieldValuesSnapshot + `, e.author, e.creation_date
+		FROM elements e
+		WHERE e.uri = $1 AND NOT EXISTS (SELECT 1 FROM element_revisions er WHERE er.element_uri = e.uri)
+	`
+
+	if _, err := conn(ctx, r.db).Exec(ctx, query, elementURI); err != nil {
+		return fmt.Errorf("failed to create baseline revision: %w", err)
+	}
+	return nil
+}
+
+// Create captures the current title and field values of an element.
+func (r *elementRevisionRepository) Create(ctx context.Context, elementURI, authorURI string, creationDate int64) error {
+	query := `
+		INSERT INTO element_revisions (element_uri, title, field_values, author, creation_date)
+		SELECT e.uri, e.title, ` + fieldValuesSnapshot + `, $2, $3
+		FROM elements e
+		WHERE e.uri = $1
+	`
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, elementURI, authorURI, creationDate)
+	if err != nil {
+		return fmt.Errorf("failed to create revision: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return apperror.NotFound("element not found: %s", elementURI)
+	}
+	return nil
+}
+
+// List returns the revisions of an element, newest first. The revision ID is
+// used as the cursor. Like elementRepository.List it fetches one extra row so
+// the caller can tell whether there is a next page.
+func (r *elementRevisionRepository) List(ctx context.Context, elementURI string, limit int32, after *string) ([]*models.RevisionWithAuthor, error) {
+	query := `
+		SELECT id, title, author, creation_date FROM element_revisions
+		WHERE element_uri = $1 AND ($2::bigint IS NULL OR id < $2)
+		ORDER BY id DESC LIMIT $3
+	`
+
+	var afterID *int64
+	if after != nil {
+		id, err := parseRevisionID(*after)
+		if err != nil {
+			return nil, err
+		}
+		afterID = &id
+	}
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, elementURI, afterID, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revisions: %w", err)
+	}
+
+	var id, creationDate int64
+	var title, authorURI string
+	var revisions []*models.RevisionWithAuthor
+
+	_, err = pgx.ForEachRow(rows, []any{&id, &title, &authorURI, &creationDate}, func() error {
+		revisions = append(revisions, &models.RevisionWithAuthor{
+			ElementRevision: &model.ElementRevision{
+				ID:           strconv.FormatInt(id, 10),
+				Title:        title,
+				CreationDate: time.UnixMilli(creationDate),
+			},
+			AuthorURI: authorURI,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error iterating revisions: %w", err)
+	}
+
+	return revisions, nil
+}
+
+// GetAt returns the latest revision of an element created at or before the
+// given epoch millisecond timestamp, or nil if there is none.
+func (r *elementRevisionRepository) GetAt(ctx context.Context, elementURI string, timestamp int64) (*models.RevisionWithAuthor, error) {
+	query := `
+		SELECT id, title, author, creation_date FROM element_revisions
+		WHERE element_uri = $1 AND creation_date <= $2
+		ORDER BY creation_date DESC, id DESC LIMIT 1
+	`
+
+	var id, creationDate int64
+	rev := models.RevisionWithAuthor{ElementRevision: &model.ElementRevision{}}
+
+	err := conn(ctx, r.db).QueryRow(ctx, query, elementURI, timestamp).Scan(
+		&id, &rev.Title, &rev.AuthorURI, &creationDate,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revision: %w", err)
+	}
+
+	rev.ID = strconv.FormatInt(id, 10)
+	rev.CreationDate = time.UnixMilli(creationDate)
+
+	return &rev, nil
+}
+
+// Get returns a revision of an element.
+func (r *elementRevisionRepository) Get(ctx context.Context, elementURI, revisionID string) (*models.RevisionWithAuthor, error) {
+	query := `
+		SELECT title, author, creation_date FROM element_revisions
+		WHERE id = $1 AND element_uri = $2
+	`
+
+	id, err := parseRevisionID(revisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var creationDate int64
+	rev := models.RevisionWithAuthor{ElementRevision: &model.ElementRevision{ID: revisionID}}
+
+	err = conn(ctx, r.db).QueryRow(ctx, query, id, elementURI).Scan(&rev.Title, &rev.AuthorURI, &creationDate)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apperror.NotFound("revision %s not found for element: %s", revisionID, elementURI)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revision: %w", err)
+	}
+
+	rev.CreationDate = time.UnixMilli(creationDate)
+
+	return &rev, nil
+}
+
+func parseRevisionID(revisionID string) (int64, error) {
+	id, err := strconv.ParseInt(revisionID, 10, 64)
+	if err != nil {
+		return 0, apperror.Validation("invalid revision id %q: %w", revisionID, err)
+	}
+	return id, nil
+}
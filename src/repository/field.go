@@ -4,10 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -27,26 +28,27 @@ func NewFieldRepository(db *pgxpool.Pool, typeRepo TypeRepository, user UserRepo
 }
 
 func (r *fieldRepository) GetByURI(ctx context.Context, uri string) (*model.Field, error) {
-	query := `SELECT uri, name, field_type, type_uri, creation_date, author, options, required FROM fields WHERE uri = $1`
+	query := `SELECT uri, name, field_type, type_uri, creation_date, author, options, required, constraints FROM fields WHERE uri = $1`
 
 	var field model.Field
 	var fieldTypeStr, typeURI, authorURI string
 	var creationDate int64
-	var options *string
+	var options, constraints *string
 
 	err := r.db.QueryRow(ctx, query, uri).Scan(
-		&field.URI, &field.Name, &fieldTypeStr, &typeURI, &creationDate, &authorURI, &options, &field.Required,
+		&field.URI, &field.Name, &fieldTypeStr, &typeURI, &creationDate, &authorURI, &options, &field.Required, &constraints,
 	)
 	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, fmt.Errorf("field not found: %s", uri)
+		return nil, apperror.NotFound("field not found: %s", uri)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get field: %w", err)
 	}
 
 	field.FieldType = model.FieldType(strings.ToUpper(fieldTypeStr))
-	field.CreationDate = strconv.FormatInt(creationDate, 10)
+	field.CreationDate = time.UnixMilli(creationDate)
 	field.Options = options
+	field.Constraints = constraints
 
 	fieldType, err := r.typeRepo.GetByURI(ctx, typeURI)
 	if err != nil {
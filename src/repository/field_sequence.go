@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type FieldSequenceRepository interface {
+	Next(ctx context.Context, fieldURI string) (int64, error)
+}
+
+type fieldSequenceRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewFieldSequenceRepository(db *pgxpool.Pool) FieldSequenceRepository {
+	return &fieldSequenceRepository{db: db}
+}
+
+// Next returns the next number of an auto-increment field, starting at 1. The
+// sequence stays locked until the surrounding transaction ends and is only
+// advanced if it commits, so numbers are handed out without gaps.
+func (r *fieldSequenceRepository) Next(ctx context.Context, fieldURI string) (int64, error) {
+	var value int64
+	err := conn(ctx, r.db).QueryRow(ctx, `
+		INSERT INTO field_sequences (field_uri, value) VALUES ($1, 1)
+		ON CONFLICT (field_uri) DO UPDATE SET value = field_sequences.value + 1
+		RETURNING value
+	`, fieldURI).Scan(&value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to advance field sequence: %w", err)
+	}
+	return value, nil
+}
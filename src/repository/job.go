@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bamdadam/backend/src/apperror"
+	models "github.com/bamdadam/backend/src/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// JobRepository stores the queue of background jobs. Apart from Create, its
+// methods deliberately bypass the transaction carried by ctx: the bookkeeping
+// of a job must not be rolled back with the work the job does.
+type JobRepository interface {
+	Create(ctx context.Context, job *models.Job) error
+	GetByID(ctx context.Context, id string) (*models.Job, error)
+	Claim(ctx context.Context, now, lockedUntil int64) (*models.Job, error)
+	Heartbeat(ctx context.Context, id string, attempt int32, progress int32, total *int32, lockedUntil, now int64) (bool, error)
+	Finish(ctx context.Context, id string, attempt int32, status string, result []byte, errMsg *string, now int64) error
+	Retry(ctx context.Context, id string, attempt int32, errMsg string, runAfter, now int64) error
+	Release(ctx context.Context, id string, attempt int32, now int64) error
+	Cancel(ctx context.Context, id, userURI string, now int64) (*models.Job, error)
+}
+
+type jobRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewJobRepository(db *pgxpool.Pool) JobRepository {
+	return &jobRepository{db: db}
+}
+
+const jobColumns = `id, kind, payload, user_uri, status, progress, total, result, error, attempts, max_attempts,
+	cancel_requested, run_after, creation_date, started_date, finished_date, updated_date`
+
+func scanJob(row pgx.Row) (*models.Job, error) {
+	var job models.Job
+	err := row.Scan(&job.ID, &job.Kind, &job.Payload, &job.UserURI, &job.Status, &job.Progress, &job.Total,
+		&job.Result, &job.Error, &job.Attempts, &job.MaxAttempts, &job.CancelRequested, &job.RunAfter,
+		&job.CreationDate, &job.StartedDate, &job.FinishedDate, &job.UpdatedDate)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Create queues a job. It runs in the transaction carried by ctx, if any, so
+// that the job is only queued if the transaction commits.
+func (r *jobRepository) Create(ctx context.Context, job *models.Job) error {
+	query := `
+		INSERT INTO jobs (id, kind, payload, user_uri, status, max_attempts, run_after, creation_date, updated_date)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+	`
+
+	_, err := conn(ctx, r.db).Exec(ctx, query, job.ID, job.Kind, job.Payload, job.UserURI, job.Status,
+		job.MaxAttempts, job.RunAfter, job.CreationDate)
+	if err != nil {
+		return fmt.Errorf("failed to create job: %w", err)
+	}
+	return nil
+}
+
+func (r *jobRepository) GetByID(ctx context.Context, id string) (*models.Job, error) {
+	job, err := scanJob(r.db.QueryRow(ctx, `SELECT `+jobColumns+` FROM jobs WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apperror.NotFound("job not found: %s", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	return job, nil
+}
+
+// Claim marks the next runnable job as running for the calling worker until
+// lockedUntil, and returns it, or nil when there is none. Runnable jobs are
+// the queued ones due to run and the running ones whose worker stopped
+// renewing its lock. Every claim counts as an attempt.
+func (r *jobRepository) Claim(ctx context.Context, now, lockedUntil int64) (*models.Job, error) {
+	query := `
+		UPDATE jobs SET status = 'running', attempts = attempts + 1, locked_until = $2,
+			started_date = COALESCE(started_date, $1), updated_date = $1
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE (status = 'queued' AND run_after <= $1) OR (status = 'running' AND locked_until < $1)
+			ORDER BY run_after, creation_date
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + jobColumns
+
+	job, err := scanJob(r.db.QueryRow(ctx, query, now, lockedUntil))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim job: %w", err)
+	}
+	return job, nil
+}
+
+// Heartbeat records the progress of a running job and extends its lock, and
+// tells whether it has been asked to cancel. The writes of a worker whose
+// attempt has been taken over by another one are ignored, which is reported
+// as a cancellation.
+func (r *jobRepository) Heartbeat(ctx context.Context, id string, attempt int32, progress int32, total *int32, lockedUntil, now int64) (bool, error) {
+	query := `
+		UPDATE jobs SET progress = $3, total = $4, locked_until = $5, updated_date = $6
+		WHERE id = $1 AND attempts = $2 AND status = 'running'
+		RETURNING cancel_requested
+	`
+
+	var cancelRequested bool
+	err := r.db.QueryRow(ctx, query, id, attempt, progress, total, lockedUntil, now).Scan(&cancelRequested)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update job progress: %w", err)
+	}
+	return cancelRequested, nil
+}
+
+// Finish ends an attempt of a job with its final status.
+func (r *jobRepository) Finish(ctx context.Context, id string, attempt int32, status string, result []byte, errMsg *string, now int64) error {
+	query := `
+		UPDATE jobs SET status = $3, result = $4, error = $5, locked_until = NULL, finished_date = $6, updated_date = $6
+		WHERE id = $1 AND attempts = $2 AND status = 'running'
+	`
+
+	if _, err := r.db.Exec(ctx, query, id, attempt, status, result, errMsg, now); err != nil {
+		return fmt.Errorf("failed to finish job: %w", err)
+	}
+	return nil
+}
+
+// Retry puts a job whose attempt failed back in the queue, to run again after
+// runAfter.
+func (r *jobRepository) Retry(ctx context.Context, id string, attempt int32, errMsg string, runAfter, now int64) error {
+	query := `
+		UPDATE jobs SET status = 'queued', error = $3, run_after = $4, locked_until = NULL, updated_date = $5
+		WHERE id = $1 AND attempts = $2 AND status = 'running'
+	`
+
+	if _, err := r.db.Exec(ctx, query, id, attempt, errMsg, runAfter, now); err != nil {
+		return fmt.Errorf("failed to retry job: %w", err)
+	}
+	return nil
+}
+
+// Release puts a job back in the queue without counting the interrupted
+// attempt, for workers shutting down.
+func (r *jobRepository) Release(ctx context.Context, id string, attempt int32, now int64) error {
+	query := `
+		UPDATE jobs SET status = 'queued', attempts = attempts - 1, progress = 0, locked_until = NULL,
+			run_after = $3, updated_date = $3
+		WHERE id = $1 AND attempts = $2 AND status = 'running'
+	`
+
+	if _, err := r.db.Exec(ctx, query, id, attempt, now); err != nil {
+		return fmt.Errorf("failed to release job: %w", err)
+	}
+	return nil
+}
+
+// Cancel cancels a queued job of a user right away, and asks the worker of a
+// running one to stop. Jobs that already ended are returned unchanged.
+func (r *jobRepository) Cancel(ctx context.Context, id, userURI string, now int64) (*models.Job, error) {
+	query := `
+		UPDATE jobs SET cancel_requested = true, updated_date = $3,
+			status = CASE WHEN status = 'queued' THEN 'cancelled' ELSE status END,
+			finished_date = CASE WHEN status = 'queued' THEN $3 ELSE finished_date END
+		WHERE id = $1 AND user_uri = $2 AND status IN ('queued', 'running')
+		RETURNING ` + jobColumns
+
+	job, err := scanJob(r.db.QueryRow(ctx, query, id, userURI, now))
+	if errors.Is(err, pgx.ErrNoRows) {
+		job, err = r.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if job.UserURI != userURI {
+			return nil, apperror.NotFound("job not found: %s", id)
+		}
+		return job, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel job: %w", err)
+	}
+	return job, nil
+}
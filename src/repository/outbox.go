@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	models "github.com/bamdadam/backend/src/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// OutboxRepository stores the events of the outbox. Events are appended in the
+// transaction carried by ctx, so that they are only relayed if it commits.
+type OutboxRepository interface {
+	Append(ctx context.Context, event *models.OutboxEvent) error
+	ClaimUndispatched(ctx context.Context, limit int32) ([]*models.OutboxEvent, error)
+	MarkDispatched(ctx context.Context, ids []int64, now int64) error
+	Snapshot(ctx context.Context) (string, error)
+	ListCommittedSince(ctx context.Context, snapshot string, aggregateURIs []string) ([]*models.OutboxEvent, string, error)
+	DeleteDispatchedBefore(ctx context.Context, cutoff int64) (int64, error)
+}
+
+type outboxRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewOutboxRepository(db *pgxpool.Pool) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+const outboxColumns = `id, event_id, topic, aggregate_uri, payload, tx_id::text, creation_date`
+
+func scanOutboxEvents(rows pgx.Rows) ([]*models.OutboxEvent, error) {
+	defer rows.Close()
+
+	var events []*models.OutboxEvent
+	for rows.Next() {
+		var e models.OutboxEvent
+		err := rows.Scan(&e.ID, &e.EventID, &e.Topic, &e.AggregateURI, &e.Payload, &e.TxID, &e.CreationDate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		events = append(events, &e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating outbox events: %w", err)
+	}
+	return events, nil
+}
+
+func (r *outboxRepository) Append(ctx context.Context, event *models.OutboxEvent) error {
+	query := `
+		INSERT INTO outbox (event_id, topic, aggregate_uri, payload, creation_date)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := conn(ctx, r.db).Exec(ctx, query, event.EventID, event.Topic, event.AggregateURI, event.Payload, event.CreationDate)
+	if err != nil {
+		return fmt.Errorf("failed to append outbox event: %w", err)
+	}
+	return nil
+}
+
+// ClaimUndispatched locks the oldest events not handed to webhooks yet until
+// the transaction carried by ctx ends, skipping those claimed by other relays.
+func (r *outboxRepository) ClaimUndispatched(ctx context.Context, limit int32) ([]*models.OutboxEvent, error) {
+	query := `
+		SELECT ` + outboxColumns + `
+		FROM outbox
+		WHERE dispatched_date IS NULL
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+	return scanOutboxEvents(rows)
+}
+
+func (r *outboxRepository) MarkDispatched(ctx context.Context, ids []int64, now int64) error {
+	_, err := conn(ctx, r.db).Exec(ctx, `UPDATE outbox SET dispatched_date = $2 WHERE id = ANY($1)`, ids, now)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox events dispatched: %w", err)
+	}
+	return nil
+}
+
+// Snapshot returns the current database snapshot, from which
+// ListCommittedSince lists the events committed later on.
+func (r *outboxRepository) Snapshot(ctx context.Context) (string, error) {
+	var snapshot string
+	if err := r.db.QueryRow(ctx, `SELECT pg_current_snapshot()::text`).Scan(&snapshot); err != nil {
+		return "", fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	return snapshot, nil
+}
+
+// ListCommittedSince returns the events of the given aggregates written by
+// transactions that have committed since snapshot was taken, in the order
+// they were written, along with the snapshot to list the next ones from.
+// Transactions still running are left for a later call, without holding back
+// the events of those that committed meanwhile.
+func (r *outboxRepository) ListCommittedSince(ctx context.Context, snapshot string, aggregateURIs []string) ([]*models.OutboxEvent, string, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// both queries see the same snapshot, the one of the transaction
+	var next string
+	if err = tx.QueryRow(ctx, `SELECT pg_current_snapshot()::text`).Scan(&next); err != nil {
+		return nil, "", fmt.Errorf("failed to get snapshot: %w", err)
+	}
+
+	query := `
+		SELECT ` + outboxColumns + `
+		FROM outbox
+		WHERE aggregate_uri = ANY($1)
+			AND tx_id >= pg_snapshot_xmin($2::text::pg_snapshot)
+			AND NOT pg_visible_in_snapshot(tx_id, $2::text::pg_snapshot)
+		ORDER BY id
+	`
+
+	rows, err := tx.Query(ctx, query, aggregateURIs, snapshot)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list outbox events: %w", err)
+	}
+	events, err := scanOutboxEvents(rows)
+	if err != nil {
+		return nil, "", err
+	}
+	return events, next, nil
+}
+
+// DeleteDispatchedBefore purges the events handed to webhooks before cutoff
+// and returns how many were deleted.
+func (r *outboxRepository) DeleteDispatchedBefore(ctx context.Context, cutoff int64) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM outbox WHERE dispatched_date < $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge outbox: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
+	models "github.com/bamdadam/backend/src/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type RollupRepository interface {
+	GetByFieldURI(ctx context.Context, fieldURI string) (*models.Rollup, error)
+	ListAffected(ctx context.Context, typeURI string) ([]*models.Rollup, error)
+	Refresh(ctx context.Context, rollup *models.Rollup, elementURI string, previous []string, updatedDate int64) error
+}
+
+type rollupRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRollupRepository(db *pgxpool.Pool) RollupRepository {
+	return &rollupRepository{db: db}
+}
+
+// rollupQuery selects rollup fields along with their relation and target
+// fields, for the conditions appended to it.
+const rollupQuery = `
+	SELECT r.uri, r.type_uri, r.options, rel.uri, rel.type_uri, rel.options->>'targetTypeUri', tf.type_uri, tf.field_type
+	FROM fields r
+	LEFT JOIN fields rel ON rel.uri = r.options->>'relationFieldUri' AND rel.field_type = 'relation'
+	LEFT JOIN fields tf ON tf.uri = r.options->>'targetFieldUri'
+	WHERE r.field_type = 'rollup'
+`
+
+// GetByFieldURI returns a rollup field by its URI.
+func (r *rollupRepository) GetByFieldURI(ctx context.Context, fieldURI string) (*models.Rollup, error) {
+	rollups, err := r.list(ctx, rollupQuery+` AND r.uri = $1`, fieldURI)
+	if err != nil {
+		return nil, err
+	}
+	if len(rollups) == 0 {
+		return nil, apperror.NotFound("rollup field not found: %s", fieldURI)
+	}
+	return rollups[0], nil
+}
+
+// ListAffected returns the rollups whose values may change when an element
+// of the given type is written: the rollups of the type itself and the
+// rollups aggregating over relations from or to it.
+func (r *rollupRepository) ListAffected(ctx context.Context, typeURI string) ([]*models.Rollup, error) {
+	query := rollupQuery + `
+		AND (r.type_uri = $1 OR rel.type_uri = $1 OR rel.options->>'targetTypeUri' = $1)
+		ORDER BY r.uri
+	`
+	return r.list(ctx, query, typeURI)
+}
+
+func (r *rollupRepository) list(ctx context.Context, query string, args ...any) ([]*models.Rollup, error) {
+	rows, err := conn(ctx, r.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rollup fields: %w", err)
+	}
+
+	var rollups []*models.Rollup
+	var fieldURI, typeURI string
+	var options, relationURI, relationTypeURI, relationTargetURI, targetTypeURI, targetFieldType *string
+
+	_, err = pgx.ForEachRow(rows,
+		[]any{&fieldURI, &typeURI, &options, &relationURI, &relationTypeURI, &relationTargetURI, &targetTypeURI, &targetFieldType},
+		func() error {
+			opts, err := models.ParseRollupOptions(options)
+			if err != nil {
+				return apperror.Validation("invalid rollup field %s: %w", fieldURI, err)
+			}
+
+			rollup := &models.Rollup{FieldURI: fieldURI, TypeURI: typeURI, RollupOptions: *opts}
+
+			// the type of the elements the rollup aggregates over
+			var linkedTypeURI *string
+			switch {
+			case relationURI == nil:
+				return apperror.Validation("invalid rollup field %s: relation field not found: %s", fieldURI, opts.RelationFieldURI)
+			case *relationTypeURI == typeURI:
+				rollup.Forward = true
+				linkedTypeURI = relationTargetURI
+			case relationTargetURI != nil && *relationTargetURI == typeURI:
+				linkedTypeURI = relationTypeURI
+			default:
+				return apperror.Validation("invalid rollup field %s: relation field %s neither belongs nor links to type %s", fieldURI, *relationURI, typeURI)
+			}
+
+			var targetType model.FieldType
+			if opts.TargetFieldURI != "" {
+				if targetTypeURI == nil || linkedTypeURI == nil || *targetTypeURI != *linkedTypeURI {
+					return apperror.Validation("invalid rollup field %s: target field %s is not a field of the linked elements", fieldURI, opts.TargetFieldURI)
+				}
+				targetType = model.FieldType(strings.ToUpper(*targetFieldType))
+			}
+
+			rollup.ResultType, err = opts.ResultType(targetType)
+			if err != nil {
+				return apperror.Validation("invalid rollup field %s: %w", fieldURI, err)
+			}
+
+			rollups = append(rollups, rollup)
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rollup fields: %w", err)
+	}
+
+	return rollups, nil
+}
+
+// Refresh recomputes the values of a rollup after elementURI was written, for
+// the element itself if the rollup belongs to its type and for the elements
+// of the rollup's type it is linked with through the rollup's relation. previous
+// holds elements it was linked with before the write, which need their values
+// recomputed as well. Rollups without any value to aggregate have their value
+// removed, except for count which is 0.
+func (r *rollupRepository) Refresh(ctx context.Context, rollup *models.Rollup, elementURI string, previous []string, updatedDate int64) error {
+	links := `SELECT target_element_uri AS owner, source_element_uri AS linked, creation_date AS ord FROM element_links WHERE field_uri = $3`
+	if rollup.Forward {
+		links = `SELECT source_element_uri AS owner, target_element_uri AS linked, position AS ord FROM element_links WHERE field_uri = $3`
+	}
+
+	column, aggregate := rollupAggregate(rollup)
+
+	query := fmt.Sprintf(`
+		WITH owners AS (
+			SELECT e.uri FROM elements e
+			WHERE e.type_uri = $2 AND (
+				e.uri = $1 OR e.uri = ANY($6)
+				OR e.uri IN (SELECT target_element_uri FROM element_links WHERE source_element_uri = $1 AND field_uri = $3)
+				OR e.uri IN (SELECT source_element_uri FROM element_links WHERE target_element_uri = $1 AND field_uri = $3)
+			)
+		),
+		links AS (%[1]s),
+		aggregated AS (
+			SELECT o.uri AS owner, %[2]s AS value
+			FROM owners o
+			LEFT JOIN links l ON l.owner = o.uri
+			LEFT JOIN element_field_values v ON v.element_uri = l.linked AND v.field_uri = $4
+			GROUP BY o.uri
+		),
+		cleared AS (
+			DELETE FROM element_field_values efv
+			USING aggregated r
+			WHERE efv.element_uri = r.owner AND efv.field_uri = $5 AND r.value IS NULL
+		)
+		INSERT INTO element_field_values (uri, element_uri, field_uri, %[3]s, creation_date, updated_date)
+		SELECT 'efv:' || r.owner || ':' || $5, r.owner, $5, r.value, $7, $7
+		FROM aggregated r
+		WHERE r.value IS NOT NULL
+		ON CONFLICT (element_uri, field_uri) DO UPDATE
+		SET %[3]s = EXCLUDED.%[3]s, updated_date = EXCLUDED.updated_date
+		WHERE element_field_values.%[3]s IS DISTINCT FROM EXCLUDED.%[3]s
+	`, links, aggregate, column)
+
+	_, err := conn(ctx, r.db).Exec(ctx, query,
+		elementURI, rollup.TypeURI, rollup.RelationFieldURI, rollup.TargetFieldURI, rollup.FieldURI, previous, updatedDate,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to refresh rollup %s: %w", rollup.FieldURI, err)
+	}
+	return nil
+}
+
+// rollupAggregate returns the element_field_values column a rollup is stored
+// in and the SQL aggregate computing it over linked elements l and their
+// target field values v.
+func rollupAggregate(rollup *models.Rollup) (string, string) {
+	switch {
+	case rollup.Function == models.RollupCount:
+		return "value_number", "count(l.linked)::double precision"
+	case rollup.Function == models.RollupConcat:
+		return "value_text", "string_agg(v.value_text, ', ' ORDER BY l.ord, l.linked)"
+	case rollup.ResultType == model.FieldTypeDate:
+		return "value_date", rollup.Function + "(v.value_date)"
+	default:
+		// sum, avg, min and max of numbers
+		return "value_number", rollup.Function + "(v.value_number)"
+	}
+}
@@ -4,15 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strconv"
+	"time"
 
 	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type SpaceRepository interface {
 	GetByURI(ctx context.Context, uri string) (*model.Space, error)
+	Create(ctx context.Context, uri, name, tenantURI string, creationDate int64) error
 }
 
 type spaceRepository struct {
@@ -35,13 +38,13 @@ func (r *spaceRepository) GetByURI(ctx context.Context, uri string) (*model.Spac
 		&space.URI, &space.Name, &creationDate, &tenantURI,
 	)
 	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, fmt.Errorf("space not found: %s", uri)
+		return nil, apperror.NotFound("space not found: %s", uri)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get space: %w", err)
 	}
 
-	space.CreationDate = strconv.FormatInt(creationDate, 10)
+	space.CreationDate = time.UnixMilli(creationDate)
 
 	tenant, err := r.tenant.GetByURI(ctx, tenantURI)
 	if err != nil {
@@ -51,3 +54,20 @@ func (r *spaceRepository) GetByURI(ctx context.Context, uri string) (*model.Spac
 
 	return &space, nil
 }
+
+// Create inserts a new space in a tenant, returning a CONFLICT error if a space
+// with the same URI exists.
+func (r *spaceRepository) Create(ctx context.Context, uri, name, tenantURI string, creationDate int64) error {
+	query := `INSERT INTO spaces (uri, name, tenant_uri, creation_date) VALUES ($1, $2, $3, $4)`
+
+	_, err := conn(ctx, r.db).Exec(ctx, query, uri, name, tenantURI, creationDate)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return apperror.Conflict("space already exists: %s", uri)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create space: %w", err)
+	}
+	return nil
+}
@@ -4,16 +4,20 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type TenantRepository interface {
 	GetByURI(ctx context.Context, uri string) (*model.Tenant, error)
+	Create(ctx context.Context, tenant *model.Tenant) error
+	SetStatus(ctx context.Context, uri string, status model.TenantStatus) error
 }
 
 type tenantRepository struct {
@@ -35,14 +39,44 @@ func (r *tenantRepository) GetByURI(ctx context.Context, uri string) (*model.Ten
 		&tenant.URI, &tenant.Name, &status, &creationDate,
 	)
 	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, fmt.Errorf("tenant not found: %s", uri)
+		return nil, apperror.NotFound("tenant not found: %s", uri)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get tenant: %w", err)
 	}
 
 	tenant.Status = model.TenantStatus(strings.ToUpper(status))
-	tenant.CreationDate = strconv.FormatInt(creationDate, 10)
+	tenant.CreationDate = time.UnixMilli(creationDate)
 
 	return &tenant, nil
 }
+
+// Create inserts a new tenant, returning a CONFLICT error if a tenant with the
+// same URI exists.
+func (r *tenantRepository) Create(ctx context.Context, tenant *model.Tenant) error {
+	query := `INSERT INTO tenants (uri, name, status, creation_date) VALUES ($1, $2, $3, $4)`
+
+	_, err := conn(ctx, r.db).Exec(ctx, query,
+		tenant.URI, tenant.Name, strings.ToLower(string(tenant.Status)), tenant.CreationDate.UnixMilli(),
+	)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return apperror.Conflict("tenant already exists: %s", tenant.URI)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create tenant: %w", err)
+	}
+	return nil
+}
+
+func (r *tenantRepository) SetStatus(ctx context.Context, uri string, status model.TenantStatus) error {
+	result, err := conn(ctx, r.db).Exec(ctx, `UPDATE tenants SET status = $2 WHERE uri = $1`, uri, strings.ToLower(string(status)))
+	if err != nil {
+		return fmt.Errorf("failed to set tenant status: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return apperror.NotFound("tenant not found: %s", uri)
+	}
+	return nil
+}
@@ -4,15 +4,20 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strconv"
+	"strings"
+	"time"
 
 	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
+	models "github.com/bamdadam/backend/src/model"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type TypeRepository interface {
 	GetByURI(ctx context.Context, uri string) (*model.Type, error)
+	ListDefinitions(ctx context.Context, tenantURI string) ([]*models.TypeDefinition, error)
+	GetDefinition(ctx context.Context, uri string) (*models.TypeDefinition, error)
 }
 
 type typeRepository struct {
@@ -37,12 +42,12 @@ func (r *typeRepository) GetByURI(ctx context.Context, uri string) (*model.Type,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, fmt.Errorf("type not found: %s", uri)
+			return nil, apperror.NotFound("type not found: %s", uri)
 		}
 		return nil, fmt.Errorf("failed to get type: %w", err)
 	}
 
-	t.CreationDate = strconv.FormatInt(creationDate, 10)
+	t.CreationDate = time.UnixMilli(creationDate)
 
 	t.Space, err = r.space.GetByURI(ctx, spaceURI)
 	if err != nil {
@@ -56,3 +61,83 @@ func (r *typeRepository) GetByURI(ctx context.Context, uri string) (*model.Type,
 
 	return &t, nil
 }
+
+// ListDefinitions returns all types of a tenant with their fields, ordered by
+// type URI.
+func (r *typeRepository) ListDefinitions(ctx context.Context, tenantURI string) ([]*models.TypeDefinition, error) {
+	query := `
+		SELECT t.uri, t.name, t.space_uri, f.uri, f.name, f.field_type, f.options, f.required, f.constraints
+		FROM types t
+		JOIN spaces s ON s.uri = t.space_uri
+		LEFT JOIN fields f ON f.type_uri = t.uri
+		WHERE s.tenant_uri = $1
+		ORDER BY t.uri, f.creation_date, f.uri
+	`
+
+	rows, err := r.db.Query(ctx, query, tenantURI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list type definitions: %w", err)
+	}
+	return scanDefinitions(rows)
+}
+
+// GetDefinition returns a type with its fields.
+func (r *typeRepository) GetDefinition(ctx context.Context, uri string) (*models.TypeDefinition, error) {
+	query := `
+		SELECT t.uri, t.name, t.space_uri, f.uri, f.name, f.field_type, f.options, f.required, f.constraints
+		FROM types t
+		LEFT JOIN fields f ON f.type_uri = t.uri
+		WHERE t.uri = $1
+		ORDER BY f.creation_date, f.uri
+	`
+
+	rows, err := r.db.Query(ctx, query, uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get type definition: %w", err)
+	}
+
+	defs, err := scanDefinitions(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(defs) == 0 {
+		return nil, apperror.NotFound("type not found: %s", uri)
+	}
+	return defs[0], nil
+}
+
+// scanDefinitions groups rows of types joined with their fields, ordered by
+// type, into type definitions.
+func scanDefinitions(rows pgx.Rows) ([]*models.TypeDefinition, error) {
+	var defs []*models.TypeDefinition
+	var typeURI, typeName, spaceURI string
+	var fieldURI, fieldName, fieldType, options, constraints *string
+	var required *bool
+
+	_, err := pgx.ForEachRow(rows,
+		[]any{&typeURI, &typeName, &spaceURI, &fieldURI, &fieldName, &fieldType, &options, &required, &constraints},
+		func() error {
+			if len(defs) == 0 || defs[len(defs)-1].URI != typeURI {
+				defs = append(defs, &models.TypeDefinition{URI: typeURI, Name: typeName, SpaceURI: spaceURI})
+			}
+			if fieldURI == nil {
+				return nil
+			}
+
+			def := defs[len(defs)-1]
+			def.Fields = append(def.Fields, &models.FieldDefinition{
+				URI:         *fieldURI,
+				Name:        *fieldName,
+				FieldType:   model.FieldType(strings.ToUpper(*fieldType)),
+				Options:     options,
+				Required:    *required,
+				Constraints: constraints,
+			})
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan type definitions: %w", err)
+	}
+
+	return defs, nil
+}
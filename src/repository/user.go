@@ -6,13 +6,17 @@ import (
 	"fmt"
 
 	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type UserRepository interface {
 	GetByURI(ctx context.Context, uri string) (*model.User, error)
 	GetByEmail(ctx context.Context, email string) (*model.User, error)
+	Create(ctx context.Context, user *model.User) error
+	List(ctx context.Context, tenantURI string) ([]*model.User, error)
 }
 
 type userRepository struct {
@@ -31,7 +35,7 @@ func (r *userRepository) GetByURI(ctx context.Context, uri string) (*model.User,
 		&user.URI, &user.Email, &user.DisplayName,
 	)
 	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, fmt.Errorf("user not found: %s", uri)
+		return nil, apperror.NotFound("user not found: %s", uri)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
@@ -48,7 +52,7 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*model.U
 		&user.URI, &user.Email, &user.DisplayName,
 	)
 	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, fmt.Errorf("user not found with email: %s", email)
+		return nil, apperror.NotFound("user not found with email: %s", email)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
@@ -56,3 +60,47 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*model.U
 
 	return &user, nil
 }
+
+// Create inserts a new user, returning a CONFLICT error if a user with the same
+// URI or email exists.
+func (r *userRepository) Create(ctx context.Context, user *model.User) error {
+	query := `INSERT INTO users (uri, email, display_name) VALUES ($1, $2, $3)`
+
+	_, err := conn(ctx, r.db).Exec(ctx, query, user.URI, user.Email, user.DisplayName)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		if pgErr.ConstraintName == "users_email_key" {
+			return apperror.Conflict("user already exists with email: %s", user.Email)
+		}
+		return apperror.Conflict("user already exists: %s", user.URI)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	return nil
+}
+
+// List returns the users sorted by email, only the members of a tenant if
+// tenantURI is set.
+func (r *userRepository) List(ctx context.Context, tenantURI string) ([]*model.User, error) {
+	query := `
+		SELECT uri, email, display_name FROM users
+		WHERE $1 = '' OR uri IN (SELECT user_uri FROM user_tenants WHERE tenant_uri = $1)
+		ORDER BY email
+	`
+
+	rows, err := r.db.Query(ctx, query, tenantURI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	users, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.User, error) {
+		var user model.User
+		err := row.Scan(&user.URI, &user.Email, &user.DisplayName)
+		return &user, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect users: %w", err)
+	}
+	return users, nil
+}
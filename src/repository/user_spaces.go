@@ -2,14 +2,21 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/bamdadam/backend/src/apperror"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type UserSpacesRepository interface {
 	GetByUser(ctx context.Context, uri string) ([]string, error)
+	GetByUserAndVerbs(ctx context.Context, uri string, verbs []string) ([]string, error)
+	GetVerbs(ctx context.Context, userURI, spaceURI string) ([]string, error)
+	Grant(ctx context.Context, userURI, spaceURI string, verbs []string) error
+	Revoke(ctx context.Context, userURI, spaceURI string, verbs []string) error
 }
 
 type userSpacesRepository struct {
@@ -21,7 +28,12 @@ func NewUserSpacesRepository(db *pgxpool.Pool) UserSpacesRepository {
 }
 
 func (r *userSpacesRepository) GetByUser(ctx context.Context, uri string) ([]string, error) {
-	query := `SELECT space_uri from user_spaces WHERE user_uri = $1`
+	query := `
+		SELECT us.space_uri FROM user_spaces us
+		JOIN spaces s ON s.uri = us.space_uri
+		JOIN tenants t ON t.uri = s.tenant_uri
+		WHERE us.user_uri = $1 AND t.status = 'active'
+	`
 
 	rows, err := r.db.Query(ctx, query, uri)
 	if err != nil {
@@ -49,3 +61,88 @@ func (r *userSpacesRepository) GetByUser(ctx context.Context, uri string) ([]str
 	//}
 	return spaceList, nil
 }
+
+// GetByUserAndVerbs returns the spaces a user has been granted any of verbs
+// on, leaving out those of inactive tenants.
+func (r *userSpacesRepository) GetByUserAndVerbs(ctx context.Context, uri string, verbs []string) ([]string, error) {
+	query := `
+		SELECT DISTINCT p.space_uri FROM user_space_permissions p
+		JOIN spaces s ON s.uri = p.space_uri
+		JOIN tenants t ON t.uri = s.tenant_uri
+		WHERE p.user_uri = $1 AND p.verb_uri = ANY($2) AND t.status = 'active'
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, uri, verbs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user space permissions: %w", err)
+	}
+	spaces, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect user space permissions: %w", err)
+	}
+	return spaces, nil
+}
+
+// GetVerbs returns the verbs a user has been granted on a space.
+func (r *userSpacesRepository) GetVerbs(ctx context.Context, userURI, spaceURI string) ([]string, error) {
+	query := `SELECT verb_uri FROM user_space_permissions WHERE user_uri = $1 AND space_uri = $2 ORDER BY verb_uri`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, userURI, spaceURI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user space permissions: %w", err)
+	}
+	verbs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect user space permissions: %w", err)
+	}
+	return verbs, nil
+}
+
+// foreignKeyViolation is the SQLSTATE of foreign key violations.
+const foreignKeyViolation = "23503"
+
+// Grant gives a user access to a space along with verbs, keeping those granted
+// before.
+func (r *userSpacesRepository) Grant(ctx context.Context, userURI, spaceURI string, verbs []string) error {
+	query := `INSERT INTO user_spaces (user_uri, space_uri) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	if _, err := conn(ctx, r.db).Exec(ctx, query, userURI, spaceURI); err != nil {
+		return fmt.Errorf("failed to grant space: %w", err)
+	}
+
+	query = `
+		INSERT INTO user_space_permissions (user_uri, space_uri, verb_uri)
+		SELECT $1, $2, unnest($3::text[])
+		ON CONFLICT DO NOTHING
+	`
+	_, err := conn(ctx, r.db).Exec(ctx, query, userURI, spaceURI, verbs)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return apperror.Validation("unknown permission verb in %v", verbs)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to grant permissions: %w", err)
+	}
+	return nil
+}
+
+// Revoke takes verbs on a space away from a user, or the access to the space
+// along with every verb if verbs is empty.
+func (r *userSpacesRepository) Revoke(ctx context.Context, userURI, spaceURI string, verbs []string) error {
+	query := `
+		DELETE FROM user_space_permissions
+		WHERE user_uri = $1 AND space_uri = $2 AND (cardinality($3::text[]) = 0 OR verb_uri = ANY($3))
+	`
+	if _, err := conn(ctx, r.db).Exec(ctx, query, userURI, spaceURI, verbs); err != nil {
+		return fmt.Errorf("failed to revoke permissions: %w", err)
+	}
+	if len(verbs) > 0 {
+		return nil
+	}
+
+	query = `DELETE FROM user_spaces WHERE user_uri = $1 AND space_uri = $2`
+	if _, err := conn(ctx, r.db).Exec(ctx, query, userURI, spaceURI); err != nil {
+		return fmt.Errorf("failed to revoke space: %w", err)
+	}
+	return nil
+}
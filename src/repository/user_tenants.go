@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	TenantRoleAdmin  = "admin"
+	TenantRoleMember = "member"
+)
+
+type UserTenantsRepository interface {
+	GetRole(ctx context.Context, userURI, tenantURI string) (string, error)
+	AddMember(ctx context.Context, userURI, tenantURI, role string) error
+}
+
+type userTenantsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewUserTenantsRepository(db *pgxpool.Pool) UserTenantsRepository {
+	return &userTenantsRepository{db: db}
+}
+
+// GetRole returns the role of a user within a tenant, or an empty string if
+// the user is not a member of the tenant.
+func (r *userTenantsRepository) GetRole(ctx context.Context, userURI, tenantURI string) (string, error) {
+	query := `SELECT role FROM user_tenants WHERE user_uri = $1 AND tenant_uri = $2`
+
+	var role string
+	err := r.db.QueryRow(ctx, query, userURI, tenantURI).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get user tenant role: %w", err)
+	}
+
+	return role, nil
+}
+
+// AddMember makes a user a member of a tenant with role, leaving the role of
+// existing members as it is.
+func (r *userTenantsRepository) AddMember(ctx context.Context, userURI, tenantURI, role string) error {
+	query := `INSERT INTO user_tenants (user_uri, tenant_uri, role) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
+
+	if _, err := conn(ctx, r.db).Exec(ctx, query, userURI, tenantURI, role); err != nil {
+		return fmt.Errorf("failed to add tenant member: %w", err)
+	}
+	return nil
+}
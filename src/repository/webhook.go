@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bamdadam/backend/src/apperror"
+	models "github.com/bamdadam/backend/src/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// WebhookRepository stores webhooks and the outbox of their deliveries.
+// Deliveries are queued in the transaction carried by ctx, while the
+// bookkeeping of the delivery workers bypasses it.
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *models.Webhook) error
+	GetByURI(ctx context.Context, uri string) (*models.Webhook, error)
+	ListBySpace(ctx context.Context, spaceURI string) ([]*models.Webhook, error)
+	Delete(ctx context.Context, uri string) error
+	Enqueue(ctx context.Context, event *models.WebhookEvent, now int64) error
+	GetDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error)
+	ListDeliveries(ctx context.Context, webhookURI string, status *string, limit int32, after *int64) ([]*models.WebhookDelivery, error)
+	Redeliver(ctx context.Context, id int64, now int64) (*models.WebhookDelivery, error)
+	ClaimDelivery(ctx context.Context, now, lockedUntil int64) (*models.WebhookDelivery, error)
+	RecordAttempt(ctx context.Context, delivery *models.WebhookDelivery, now int64) error
+}
+
+type webhookRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWebhookRepository(db *pgxpool.Pool) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+const webhookColumns = `uri, space_uri, type_uri, url, events, secret, author, creation_date`
+
+func scanWebhook(row pgx.Row) (*models.Webhook, error) {
+	var w models.Webhook
+	err := row.Scan(&w.URI, &w.SpaceURI, &w.TypeURI, &w.URL, &w.Events, &w.Secret, &w.AuthorURI, &w.CreationDate)
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+const deliveryColumns = `d.id, d.webhook_uri, d.event_id, d.event, d.payload, d.status, d.attempts, d.next_attempt,
+	d.response_status, d.response_body, d.error, d.creation_date, d.delivered_date, w.url, w.secret`
+
+func scanDelivery(row pgx.Row) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	err := row.Scan(&d.ID, &d.WebhookURI, &d.EventID, &d.Event, &d.Payload, &d.Status, &d.Attempts, &d.NextAttempt,
+		&d.ResponseStatus, &d.ResponseBody, &d.Error, &d.CreationDate, &d.DeliveredDate, &d.URL, &d.Secret)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *webhookRepository) Create(ctx context.Context, webhook *models.Webhook) error {
+	query := `
+		INSERT INTO webhooks (uri, space_uri, type_uri, url, events, secret, author, creation_date)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := conn(ctx, r.db).Exec(ctx, query, webhook.URI, webhook.SpaceURI, webhook.TypeURI, webhook.URL,
+		webhook.Events, webhook.Secret, webhook.AuthorURI, webhook.CreationDate)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return nil
+}
+
+func (r *webhookRepository) GetByURI(ctx context.Context, uri string) (*models.Webhook, error) {
+	webhook, err := scanWebhook(conn(ctx, r.db).QueryRow(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE uri = $1`, uri))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apperror.NotFound("webhook not found: %s", uri)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+func (r *webhookRepository) ListBySpace(ctx context.Context, spaceURI string) ([]*models.Webhook, error) {
+	rows, err := conn(ctx, r.db).Query(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE space_uri = $1 ORDER BY creation_date, uri`, spaceURI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var webhooks []*models.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// Delete removes a webhook along with its deliveries.
+func (r *webhookRepository) Delete(ctx context.Context, uri string) error {
+	tag, err := conn(ctx, r.db).Exec(ctx, `DELETE FROM webhooks WHERE uri = $1`, uri)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperror.NotFound("webhook not found: %s", uri)
+	}
+	return nil
+}
+
+// Enqueue queues a delivery of event to every webhook of its space subscribed
+// to it, in the transaction carried by ctx so that nothing is sent for
+// mutations that are rolled back.
+func (r *webhookRepository) Enqueue(ctx context.Context, event *models.WebhookEvent, now int64) error {
+	query := `
+		INSERT INTO webhook_deliveries (webhook_uri, event_id, event, payload, next_attempt, creation_date, updated_date)
+		SELECT uri, $1, $2, $3, $6, $6, $6
+		FROM webhooks
+		WHERE space_uri = $4 AND (type_uri IS NULL OR type_uri = $5) AND $2 = ANY(events)
+	`
+
+	_, err := conn(ctx, r.db).Exec(ctx, query, event.ID, event.Event, event.Payload, event.SpaceURI, event.TypeURI, now)
+	if err != nil {
+		return fmt.Errorf("failed to queue webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+func (r *webhookRepository) GetDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries d JOIN webhooks w ON w.uri = d.webhook_uri WHERE d.id = $1`
+
+	delivery, err := scanDelivery(conn(ctx, r.db).QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apperror.NotFound("webhook delivery not found: %d", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+// ListDeliveries returns the deliveries of a webhook, latest first. It fetches
+// one more than limit so the caller can tell whether there is a next page.
+func (r *webhookRepository) ListDeliveries(ctx context.Context, webhookURI string, status *string, limit int32, after *int64) ([]*models.WebhookDelivery, error) {
+	query := `
+		SELECT ` + deliveryColumns + `
+		FROM webhook_deliveries d JOIN webhooks w ON w.uri = d.webhook_uri
+		WHERE d.webhook_uri = $1
+			AND ($2::text IS NULL OR d.status = $2)
+			AND ($3::bigint IS NULL OR d.id < $3)
+		ORDER BY d.id DESC
+		LIMIT $4
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, webhookURI, status, after, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*models.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// Redeliver queues a new delivery of the event of a past one, to be sent
+// right away.
+func (r *webhookRepository) Redeliver(ctx context.Context, id int64, now int64) (*models.WebhookDelivery, error) {
+	query := `
+		WITH d AS (
+			INSERT INTO webhook_deliveries (webhook_uri, event_id, event, payload, next_attempt, creation_date, updated_date)
+			SELECT webhook_uri, event_id, event, payload, $2, $2, $2
+			FROM webhook_deliveries
+			WHERE id = $1
+			RETURNING *
+		)
+		SELECT ` + deliveryColumns + ` FROM d JOIN webhooks w ON w.uri = d.webhook_uri
+	`
+
+	delivery, err := scanDelivery(conn(ctx, r.db).QueryRow(ctx, query, id, now))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apperror.NotFound("webhook delivery not found: %d", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeliver webhook delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+// ClaimDelivery locks the next pending delivery due to be sent until
+// lockedUntil and returns it, or nil when there is none. Deliveries whose
+// worker stopped before recording the outcome are claimed again once their
+// lock expires. Every claim counts as an attempt.
+func (r *webhookRepository) ClaimDelivery(ctx context.Context, now, lockedUntil int64) (*models.WebhookDelivery, error) {
+	query := `
+		WITH d AS (
+			UPDATE webhook_deliveries SET attempts = attempts + 1, locked_until = $2, updated_date = $1
+			WHERE id = (
+				SELECT id FROM webhook_deliveries
+				WHERE status = 'pending' AND next_attempt <= $1 AND (locked_until IS NULL OR locked_until < $1)
+				ORDER BY next_attempt, id
+				LIMIT 1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING *
+		)
+		SELECT ` + deliveryColumns + ` FROM d JOIN webhooks w ON w.uri = d.webhook_uri
+	`
+
+	delivery, err := scanDelivery(r.db.QueryRow(ctx, query, now, lockedUntil))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+// RecordAttempt stores the outcome of the attempt of a claimed delivery, and
+// releases it. Pending deliveries are tried again after NextAttempt. Nothing
+// is written if another worker has claimed the delivery since.
+func (r *webhookRepository) RecordAttempt(ctx context.Context, delivery *models.WebhookDelivery, now int64) error {
+	query := `
+		UPDATE webhook_deliveries SET status = $3, next_attempt = $4, response_status = $5, response_body = $6,
+			error = $7, delivered_date = $8, locked_until = NULL, updated_date = $9
+		WHERE id = $1 AND attempts = $2 AND status = 'pending'
+	`
+
+	_, err := r.db.Exec(ctx, query, delivery.ID, delivery.Attempts, delivery.Status, delivery.NextAttempt,
+		delivery.ResponseStatus, delivery.ResponseBody, delivery.Error, delivery.DeliveredDate, now)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery attempt: %w", err)
+	}
+	return nil
+}
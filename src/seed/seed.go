@@ -0,0 +1,409 @@
+// Package seed generates sample data for load testing. Data is generated
+// deterministically from a Spec and written with COPY in a single
+// transaction, so that runs either write all of it or nothing.
+package seed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"math/rand/v2"
+	"time"
+
+	"github.com/bamdadam/backend/graph/model"
+	models "github.com/bamdadam/backend/src/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TableStats is the number of rows written to a table.
+type TableStats struct {
+	Table    string
+	Rows     int64
+	Duration time.Duration
+}
+
+type space struct {
+	uri       string
+	tenantURI string
+}
+
+type typ struct {
+	index    int
+	uri      string
+	name     string
+	spaceURI string
+	fields   []*field
+}
+
+type field struct {
+	uri       string
+	name      string
+	fieldType model.FieldType
+	options   []string
+	// target is the type RELATION fields link to.
+	target *typ
+}
+
+type seeder struct {
+	spec    *Spec
+	tenants []string
+	spaces  []*space
+	types   []*typ
+	stats   []*TableStats
+}
+
+// Run writes the data described by spec, returning the number of rows written
+// to each table in the order they were written.
+func Run(ctx context.Context, db *pgxpool.Pool, spec *Spec) ([]*TableStats, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	var exists bool
+	err := db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM tenants WHERE uri LIKE 'tenant:' || $1 || '-%')`, spec.Prefix).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for seeded data: %w", err)
+	}
+	if exists {
+		return nil, fmt.Errorf("data seeded with prefix %q exists, use another prefix", spec.Prefix)
+	}
+
+	s := &seeder{spec: spec}
+	s.plan()
+
+	err = pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		steps := []func(ctx context.Context, tx pgx.Tx) error{
+			s.copyUsers,
+			s.copyTenants,
+			s.copySpaces,
+			s.copyTypes,
+			s.copyFields,
+			s.copyMemberships,
+			s.copyElements,
+			s.copyFieldValues,
+			s.copyLinks,
+		}
+		for _, step := range steps {
+			if err := step(ctx, tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(ctx, `ANALYZE elements, element_field_values, element_links`); err != nil {
+		return nil, fmt.Errorf("failed to analyze tables: %w", err)
+	}
+	return s.stats, nil
+}
+
+// rng returns the random numbers of a stream of a type, so that every table
+// is generated the same regardless of the others.
+func (s *seeder) rng(stream, typeIndex int) *rand.Rand {
+	return rand.New(rand.NewPCG(s.spec.Seed, uint64(stream)<<32|uint64(typeIndex)))
+}
+
+const (
+	streamStructure = iota
+	streamElements
+	streamValues
+	streamLinks
+)
+
+// plan lays out the tenants, spaces, types and fields.
+func (s *seeder) plan() {
+	r := s.rng(streamStructure, 0)
+	prefix := s.spec.Prefix
+
+	for t := range s.spec.Tenants {
+		tenantURI := fmt.Sprintf("tenant:%s-%d", prefix, t+1)
+		s.tenants = append(s.tenants, tenantURI)
+
+		for range s.spec.SpacesPerTenant {
+			sp := &space{uri: fmt.Sprintf("space:%s-%d", prefix, len(s.spaces)+1), tenantURI: tenantURI}
+			s.spaces = append(s.spaces, sp)
+
+			first := len(s.types)
+			for i := range s.spec.TypesPerSpace {
+				s.types = append(s.types, &typ{
+					index:    len(s.types),
+					uri:      fmt.Sprintf("type:%s-%d", prefix, len(s.types)+1),
+					name:     typeNames[(first+i)%len(typeNames)],
+					spaceURI: sp.uri,
+				})
+			}
+			// relations link to the next type of the space
+			spaceTypes := s.types[first:]
+			for i, ty := range spaceTypes {
+				target := spaceTypes[(i+1)%len(spaceTypes)]
+				for _, ft := range fieldTypes {
+					for n := range s.spec.Fields[fieldTypeName(ft)] {
+						f := &field{
+							uri:       fmt.Sprintf("field:%s-%d-%d", prefix, ty.index+1, len(ty.fields)+1),
+							name:      fieldName(ft, n),
+							fieldType: ft,
+						}
+						switch ft {
+						case model.FieldTypeSelect, model.FieldTypeMultiSelect:
+							f.options = selectOptions(r, s.spec.SelectOptions)
+						case model.FieldTypeRelation:
+							f.target = target
+						}
+						ty.fields = append(ty.fields, f)
+					}
+				}
+			}
+		}
+	}
+}
+
+func (s *seeder) userURI(n int) string {
+	return fmt.Sprintf("user:%s-%d", s.spec.Prefix, n+1)
+}
+
+func (s *seeder) elementURI(t *typ, n int) string {
+	return fmt.Sprintf("element:%s-%d-%d", s.spec.Prefix, t.index+1, n+1)
+}
+
+// elementDate spreads the creation of the elements of a type over the two
+// years before epoch.
+func (s *seeder) elementDate(n int) int64 {
+	span := 730 * 24 * time.Hour
+	return epoch.Add(-span).UnixMilli() + int64(n)*span.Milliseconds()/int64(max(s.spec.ElementsPerType, 1))
+}
+
+func (s *seeder) copy(ctx context.Context, tx pgx.Tx, table string, columns []string, rows iter.Seq[[]any]) error {
+	start := time.Now()
+
+	next, stop := iter.Pull(rows)
+	defer stop()
+
+	n, err := tx.CopyFrom(ctx, pgx.Identifier{table}, columns, &pullSource{next: next})
+	if err != nil {
+		return fmt.Errorf("failed to copy %s: %w", table, err)
+	}
+	s.stats = append(s.stats, &TableStats{Table: table, Rows: n, Duration: time.Since(start)})
+	return nil
+}
+
+// pullSource feeds COPY with the rows of an iterator.
+type pullSource struct {
+	next func() ([]any, bool)
+	row  []any
+}
+
+func (p *pullSource) Next() bool {
+	var ok bool
+	p.row, ok = p.next()
+	return ok
+}
+
+func (p *pullSource) Values() ([]any, error) { return p.row, nil }
+
+func (p *pullSource) Err() error { return nil }
+
+func (s *seeder) copyUsers(ctx context.Context, tx pgx.Tx) error {
+	return s.copy(ctx, tx, "users", []string{"uri", "email", "display_name"}, func(yield func([]any) bool) {
+		for n := range s.spec.Users {
+			first := firstNames[n%len(firstNames)]
+			last := lastNames[(n/len(firstNames))%len(lastNames)]
+			email := fmt.Sprintf("%s-user-%d@example.com", s.spec.Prefix, n+1)
+			if !yield([]any{s.userURI(n), email, first + " " + last}) {
+				return
+			}
+		}
+	})
+}
+
+func (s *seeder) copyTenants(ctx context.Context, tx pgx.Tx) error {
+	return s.copy(ctx, tx, "tenants", []string{"uri", "name", "status", "creation_date"}, func(yield func([]any) bool) {
+		for i, uri := range s.tenants {
+			name := fmt.Sprintf("%s %s %d", capitalize(words[i%len(words)]), "Corporation", i+1)
+			if !yield([]any{uri, name, "active", epoch.AddDate(-3, 0, i).UnixMilli()}) {
+				return
+			}
+		}
+	})
+}
+
+func (s *seeder) copySpaces(ctx context.Context, tx pgx.Tx) error {
+	return s.copy(ctx, tx, "spaces", []string{"uri", "name", "tenant_uri", "creation_date"}, func(yield func([]any) bool) {
+		for i, sp := range s.spaces {
+			name := capitalize(words[(i*7)%len(words)]) + " Space"
+			if !yield([]any{sp.uri, name, sp.tenantURI, epoch.AddDate(-3, 0, i+1).UnixMilli()}) {
+				return
+			}
+		}
+	})
+}
+
+func (s *seeder) copyTypes(ctx context.Context, tx pgx.Tx) error {
+	return s.copy(ctx, tx, "types", []string{"uri", "space_uri", "name", "creation_date", "author"}, func(yield func([]any) bool) {
+		for _, t := range s.types {
+			if !yield([]any{t.uri, t.spaceURI, t.name, epoch.AddDate(-3, 0, 1).UnixMilli(), s.userURI(0)}) {
+				return
+			}
+		}
+	})
+}
+
+func (s *seeder) copyFields(ctx context.Context, tx pgx.Tx) error {
+	columns := []string{"uri", "name", "field_type", "type_uri", "creation_date", "author", "options", "required"}
+	return s.copy(ctx, tx, "fields", columns, func(yield func([]any) bool) {
+		for _, t := range s.types {
+			for _, f := range t.fields {
+				var options any
+				switch {
+				case f.options != nil:
+					b, _ := json.Marshal(map[string][]string{"options": f.options})
+					options = string(b)
+				case f.target != nil:
+					b, _ := json.Marshal(models.RelationOptions{TargetTypeURI: f.target.uri, Cardinality: models.CardinalitySingle})
+					options = string(b)
+				}
+				row := []any{f.uri, f.name, fieldTypeName(f.fieldType), t.uri, epoch.AddDate(-3, 0, 1).UnixMilli(), s.userURI(0), options, false}
+				if !yield(row) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// copyMemberships makes every user a member of every tenant with read and
+// write access to every space, the first user being an admin of all of them.
+func (s *seeder) copyMemberships(ctx context.Context, tx pgx.Tx) error {
+	err := s.copy(ctx, tx, "user_tenants", []string{"user_uri", "tenant_uri", "role"}, func(yield func([]any) bool) {
+		for _, tenantURI := range s.tenants {
+			for n := range s.spec.Users {
+				role := "member"
+				if n == 0 {
+					role = "admin"
+				}
+				if !yield([]any{s.userURI(n), tenantURI, role}) {
+					return
+				}
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.copy(ctx, tx, "user_spaces", []string{"user_uri", "space_uri"}, func(yield func([]any) bool) {
+		for _, sp := range s.spaces {
+			for n := range s.spec.Users {
+				if !yield([]any{s.userURI(n), sp.uri}) {
+					return
+				}
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.copy(ctx, tx, "user_space_permissions", []string{"user_uri", "space_uri", "verb_uri"}, func(yield func([]any) bool) {
+		for _, sp := range s.spaces {
+			for n := range s.spec.Users {
+				verbs := []string{models.VerbRead, models.VerbWrite}
+				if n == 0 {
+					verbs = append(verbs, models.VerbDelete, models.VerbAdmin)
+				}
+				for _, verb := range verbs {
+					if !yield([]any{s.userURI(n), sp.uri, verb}) {
+						return
+					}
+				}
+			}
+		}
+	})
+}
+
+func (s *seeder) copyElements(ctx context.Context, tx pgx.Tx) error {
+	columns := []string{"uri", "title", "type_uri", "space_uri", "creation_date", "author"}
+	return s.copy(ctx, tx, "elements", columns, func(yield func([]any) bool) {
+		for _, t := range s.types {
+			r := s.rng(streamElements, t.index)
+			for n := range s.spec.ElementsPerType {
+				title := fmt.Sprintf("%s %d: %s", t.name, n+1, phrase(r, 1, 3))
+				author := s.userURI(skewed(r, s.spec.Users))
+				if !yield([]any{s.elementURI(t, n), title, t.uri, t.spaceURI, s.elementDate(n), author}) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// valueColumns is the index of the value column of each field type among
+// value_text, value_number, value_date, value_boolean and value_json.
+var valueColumns = map[model.FieldType]int{
+	model.FieldTypeText:        0,
+	model.FieldTypeNumber:      1,
+	model.FieldTypeDate:        2,
+	model.FieldTypeBoolean:     3,
+	model.FieldTypeSelect:      0,
+	model.FieldTypeMultiSelect: 4,
+	model.FieldTypeURL:         0,
+	model.FieldTypeEmail:       0,
+}
+
+func (s *seeder) copyFieldValues(ctx context.Context, tx pgx.Tx) error {
+	columns := []string{"uri", "element_uri", "field_uri", "value_text", "value_number", "value_date", "value_boolean", "value_json", "creation_date", "updated_date"}
+	return s.copy(ctx, tx, "element_field_values", columns, func(yield func([]any) bool) {
+		for _, t := range s.types {
+			r := s.rng(streamValues, t.index)
+			for n := range s.spec.ElementsPerType {
+				elementURI := s.elementURI(t, n)
+				date := s.elementDate(n)
+				for _, f := range t.fields {
+					col, ok := valueColumns[f.fieldType]
+					if !ok || r.Float64() >= s.spec.FillRate {
+						continue
+					}
+
+					values := make([]any, 5)
+					values[col] = fieldValue(r, f, n)
+					if options, ok := values[col].([]string); ok {
+						b, _ := json.Marshal(options)
+						values[col] = string(b)
+					}
+
+					row := append([]any{"efv:" + elementURI + ":" + f.uri, elementURI, f.uri}, values...)
+					if !yield(append(row, date, date)) {
+						return
+					}
+				}
+			}
+		}
+	})
+}
+
+func (s *seeder) copyLinks(ctx context.Context, tx pgx.Tx) error {
+	columns := []string{"source_element_uri", "field_uri", "target_element_uri", "position", "creation_date"}
+	return s.copy(ctx, tx, "element_links", columns, func(yield func([]any) bool) {
+		if s.spec.ElementsPerType == 0 {
+			return
+		}
+		for _, t := range s.types {
+			r := s.rng(streamLinks, t.index)
+			for n := range s.spec.ElementsPerType {
+				for _, f := range t.fields {
+					if f.target == nil || r.Float64() >= s.spec.FillRate {
+						continue
+					}
+					target := s.elementURI(f.target, skewed(r, s.spec.ElementsPerType))
+					if !yield([]any{s.elementURI(t, n), f.uri, target, 0, s.elementDate(n)}) {
+						return
+					}
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package seed
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/bamdadam/backend/graph/model"
+	"github.com/goccy/go-yaml"
+)
+
+// Spec describes the data to generate. Every tenant gets the same number of
+// spaces, every space the same number of types, and every type the same
+// fields and number of elements.
+type Spec struct {
+	// Seed makes runs with the same spec generate the same data.
+	Seed uint64 `yaml:"seed"`
+	// Prefix is part of every generated URI, so that runs with different
+	// prefixes can share a database.
+	Prefix          string `yaml:"prefix"`
+	Users           int    `yaml:"users"`
+	Tenants         int    `yaml:"tenants"`
+	SpacesPerTenant int    `yaml:"spaces_per_tenant"`
+	TypesPerSpace   int    `yaml:"types_per_space"`
+	ElementsPerType int    `yaml:"elements_per_type"`
+	// Fields is the number of fields of every type, by field type as stored
+	// in the database, e.g. text or multi_select.
+	Fields map[string]int `yaml:"fields"`
+	// SelectOptions is the number of options of select and multi_select
+	// fields.
+	SelectOptions int `yaml:"select_options"`
+	// FillRate is the share of elements having a value for each field.
+	FillRate float64 `yaml:"fill_rate"`
+}
+
+// DefaultSpec returns a spec generating a few thousand elements.
+func DefaultSpec() *Spec {
+	return &Spec{
+		Seed:            1,
+		Prefix:          "seed",
+		Users:           10,
+		Tenants:         1,
+		SpacesPerTenant: 2,
+		TypesPerSpace:   2,
+		ElementsPerType: 1000,
+		Fields: map[string]int{
+			"text":         2,
+			"number":       2,
+			"date":         1,
+			"boolean":      1,
+			"select":       1,
+			"multi_select": 1,
+			"url":          1,
+			"email":        1,
+			"relation":     1,
+		},
+		SelectOptions: 5,
+		FillRate:      0.9,
+	}
+}
+
+// LoadSpec reads a YAML spec, keeping the defaults of the keys it leaves out.
+// A fields key replaces the default fields entirely.
+func LoadSpec(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spec: %w", err)
+	}
+
+	spec := DefaultSpec()
+	spec.Fields = nil
+	if err := yaml.UnmarshalWithOptions(data, spec, yaml.DisallowUnknownField()); err != nil {
+		return nil, fmt.Errorf("failed to parse spec: %w", err)
+	}
+	if spec.Fields == nil {
+		spec.Fields = DefaultSpec().Fields
+	}
+	return spec, nil
+}
+
+// fieldTypes are the field types values are generated for, in the order their
+// fields are created. FORMULA and ROLLUP fields are computed by the API.
+var fieldTypes = []model.FieldType{
+	model.FieldTypeText,
+	model.FieldTypeNumber,
+	model.FieldTypeDate,
+	model.FieldTypeBoolean,
+	model.FieldTypeSelect,
+	model.FieldTypeMultiSelect,
+	model.FieldTypeURL,
+	model.FieldTypeEmail,
+	model.FieldTypeRelation,
+}
+
+var prefixPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// Validate checks the spec, returning the first problem found.
+func (s *Spec) Validate() error {
+	if !prefixPattern.MatchString(s.Prefix) {
+		return fmt.Errorf("prefix %q must be lowercase letters, digits and dashes", s.Prefix)
+	}
+	for name, n := range map[string]int{
+		"users":             s.Users,
+		"tenants":           s.Tenants,
+		"spaces_per_tenant": s.SpacesPerTenant,
+		"types_per_space":   s.TypesPerSpace,
+	} {
+		if n < 1 {
+			return fmt.Errorf("%s must be at least 1", name)
+		}
+	}
+	if s.ElementsPerType < 0 {
+		return fmt.Errorf("elements_per_type can't be negative")
+	}
+	if s.FillRate < 0 || s.FillRate > 1 {
+		return fmt.Errorf("fill_rate must be between 0 and 1")
+	}
+
+	for name, n := range s.Fields {
+		known := false
+		for _, t := range fieldTypes {
+			known = known || name == fieldTypeName(t)
+		}
+		if !known {
+			return fmt.Errorf("values can't be generated for %s fields", name)
+		}
+		if n < 0 {
+			return fmt.Errorf("number of %s fields can't be negative", name)
+		}
+	}
+	if s.Fields["select"]+s.Fields["multi_select"] > 0 && s.SelectOptions < 1 {
+		return fmt.Errorf("select_options must be at least 1")
+	}
+	return nil
+}
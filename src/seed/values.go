@@ -0,0 +1,143 @@
+package seed
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bamdadam/backend/graph/model"
+)
+
+// epoch is the date generated data is spread around, fixed so that runs are
+// deterministic.
+var epoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var words = []string{
+	"alpha", "amber", "anchor", "apex", "atlas", "aurora", "beacon", "birch",
+	"bolt", "bridge", "canyon", "cedar", "cobalt", "comet", "coral", "crest",
+	"delta", "drift", "ember", "falcon", "fern", "forge", "glacier", "granite",
+	"harbor", "horizon", "iris", "jade", "juniper", "keystone", "lagoon",
+	"lantern", "maple", "meadow", "meridian", "nebula", "nova", "oak", "onyx",
+	"orbit", "pine", "prairie", "prism", "quartz", "raven", "ridge", "river",
+	"sable", "sage", "sierra", "slate", "summit", "tide", "timber", "topaz",
+	"tundra", "valley", "vertex", "willow", "zenith",
+}
+
+var firstNames = []string{
+	"Ada", "Ben", "Chloe", "Daniel", "Elena", "Farid", "Grace", "Hugo",
+	"Ines", "Jonas", "Kira", "Liam", "Maya", "Noah", "Olga", "Pablo",
+	"Quinn", "Rosa", "Samir", "Tara", "Umar", "Vera", "Wei", "Yara",
+}
+
+var lastNames = []string{
+	"Andersen", "Baker", "Chen", "Dubois", "Evans", "Fischer", "Garcia",
+	"Hansen", "Ito", "Jensen", "Khan", "Lopez", "Moreau", "Novak", "Okafor",
+	"Petrov", "Rossi", "Silva", "Tanaka", "Weber",
+}
+
+var typeNames = []string{
+	"Project", "Task", "Customer", "Product", "Ticket", "Invoice", "Asset",
+	"Campaign", "Contract", "Employee", "Supplier", "Order",
+}
+
+var fieldNames = map[model.FieldType][]string{
+	model.FieldTypeText:        {"Name", "Summary", "Notes", "Description", "Code"},
+	model.FieldTypeNumber:      {"Amount", "Quantity", "Score", "Price", "Estimate"},
+	model.FieldTypeDate:        {"Due Date", "Start Date", "End Date", "Renewal Date"},
+	model.FieldTypeBoolean:     {"Active", "Approved", "Archived", "Flagged"},
+	model.FieldTypeSelect:      {"Status", "Priority", "Category", "Region"},
+	model.FieldTypeMultiSelect: {"Tags", "Labels", "Channels"},
+	model.FieldTypeURL:         {"Website", "Link", "Documentation"},
+	model.FieldTypeEmail:       {"Contact", "Owner Email", "Billing Email"},
+	model.FieldTypeRelation:    {"Related", "Parent", "Linked"},
+}
+
+// fieldTypeName returns the name of a field type as stored in the database.
+func fieldTypeName(t model.FieldType) string {
+	return strings.ToLower(string(t))
+}
+
+// fieldName names the n-th field of a type, numbering names once they run
+// out.
+func fieldName(t model.FieldType, n int) string {
+	names := fieldNames[t]
+	name := names[n%len(names)]
+	if n >= len(names) {
+		name = fmt.Sprintf("%s %d", name, n/len(names)+1)
+	}
+	return name
+}
+
+func capitalize(s string) string {
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// phrase returns between min and max words, capitalized.
+func phrase(r *rand.Rand, min, max int) string {
+	n := min + r.IntN(max-min+1)
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = words[r.IntN(len(words))]
+	}
+	return capitalize(strings.Join(parts, " "))
+}
+
+// skewed returns an index below n, the lower ones being the most frequent as
+// with the statuses or categories of real data.
+func skewed(r *rand.Rand, n int) int {
+	return int(float64(n) * r.Float64() * r.Float64())
+}
+
+// selectOptions returns the options of a select field.
+func selectOptions(r *rand.Rand, n int) []string {
+	options := make([]string, 0, n)
+	for len(options) < n {
+		option := capitalize(words[r.IntN(len(words))])
+		if slices.Contains(options, option) {
+			option = fmt.Sprintf("%s %d", option, len(options)+1)
+		}
+		options = append(options, option)
+	}
+	return options
+}
+
+// dateBetween returns a random day between from and to days after epoch.
+func dateBetween(r *rand.Rand, from, to int) time.Time {
+	return epoch.AddDate(0, 0, from+r.IntN(to-from+1))
+}
+
+// fieldValue returns the value of a field of the n-th element of a type in the
+// element_field_values column of its type. Dates are Unix milliseconds.
+func fieldValue(r *rand.Rand, f *field, n int) any {
+	switch f.fieldType {
+	case model.FieldTypeText:
+		return phrase(r, 2, 6)
+	case model.FieldTypeNumber:
+		// log-normal, most values in the tens to hundreds with a long tail
+		return math.Round(math.Exp(4+1.2*r.NormFloat64())*100) / 100
+	case model.FieldTypeDate:
+		return dateBetween(r, -730, 365).UnixMilli()
+	case model.FieldTypeBoolean:
+		return r.Float64() < 0.3
+	case model.FieldTypeSelect:
+		return f.options[skewed(r, len(f.options))]
+	case model.FieldTypeMultiSelect:
+		picked := r.Perm(len(f.options))[:1+r.IntN(min(3, len(f.options)))]
+		slices.Sort(picked)
+		values := make([]string, len(picked))
+		for i, p := range picked {
+			values[i] = f.options[p]
+		}
+		return values
+	case model.FieldTypeURL:
+		return fmt.Sprintf("https://%s-%s.example.com/%s/%d", words[r.IntN(len(words))], words[r.IntN(len(words))], words[r.IntN(len(words))], n)
+	case model.FieldTypeEmail:
+		first := firstNames[r.IntN(len(firstNames))]
+		last := lastNames[r.IntN(len(lastNames))]
+		return strings.ToLower(fmt.Sprintf("%s.%s%d@example.com", first, last, n))
+	}
+	return nil
+}
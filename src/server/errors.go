@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"runtime/debug"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/bamdadam/backend/src/apperror"
+	"github.com/bamdadam/backend/src/model"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const internalErrorMessage = "internal server error"
+
+// newErrorPresenter maps domain errors to extensions.code. Errors that are not
+// an apperror are treated as INTERNAL, logged with the request ID and, in
+// production, replaced by a generic message so database details never reach
+// clients.
+func newErrorPresenter(production bool) graphql.ErrorPresenterFunc {
+	return func(ctx context.Context, err error) *gqlerror.Error {
+		gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+
+		// Parse and validation errors raised by gqlgen itself already carry
+		// their own code.
+		var raw *gqlerror.Error
+		if errors.As(err, &raw) && raw.Err == nil {
+			return gqlErr
+		}
+
+		cause := err
+		if raw != nil {
+			cause = raw.Err
+		}
+		requestID, _ := ctx.Value(model.RequestIDKey).(string)
+
+		var appErr *apperror.Error
+		if !errors.As(err, &appErr) || appErr.Code == apperror.CodeInternal {
+			log.Printf("request %s: internal error at %s: %v", requestID, gqlErr.Path, cause)
+			if production {
+				gqlErr.Message = internalErrorMessage
+			}
+			gqlErr.Extensions = map[string]any{
+				"code":      apperror.CodeInternal,
+				"requestId": requestID,
+			}
+			return gqlErr
+		}
+
+		gqlErr.Message = appErr.Message
+		gqlErr.Extensions = map[string]any{
+			"code":      appErr.Code,
+			"requestId": requestID,
+		}
+		for k, v := range appErr.Extensions {
+			gqlErr.Extensions[k] = v
+		}
+		return gqlErr
+	}
+}
+
+// recoverPanic turns a panicking resolver into an INTERNAL error instead of
+// taking the connection down, keeping the stack trace in the logs.
+func recoverPanic(ctx context.Context, p any) error {
+	requestID, _ := ctx.Value(model.RequestIDKey).(string)
+	log.Printf("request %s: panic in resolver: %v\n%s", requestID, p, debug.Stack())
+	return apperror.Internal("panic: %v", p)
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
+	"github.com/bamdadam/backend/src/middleware"
+	models "github.com/bamdadam/backend/src/model"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// exportFilenames replaces the characters of type URIs that don't belong in
+// file names.
+var exportFilenames = strings.NewReplacer(":", "-", "/", "-", `"`, "")
+
+// NewExportHandler serves GET /export, streaming the elements of a type as CSV
+// or NDJSON. Requests are authenticated by the X-User-ID header, or by the
+// token of a link created by the createExportToken mutation which then holds
+// the parameters of the export.
+func NewExportHandler(db *pgxpool.Pool, opts Options) http.Handler {
+	return newExportHandler(newServices(db, opts), opts)
+}
+
+func newExportHandler(svc *services, opts Options) http.Handler {
+	presentError := newErrorPresenter(opts.Production)
+	writeError := func(w http.ResponseWriter, r *http.Request, err error) {
+		writeJSON(w, errorStatus(err), map[string]any{"errors": []*gqlerror.Error{presentError(r.Context(), err)}})
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		userID := r.Header.Get(middleware.AuthHeader)
+		var params models.ExportParams
+
+		if token := query.Get("token"); token != "" {
+			var err error
+			if userID, params, err = svc.export.ParseToken(token); err != nil {
+				writeError(w, r, err)
+				return
+			}
+		} else {
+			params = models.ExportParams{
+				TypeURI: query.Get("typeUri"),
+				Format:  model.ExportFormat(strings.ToUpper(query.Get("format"))),
+			}
+			if params.Format == "" {
+				params.Format = model.ExportFormatCSV
+			}
+			if spaceURI := query.Get("spaceUri"); spaceURI != "" {
+				params.SpaceURI = &spaceURI
+			}
+			if filter := query.Get("filter"); filter != "" {
+				if err := json.Unmarshal([]byte(filter), &params.Filter); err != nil {
+					writeError(w, r, apperror.Validation("invalid filter: %v", err))
+					return
+				}
+			}
+			if params.TypeURI == "" {
+				writeError(w, r, apperror.Validation("typeUri is required"))
+				return
+			}
+		}
+
+		ctx, err := middleware.Authenticate(r.Context(), userID)
+		if err != nil {
+			writeError(w, r, apperror.Unauthenticated("%v", err))
+			return
+		}
+
+		contentType, extension := "text/csv; charset=utf-8", ".csv"
+		if params.Format == model.ExportFormatNdjson {
+			contentType, extension = "application/x-ndjson", ".ndjson"
+		}
+		out := &exportResponse{
+			ResponseWriter: w,
+			contentType:    contentType,
+			filename:       exportFilenames.Replace(params.TypeURI) + extension,
+		}
+
+		if err := svc.export.Export(ctx, params, out); err != nil {
+			if !out.started {
+				writeError(w, r, err)
+				return
+			}
+			// the status has been sent, all that is left is to cut the
+			// download short
+			requestID, _ := ctx.Value(models.RequestIDKey).(string)
+			log.Printf("request %s: export failed after the response started: %v", requestID, err)
+			panic(http.ErrAbortHandler)
+		}
+		if !out.started {
+			out.start()
+		}
+	})
+}
+
+// exportResponse sends the headers of a download with the first bytes of the
+// export, so that errors occurring before can still be reported with their
+// status.
+type exportResponse struct {
+	http.ResponseWriter
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (w *exportResponse) start() {
+	w.started = true
+	w.Header().Set("Content-Type", w.contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+w.filename+`"`)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (w *exportResponse) Write(b []byte) (int, error) {
+	if !w.started {
+		w.start()
+	}
+	return w.ResponseWriter.Write(b)
+}
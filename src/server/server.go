@@ -6,8 +6,13 @@ import (
 	"errors"
 	"log"
 	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/99designs/gqlgen/graphql"
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/handler/extension"
 	"github.com/99designs/gqlgen/graphql/handler/lru"
@@ -15,26 +20,151 @@ import (
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/bamdadam/backend/graph"
 	"github.com/bamdadam/backend/src/middleware"
-	"github.com/bamdadam/backend/src/model"
 	"github.com/bamdadam/backend/src/pubsub"
 	"github.com/bamdadam/backend/src/repository"
 	"github.com/bamdadam/backend/src/service"
+	"github.com/gorilla/websocket"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/vektah/gqlparser/v2/ast"
 )
 
-func Run(ctx context.Context, db *pgxpool.Pool, addr string) error {
-	graphqlHandler := NewGraphQLHandler(db)
+// MaxUploadSize is the maximum size of files uploaded to mutations such as
+// importElements.
+const MaxUploadSize = 32 << 20
+
+// defaultShutdownTimeout is how long in-flight requests and running
+// background jobs are given to finish on shutdown by default.
+const defaultShutdownTimeout = 30 * time.Second
+
+// defaultQueryCacheSize is the number of parsed queries cached by default.
+const defaultQueryCacheSize = 100
+
+// defaultKeepAliveInterval is how often subscriptions are pinged by default.
+const defaultKeepAliveInterval = 15 * time.Second
+
+// defaultJobWorkers is the number of background jobs run at once by default.
+const defaultJobWorkers = 2
+
+// defaultWebhookWorkers is the number of webhook deliveries sent at once by
+// default.
+const defaultWebhookWorkers = 2
+
+// Options configures the API server.
+type Options struct {
+	Addr string
+	// AuditRetention is how long audit log entries are kept, 0 keeps them
+	// forever.
+	AuditRetention time.Duration
+	// Production hides the details of internal errors from clients.
+	Production bool
+	// ExportTokenSecret signs the download links of exports. Without one,
+	// links are only valid on the replica that created them.
+	ExportTokenSecret string
+	// JobWorkers is the number of background jobs run at once, 0 keeps the
+	// default.
+	JobWorkers int
+	// WebhookWorkers is the number of webhook deliveries sent at once, 0
+	// keeps the default.
+	WebhookWorkers int
+	// WebhookAllowPrivateNetworks lets webhooks call loopback, private and
+	// link-local addresses, which are refused by default.
+	WebhookAllowPrivateNetworks bool
+	// MaxUpdateWhereRows caps the number of elements updateElementsWhere
+	// updates at once, 0 keeps the default.
+	MaxUpdateWhereRows int
+	// ShutdownTimeout is how long in-flight requests and running background
+	// jobs are given to finish on shutdown, 0 keeps the default.
+	ShutdownTimeout time.Duration
+	// ReadHeaderTimeout and IdleTimeout are those of http.Server, 0 disables
+	// them.
+	ReadHeaderTimeout time.Duration
+	IdleTimeout       time.Duration
+	// QueryCacheSize is the number of parsed queries cached, 0 keeps the
+	// default.
+	QueryCacheSize int
+	// KeepAliveInterval is how often subscriptions are pinged, 0 keeps the
+	// default.
+	KeepAliveInterval time.Duration
+	// DisablePlayground stops serving the GraphQL playground and
+	// DisableIntrospection rejects introspection queries.
+	DisablePlayground    bool
+	DisableIntrospection bool
+	// DefaultUserID runs requests without X-User-ID as this user, for local
+	// development.
+	DefaultUserID string
+	// CORSAllowedOrigins lets browsers on these origins call the API, "*"
+	// allowing any. CORSMaxAge is how long preflight responses are cached.
+	CORSAllowedOrigins []string
+	CORSMaxAge         time.Duration
+	// TrustedProxies are the reverse proxies the client IP is read from
+	// X-Forwarded-For of, it is the peer address of other requests.
+	TrustedProxies []netip.Prefix
+	// PaginationLimit is the page size of lists when clients don't ask for
+	// one and MaxPaginationLimit the largest they can ask for, 0 keeps the
+	// defaults.
+	PaginationLimit    int32
+	MaxPaginationLimit int32
+}
+
+func (opts Options) shutdownTimeout() time.Duration {
+	if opts.ShutdownTimeout > 0 {
+		return opts.ShutdownTimeout
+	}
+	return defaultShutdownTimeout
+}
+
+func (opts Options) keepAliveInterval() time.Duration {
+	if opts.KeepAliveInterval > 0 {
+		return opts.KeepAliveInterval
+	}
+	return defaultKeepAliveInterval
+}
+
+// handler wraps the handler of an API endpoint with the request info, CORS
+// and authentication middlewares.
+func (opts Options) handler(next http.Handler, auth bool) http.Handler {
+	if auth {
+		next = middleware.Auth(next)
+		if opts.DefaultUserID != "" {
+			next = middleware.DefaultUser(opts.DefaultUserID, next)
+		}
+	}
+	if len(opts.CORSAllowedOrigins) > 0 {
+		next = middleware.CORS(opts.CORSAllowedOrigins, opts.CORSMaxAge, next)
+	}
+	return middleware.RequestInfo(opts.TrustedProxies, next)
+}
+
+func Run(ctx context.Context, db *pgxpool.Pool, opts Options) error {
+	svc := newServices(db, opts)
+	graphqlHandler := newGraphQLHandler(svc, opts)
+	typeSchemaHandler := newTypeSchemaHandler(svc, opts)
 	healthHandler := newHealthHandler(db)
 
-	http.Handle("/", playground.Handler("GraphQL Playground", "/graphql"))
-	http.Handle("/graphql", middleware.Auth(graphqlHandler))
+	if !opts.DisablePlayground {
+		http.Handle("/", playground.Handler("GraphQL Playground", "/graphql"))
+	}
+	http.Handle("/graphql", opts.handler(graphqlHandler, true))
+	http.Handle("/tenants/{tenantUri}/graphql", opts.handler(typeSchemaHandler, true))
+	http.Handle("/export", opts.handler(newExportHandler(svc, opts), false))
 	http.Handle("/health", healthHandler)
 
-	log.Printf("GraphQL playground: http://localhost%s/", addr)
+	go service.RunAuditRetention(ctx, repository.NewAuditLogRepository(db), opts.AuditRetention, time.Hour)
+
+	workersDone := make(chan struct{})
+	go func() {
+		defer close(workersDone)
+		runWorkers(ctx, svc, opts)
+	}()
+
+	if !opts.DisablePlayground {
+		log.Printf("GraphQL playground: http://localhost%s/", opts.Addr)
+	}
 
 	server := &http.Server{
-		Addr: addr,
+		Addr:              opts.Addr,
+		ReadHeaderTimeout: opts.ReadHeaderTimeout,
+		IdleTimeout:       opts.IdleTimeout,
 	}
 
 	go func() {
@@ -46,13 +176,65 @@ func Run(ctx context.Context, db *pgxpool.Pool, addr string) error {
 	<-ctx.Done()
 	log.Println("Shutting down server...")
 
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), opts.shutdownTimeout())
 	defer cancel()
 
-	return server.Shutdown(shutdownCtx)
+	err := server.Shutdown(shutdownCtx)
+
+	// workers stopped claiming work along with ctx, wait for the running jobs
+	<-workersDone
+	log.Println("Background workers drained")
+
+	return err
+}
+
+// RunWorkers runs the background jobs, the outbox relay and the webhook
+// deliveries until ctx is cancelled, then drains them. Run already does so,
+// this is for running them apart from the API server.
+func RunWorkers(ctx context.Context, db *pgxpool.Pool, opts Options) {
+	runWorkers(ctx, newServices(db, opts), opts)
+}
+
+func runWorkers(ctx context.Context, svc *services, opts Options) {
+	jobWorkers := opts.JobWorkers
+	if jobWorkers <= 0 {
+		jobWorkers = defaultJobWorkers
+	}
+	webhookWorkers := opts.WebhookWorkers
+	if webhookWorkers <= 0 {
+		webhookWorkers = defaultWebhookWorkers
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		svc.jobs.Run(ctx, jobWorkers, opts.shutdownTimeout())
+	}()
+	go func() {
+		defer wg.Done()
+		svc.outbox.Run(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		svc.webhooks.Run(ctx, webhookWorkers)
+	}()
+	wg.Wait()
+}
+
+// services holds the services shared by the HTTP handlers of the API.
+type services struct {
+	element    *service.ElementService
+	audit      *service.AuditService
+	export     *service.ExportService
+	jobs       *service.JobService
+	webhooks   *service.WebhookService
+	outbox     *service.OutboxService
+	typeSchema *service.TypeSchemaService
+	elementPub *pubsub.ElementPubSub
 }
 
-func NewGraphQLHandler(db *pgxpool.Pool) http.Handler {
+func newServices(db *pgxpool.Pool, opts Options) *services {
 	userRepo := repository.NewUserRepository(db)
 	tenantRepo := repository.NewTenantRepository(db)
 	spaceRepo := repository.NewSpaceRepository(db, tenantRepo)
@@ -61,45 +243,123 @@ func NewGraphQLHandler(db *pgxpool.Pool) http.Handler {
 	fieldValueRepo := repository.NewElementFieldValueRepository(db, fieldRepo)
 	userSpaceRepo := repository.NewUserSpacesRepository(db)
 	elementRepo := repository.NewElementRepository(db)
+	linkRepo := repository.NewElementLinkRepository(db)
+	rollupRepo := repository.NewRollupRepository(db)
+	sequenceRepo := repository.NewFieldSequenceRepository(db)
+	revisionRepo := repository.NewElementRevisionRepository(db)
+	auditRepo := repository.NewAuditLogRepository(db)
+	userTenantRepo := repository.NewUserTenantsRepository(db)
 
 	elementPubSub := pubsub.NewElementPubSub()
+	limits := service.Limits{
+		PageSize:           opts.PaginationLimit,
+		MaxPageSize:        opts.MaxPaginationLimit,
+		MaxUpdateWhereRows: opts.MaxUpdateWhereRows,
+	}
 
 	userService := service.NewUserService(db, userRepo, userSpaceRepo)
-	elementService := service.NewElementService(db, userService, elementRepo, typeRepo, spaceRepo, fieldValueRepo, elementPubSub)
+	auditService := service.NewAuditService(userService, auditRepo, userTenantRepo, limits)
+	webhookService := service.NewWebhookService(userService, repository.NewWebhookRepository(db), typeRepo, opts.WebhookAllowPrivateNetworks, limits)
+	outboxService := service.NewOutboxService(db, repository.NewOutboxRepository(db), webhookService, elementPubSub)
+
+	elementService := service.NewElementService(db, userService, elementRepo, typeRepo, spaceRepo, fieldRepo, fieldValueRepo, linkRepo, rollupRepo, sequenceRepo, revisionRepo, auditService, outboxService, limits)
+
+	jobService := service.NewJobService(userService, repository.NewJobRepository(db))
+	elementService.RegisterJobs(jobService)
+
+	return &services{
+		element:    elementService,
+		audit:      auditService,
+		export:     service.NewExportService(elementService, []byte(opts.ExportTokenSecret)),
+		jobs:       jobService,
+		webhooks:   webhookService,
+		outbox:     outboxService,
+		typeSchema: service.NewTypeSchemaService(userTenantRepo, typeRepo),
+		elementPub: elementPubSub,
+	}
+}
+
+func NewGraphQLHandler(db *pgxpool.Pool, opts Options) http.Handler {
+	return newGraphQLHandler(newServices(db, opts), opts)
+}
 
+func newGraphQLHandler(svc *services, opts Options) http.Handler {
 	resolver := &graph.Resolver{
-		ElementService: elementService,
-		ElementPubSub:  elementPubSub,
+		ElementService: svc.element,
+		AuditService:   svc.audit,
+		ExportService:  svc.export,
+		JobService:     svc.jobs,
+		WebhookService: svc.webhooks,
+		ElementPubSub:  svc.elementPub,
 	}
 
 	srv := handler.New(graph.NewExecutableSchema(
 		graph.Config{Resolvers: resolver}),
 	)
 	srv.AddTransport(transport.Options{})
+	// SSE has to be registered ahead of POST, both accept JSON POST requests
+	// and the first transport that supports a request wins.
+	srv.AddTransport(transport.SSE{
+		KeepAlivePingInterval: opts.keepAliveInterval(),
+	})
 	srv.AddTransport(transport.POST{})
+	srv.AddTransport(transport.MultipartForm{
+		MaxUploadSize: MaxUploadSize,
+		MaxMemory:     MaxUploadSize,
+	})
 	srv.AddTransport(transport.Websocket{
-		KeepAlivePingInterval: 15 * time.Second,
-		InitFunc: func(ctx context.Context, initPayload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
-			userID := initPayload.GetString(middleware.AuthHeader)
-			if userID == "" {
-				return ctx, nil, errors.New("missing X-User-ID in websocket connection_init payload")
-			}
-
-			ctx = context.WithValue(ctx, model.UserIDKey, userID)
-			return ctx, nil, nil
-		},
+		Upgrader:              websocketUpgrader(opts),
+		KeepAlivePingInterval: opts.keepAliveInterval(),
+		PongOnlyInterval:      opts.keepAliveInterval(),
+		InitFunc:              middleware.WebsocketInit,
 		ErrorFunc: func(ctx context.Context, err error) {
 			log.Printf("WebSocket Error: %v", err)
 		},
 	})
-	srv.SetQueryCache(lru.New[*ast.QueryDocument](100))
-	srv.Use(extension.Introspection{})
+	srv.SetErrorPresenter(newErrorPresenter(opts.Production))
+	srv.SetRecoverFunc(recoverPanic)
+	queryCacheSize := opts.QueryCacheSize
+	if queryCacheSize <= 0 {
+		queryCacheSize = defaultQueryCacheSize
+	}
+	srv.SetQueryCache(lru.New[*ast.QueryDocument](queryCacheSize))
+	if !opts.DisableIntrospection {
+		srv.Use(extension.Introspection{})
+	}
+	// Formulas are compiled once per query or mutation. Subscriptions outlive
+	// changes of types, their events compile them each time.
+	srv.AroundOperations(func(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+		if op := graphql.GetOperationContext(ctx).Operation; op != nil && op.Operation != ast.Subscription {
+			ctx = service.WithFormulaCache(ctx)
+		}
+		return next(ctx)
+	})
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		srv.ServeHTTP(w, r)
 	})
 }
 
+// websocketUpgrader accepts the upgrades of same-origin pages, as the default
+// upgrader does, and of the CORS allowed origins.
+func websocketUpgrader(opts Options) websocket.Upgrader {
+	if len(opts.CORSAllowedOrigins) == 0 {
+		return websocket.Upgrader{}
+	}
+	return websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				return true
+			}
+			if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+				return true
+			}
+			return middleware.OriginAllowed(opts.CORSAllowedOrigins, origin)
+		},
+	}
+}
+
 func newHealthHandler(db *pgxpool.Pool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if err := db.Ping(r.Context()); err != nil {
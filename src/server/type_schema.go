@@ -0,0 +1,86 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/bamdadam/backend/src/apperror"
+	"github.com/bamdadam/backend/src/typeschema"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// NewTypeSchemaHandler serves the GraphQL schema generated from the types of
+// the tenant in the {tenantUri} path segment. GET returns the schema SDL, POST
+// executes a query against it.
+func NewTypeSchemaHandler(db *pgxpool.Pool, opts Options) http.Handler {
+	return newTypeSchemaHandler(newServices(db, opts), opts)
+}
+
+func newTypeSchemaHandler(svc *services, opts Options) http.Handler {
+	presentError := newErrorPresenter(opts.Production)
+
+	present := func(r *http.Request, errs []error) []*gqlerror.Error {
+		presented := make([]*gqlerror.Error, len(errs))
+		for i, err := range errs {
+			presented[i] = presentError(r.Context(), err)
+		}
+		return presented
+	}
+	writeErrors := func(w http.ResponseWriter, r *http.Request, status int, errs ...error) {
+		writeJSON(w, status, map[string]any{"errors": present(r, errs)})
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		schema, err := svc.typeSchema.Get(r.Context(), r.PathValue("tenantUri"))
+		if err != nil {
+			writeErrors(w, r, errorStatus(err), err)
+			return
+		}
+
+		if r.Method == http.MethodGet {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Write([]byte(schema.SDL))
+			return
+		}
+
+		var req typeschema.Request
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber()
+		if err := decoder.Decode(&req); err != nil {
+			writeErrors(w, r, http.StatusBadRequest, apperror.Validation("invalid request body: %w", err))
+			return
+		}
+
+		resp := schema.Execute(r.Context(), svc.element, req)
+
+		body := map[string]any{"data": resp.Data}
+		if len(resp.Errors) > 0 {
+			body["errors"] = present(r, resp.Errors)
+		}
+		writeJSON(w, http.StatusOK, body)
+	})
+}
+
+// errorStatus maps an error to the HTTP status of requests that fail before a
+// query is executed.
+func errorStatus(err error) int {
+	switch apperror.CodeOf(err) {
+	case apperror.CodeUnauthenticated:
+		return http.StatusUnauthorized
+	case apperror.CodeForbidden:
+		return http.StatusForbidden
+	case apperror.CodeNotFound:
+		return http.StatusNotFound
+	case apperror.CodeValidation:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,310 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
+	models "github.com/bamdadam/backend/src/model"
+	"github.com/bamdadam/backend/src/repository"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AdminService provisions tenants, spaces, users and their permissions. It is
+// meant for operators and makes no access checks. Changes to tenants and
+// permissions are audited on behalf of actor, the operator running it.
+type AdminService struct {
+	db         *pgxpool.Pool
+	tenant     repository.TenantRepository
+	space      repository.SpaceRepository
+	user       repository.UserRepository
+	userTenant repository.UserTenantsRepository
+	userSpace  repository.UserSpacesRepository
+	element    repository.ElementRepository
+	audit      repository.AuditLogRepository
+	actor      string
+}
+
+func NewAdminService(db *pgxpool.Pool, tenant repository.TenantRepository, space repository.SpaceRepository, user repository.UserRepository, userTenant repository.UserTenantsRepository, userSpace repository.UserSpacesRepository, element repository.ElementRepository, audit repository.AuditLogRepository, actor string) *AdminService {
+	return &AdminService{
+		db:         db,
+		tenant:     tenant,
+		space:      space,
+		user:       user,
+		userTenant: userTenant,
+		userSpace:  userSpace,
+		element:    element,
+		audit:      audit,
+		actor:      actor,
+	}
+}
+
+// CreateTenant creates an active tenant, generating its URI if uri is empty.
+func (s *AdminService) CreateTenant(ctx context.Context, uri, name string) (*model.Tenant, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, apperror.Validation("tenant name is required")
+	}
+	if uri == "" {
+		uri = newURI("tenant")
+	}
+
+	tenant := &model.Tenant{
+		URI:          uri,
+		Name:         name,
+		Status:       model.TenantStatusActive,
+		CreationDate: time.UnixMilli(time.Now().UnixMilli()),
+	}
+	err := repository.RunInTx(ctx, s.db, func(ctx context.Context) error {
+		if err := s.tenant.Create(ctx, tenant); err != nil {
+			return err
+		}
+		return s.record(ctx, AuditActionTenantCreate, uri, uri, map[string]any{"name": name})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+// DeactivateTenant makes the spaces of a tenant inaccessible to its users,
+// keeping its data and grants.
+func (s *AdminService) DeactivateTenant(ctx context.Context, uri string) error {
+	return repository.RunInTx(ctx, s.db, func(ctx context.Context) error {
+		if err := s.tenant.SetStatus(ctx, uri, model.TenantStatusInactive); err != nil {
+			return err
+		}
+		return s.record(ctx, AuditActionTenantDeactivate, uri, uri, nil)
+	})
+}
+
+// CreateSpace creates a space in a tenant, generating its URI if uri is empty.
+func (s *AdminService) CreateSpace(ctx context.Context, uri, name, tenantURI string) (*model.Space, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, apperror.Validation("space name is required")
+	}
+	if uri == "" {
+		uri = newURI("space")
+	}
+
+	tenant, err := s.tenant.GetByURI(ctx, tenantURI)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UnixMilli()
+	if err := s.space.Create(ctx, uri, name, tenant.URI, now); err != nil {
+		return nil, err
+	}
+	return &model.Space{URI: uri, Name: name, Tenant: tenant, CreationDate: time.UnixMilli(now)}, nil
+}
+
+// CreateUser creates a user, generating its URI if uri is empty.
+func (s *AdminService) CreateUser(ctx context.Context, uri, email, displayName string) (*model.User, error) {
+	if _, err := mail.ParseAddress(email); err != nil {
+		return nil, apperror.Validation("invalid email %q", email)
+	}
+	if strings.TrimSpace(displayName) == "" {
+		return nil, apperror.Validation("user display name is required")
+	}
+	if uri == "" {
+		uri = newURI("user")
+	}
+
+	user := &model.User{URI: uri, Email: email, DisplayName: displayName}
+	if err := s.user.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ListUsers lists every user, or the members of a tenant if tenantURI is set.
+func (s *AdminService) ListUsers(ctx context.Context, tenantURI string) ([]*model.User, error) {
+	if tenantURI != "" {
+		if _, err := s.tenant.GetByURI(ctx, tenantURI); err != nil {
+			return nil, err
+		}
+	}
+	return s.user.List(ctx, tenantURI)
+}
+
+// Grant gives a user access to a space along with verbs, making the user a
+// member of the tenant of the space if needed. Verbs may be given without
+// their "verb:" prefix.
+func (s *AdminService) Grant(ctx context.Context, userURI, spaceURI string, verbs []string) error {
+	return repository.RunInTx(ctx, s.db, func(ctx context.Context) error {
+		if _, err := s.user.GetByURI(ctx, userURI); err != nil {
+			return err
+		}
+		space, err := s.space.GetByURI(ctx, spaceURI)
+		if err != nil {
+			return err
+		}
+
+		if err := s.userTenant.AddMember(ctx, userURI, space.Tenant.URI, repository.TenantRoleMember); err != nil {
+			return err
+		}
+		if err := s.userSpace.Grant(ctx, userURI, spaceURI, verbURIs(verbs)); err != nil {
+			return err
+		}
+		return s.record(ctx, AuditActionSpaceGrant, userURI, space.Tenant.URI,
+			map[string]any{"spaceUri": spaceURI, "verbs": verbURIs(verbs)})
+	})
+}
+
+// Revoke takes verbs on a space away from a user, or the access to the space
+// along with every verb if verbs is empty. Tenant memberships are kept.
+func (s *AdminService) Revoke(ctx context.Context, userURI, spaceURI string, verbs []string) error {
+	return repository.RunInTx(ctx, s.db, func(ctx context.Context) error {
+		if _, err := s.user.GetByURI(ctx, userURI); err != nil {
+			return err
+		}
+		space, err := s.space.GetByURI(ctx, spaceURI)
+		if err != nil {
+			return err
+		}
+		if err := s.userSpace.Revoke(ctx, userURI, spaceURI, verbURIs(verbs)); err != nil {
+			return err
+		}
+		return s.record(ctx, AuditActionSpaceRevoke, userURI, space.Tenant.URI,
+			map[string]any{"spaceUri": spaceURI, "verbs": verbURIs(verbs)})
+	})
+}
+
+// record appends an entry to the audit log on behalf of the operator, within
+// the transaction carried by ctx. Entries of operators have no request or
+// client IP.
+func (s *AdminService) record(ctx context.Context, action, targetURI, tenantURI string, diff any) error {
+	return s.audit.Create(ctx, &models.AuditEntry{
+		UserURI:      s.actor,
+		Action:       action,
+		TargetURI:    targetURI,
+		TenantURI:    tenantURI,
+		Diff:         diff,
+		CreationDate: time.Now().UnixMilli(),
+	})
+}
+
+// Access is the effective access of a user to an element, as enforced by the
+// API.
+type Access struct {
+	User    *model.User
+	Element string
+	Space   *model.Space
+	// TenantRole is empty if the user is not a member of the tenant.
+	TenantRole  string
+	SpaceMember bool
+	Verbs       []string
+	Checks      []*AccessCheck
+}
+
+// AccessCheck tells whether the user may do an action, and why.
+type AccessCheck struct {
+	Action  string
+	Allowed bool
+	Reason  string
+}
+
+// ExplainAccess returns what a user may do with an element, and why.
+func (s *AdminService) ExplainAccess(ctx context.Context, userURI, elementURI string) (*Access, error) {
+	user, err := s.user.GetByURI(ctx, userURI)
+	if err != nil {
+		return nil, err
+	}
+	spaceURI, err := s.element.GetSpaceURI(ctx, elementURI)
+	if err != nil {
+		return nil, err
+	}
+	space, err := s.space.GetByURI(ctx, spaceURI)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := s.userTenant.GetRole(ctx, userURI, space.Tenant.URI)
+	if err != nil {
+		return nil, err
+	}
+	spaces, err := s.userSpace.GetByUser(ctx, userURI)
+	if err != nil {
+		return nil, err
+	}
+	verbs, err := s.userSpace.GetVerbs(ctx, userURI, spaceURI)
+	if err != nil {
+		return nil, err
+	}
+
+	access := &Access{
+		User:       user,
+		Element:    elementURI,
+		Space:      space,
+		TenantRole: role,
+		Verbs:      verbs,
+	}
+	// spaces of inactive tenants are left out of those of the user
+	access.SpaceMember = slices.Contains(spaces, spaceURI)
+	active := space.Tenant.Status == model.TenantStatusActive
+
+	check := func(action string, allowed bool, reason string) {
+		access.Checks = append(access.Checks, &AccessCheck{Action: action, Allowed: allowed, Reason: reason})
+	}
+
+	switch {
+	case !active:
+		check("read the element", false, fmt.Sprintf("tenant %s is inactive", space.Tenant.URI))
+	case access.SpaceMember:
+		check("read the element", true, fmt.Sprintf("granted access to space %s", spaceURI))
+	default:
+		check("read the element", false, fmt.Sprintf("not granted access to space %s", spaceURI))
+	}
+
+	writer := slices.Contains(verbs, models.VerbWrite) || slices.Contains(verbs, models.VerbAdmin)
+	switch {
+	case !active:
+		check("write elements, jobs and webhooks of the space", false, fmt.Sprintf("tenant %s is inactive", space.Tenant.URI))
+	case !access.SpaceMember:
+		check("write elements, jobs and webhooks of the space", false, fmt.Sprintf("not granted access to space %s", spaceURI))
+	case writer:
+		check("write elements, jobs and webhooks of the space", true, "granted write or admin on the space")
+	default:
+		check("write elements, jobs and webhooks of the space", false, "not granted write or admin on the space")
+	}
+
+	if role != "" {
+		check("typed schema of the tenant", true, fmt.Sprintf("%s of tenant %s", role, space.Tenant.URI))
+	} else {
+		check("typed schema of the tenant", false, fmt.Sprintf("not a member of tenant %s", space.Tenant.URI))
+	}
+	if role == repository.TenantRoleAdmin {
+		check("audit log of the tenant", true, fmt.Sprintf("admin of tenant %s", space.Tenant.URI))
+	} else {
+		check("audit log of the tenant", false, fmt.Sprintf("not an admin of tenant %s", space.Tenant.URI))
+	}
+
+	return access, nil
+}
+
+// verbURIs adds the "verb:" prefix to the verbs missing it.
+func verbURIs(verbs []string) []string {
+	uris := make([]string, len(verbs))
+	for i, verb := range verbs {
+		if !strings.HasPrefix(verb, "verb:") {
+			verb = "verb:" + verb
+		}
+		uris[i] = verb
+	}
+	return uris
+}
+
+// newURI generates the URI of a resource of the given kind created without
+// one.
+func newURI(kind string) string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return kind + ":" + hex.EncodeToString(b)
+}
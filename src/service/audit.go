@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
+	models "github.com/bamdadam/backend/src/model"
+	"github.com/bamdadam/backend/src/repository"
+)
+
+const (
+	AuditActionElementCreate      = "element.create"
+	AuditActionElementUpdate      = "element.update"
+	AuditActionElementDelete      = "element.delete"
+	AuditActionElementUpdateTitle = "element.update_title"
+	AuditActionElementRevert      = "element.revert"
+	AuditActionElementSetLinks    = "element.set_links"
+
+	AuditActionTenantCreate     = "tenant.create"
+	AuditActionTenantDeactivate = "tenant.deactivate"
+	AuditActionSpaceGrant       = "space.grant"
+	AuditActionSpaceRevoke      = "space.revoke"
+)
+
+type AuditService struct {
+	*UserService
+
+	audit      repository.AuditLogRepository
+	userTenant repository.UserTenantsRepository
+	limits     Limits
+}
+
+func NewAuditService(us *UserService, auditRepo repository.AuditLogRepository,
+	userTenantRepo repository.UserTenantsRepository, limits Limits) *AuditService {
+	return &AuditService{
+		UserService: us,
+		audit:       auditRepo,
+		userTenant:  userTenantRepo,
+		limits:      limits,
+	}
+}
+
+// Record appends an entry to the audit log for an action the requesting user
+// performed on target. Called with a transactional context, the entry is only
+// kept if the transaction commits.
+func (s *AuditService) Record(ctx context.Context, action, targetURI, tenantURI string, diff any) error {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	entry := &models.AuditEntry{
+		UserURI:      userID,
+		Action:       action,
+		TargetURI:    targetURI,
+		TenantURI:    tenantURI,
+		Diff:         diff,
+		CreationDate: time.Now().UnixMilli(),
+	}
+	if requestID, ok := ctx.Value(models.RequestIDKey).(string); ok {
+		entry.RequestID = &requestID
+	}
+	if clientIP, ok := ctx.Value(models.ClientIPKey).(string); ok {
+		entry.ClientIP = &clientIP
+	}
+
+	return s.audit.Create(ctx, entry)
+}
+
+// List returns the audit log of a tenant. Only admins of the tenant may read it.
+func (s *AuditService) List(ctx context.Context, params models.AuditLogParams) (*model.AuditLogConnection, error) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit log: %w", err)
+	}
+
+	role, err := s.userTenant.GetRole(ctx, userID, params.TenantURI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit log: %w", err)
+	}
+	if role != repository.TenantRoleAdmin {
+		return nil, apperror.Forbidden("user %s is not an admin of tenant %s", userID, params.TenantURI)
+	}
+
+	params.Limit = s.limits.pageSize(params.Limit)
+
+	entries, err := s.audit.List(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit log: %w", err)
+	}
+
+	hasNextPage := len(entries) > int(params.Limit)
+	if hasNextPage {
+		entries = entries[:params.Limit]
+	}
+
+	edges := make([]*model.AuditLogEdge, len(entries))
+	for i, entry := range entries {
+		edges[i] = &model.AuditLogEdge{Cursor: entry.ID, Node: entry}
+	}
+
+	pageInfo := &model.PageInfo{HasNextPage: hasNextPage}
+	if len(edges) > 0 {
+		pageInfo.StartCursor = &edges[0].Cursor
+		pageInfo.EndCursor = &edges[len(edges)-1].Cursor
+	}
+
+	return &model.AuditLogConnection{
+		Edges:      edges,
+		PageInfo:   pageInfo,
+		TotalCount: int32(len(edges)),
+	}, nil
+}
+
+// RunAuditRetention deletes audit entries older than retention once every
+// interval until ctx is cancelled. A zero retention keeps entries forever.
+func RunAuditRetention(ctx context.Context, auditRepo repository.AuditLogRepository, retention, interval time.Duration) {
+	if retention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		cutoff := time.Now().Add(-retention).UnixMilli()
+		deleted, err := auditRepo.DeleteBefore(ctx, cutoff)
+		if err != nil {
+			log.Printf("Audit log retention failed: %v", err)
+		} else if deleted > 0 {
+			log.Printf("Audit log retention purged %d entries", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// elementAuditDiff trims an element change down to what is worth keeping in
+// the audit log, referring to fields by URI rather than embedding them.
+func elementAuditDiff(change *model.ElementChange) map[string]any {
+	fieldValues := make([]map[string]any, len(change.FieldValues))
+	for i, fv := range change.FieldValues {
+		fieldValues[i] = map[string]any{
+			"uri":    fv.URI,
+			"field":  fv.Field.URI,
+			"before": fv.Before,
+			"after":  fv.After,
+		}
+	}
+
+	links := make([]map[string]any, len(change.Links))
+	for i, l := range change.Links {
+		links[i] = map[string]any{
+			"field":  l.Field.URI,
+			"before": l.Before,
+			"after":  l.After,
+		}
+	}
+
+	diff := map[string]any{"fieldValues": fieldValues, "links": links}
+	if change.Title != nil {
+		diff["title"] = map[string]any{"before": change.Title.Before, "after": change.Title.After}
+	}
+	return diff
+}
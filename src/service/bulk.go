@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
+	models "github.com/bamdadam/backend/src/model"
+	"github.com/bamdadam/backend/src/repository"
+)
+
+// MaxBulkSize is the maximum number of elements a bulk mutation accepts.
+var MaxBulkSize = 1000
+
+// codeAborted is reported for the elements of an atomic batch that were
+// rolled back because another element failed.
+const codeAborted = "ABORTED"
+
+// BulkUpdate updates several elements, in a single transaction if atomic is
+// set and each in its own otherwise. Elements in spaces the user can't write
+// to are reported as FORBIDDEN. Subscribers are notified once the
+// changes have committed.
+func (s *ElementService) BulkUpdate(ctx context.Context, inputs []*model.UpdateElementInput, atomic bool) ([]*model.BulkElementResult, error) {
+	uris := make([]string, len(inputs))
+	for i, input := range inputs {
+		uris[i] = input.URI
+	}
+
+	return s.bulk(ctx, uris, atomic, func(ctx context.Context, i int) (*model.ElementChange, error) {
+		input := inputs[i]
+		return s.mutateInTx(ctx, input.URI, AuditActionElementUpdate, input.ExpectedVersion, s.updateElement(*input))
+	})
+}
+
+// BulkDelete deletes several elements, in a single transaction if atomic is
+// set and each in its own otherwise. Elements in spaces the user can't write
+// to are reported as FORBIDDEN, elements changed since their expected version
+// as CONFLICT.
+func (s *ElementService) BulkDelete(ctx context.Context, inputs []*model.DeleteElementInput, atomic bool) ([]*model.BulkElementResult, error) {
+	uris := make([]string, len(inputs))
+	for i, input := range inputs {
+		uris[i] = input.URI
+	}
+
+	return s.bulk(ctx, uris, atomic, func(ctx context.Context, i int) (*model.ElementChange, error) {
+		return s.deleteInTx(ctx, inputs[i].URI, inputs[i].ExpectedVersion)
+	})
+}
+
+// bulk applies a write to each of uris and reports the outcome per element.
+// In atomic mode all writes share a transaction which is rolled back as soon
+// as one fails, the others then being reported as ABORTED.
+func (s *ElementService) bulk(ctx context.Context, uris []string, atomic bool,
+	apply func(ctx context.Context, i int) (*model.ElementChange, error)) ([]*model.BulkElementResult, error) {
+	if len(uris) > MaxBulkSize {
+		return nil, apperror.Validation("at most %d elements can be written at once, got %d", MaxBulkSize, len(uris))
+	}
+
+	results := make([]*model.BulkElementResult, len(uris))
+	changes := make([]*model.ElementChange, len(uris))
+
+	if !atomic {
+		for i, uri := range uris {
+			err := repository.RunInTx(ctx, s.db, func(ctx context.Context) error {
+				var err error
+				changes[i], err = apply(ctx, i)
+				return err
+			})
+			if err != nil {
+				results[i] = &model.BulkElementResult{URI: uri, Error: s.bulkError(ctx, err)}
+				continue
+			}
+			results[i] = bulkResult(changes[i])
+		}
+		s.outbox.Notify()
+		return results, nil
+	}
+
+	failed := -1
+	err := repository.RunInTx(ctx, s.db, func(ctx context.Context) error {
+		for i := range uris {
+			var err error
+			if changes[i], err = apply(ctx, i); err != nil {
+				failed = i
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && failed < 0 {
+		return nil, fmt.Errorf("failed to commit bulk write: %w", err)
+	}
+
+	for i, uri := range uris {
+		switch {
+		case i == failed:
+			results[i] = &model.BulkElementResult{URI: uri, Error: s.bulkError(ctx, err)}
+		case failed >= 0:
+			results[i] = &model.BulkElementResult{URI: uri, Error: &model.BulkElementError{
+				Code:        codeAborted,
+				Message:     fmt.Sprintf("rolled back because element %s failed", uris[failed]),
+				FieldErrors: []*model.FieldError{},
+			}}
+		default:
+			results[i] = bulkResult(changes[i])
+		}
+	}
+	s.outbox.Notify()
+	return results, nil
+}
+
+func bulkResult(change *model.ElementChange) *model.BulkElementResult {
+	result := &model.BulkElementResult{URI: change.Element.URI}
+	if !change.Deleted {
+		result.Element = change.Element
+	}
+	return result
+}
+
+// bulkError reports the error of a single element. Internal errors are logged
+// and replaced by a generic message, as the error presenter does for errors
+// of whole requests.
+func (s *ElementService) bulkError(ctx context.Context, err error) *model.BulkElementError {
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) || appErr.Code == apperror.CodeInternal {
+		requestID, _ := ctx.Value(models.RequestIDKey).(string)
+		log.Printf("request %s: internal error in bulk write: %v", requestID, err)
+		return &model.BulkElementError{Code: string(apperror.CodeInternal), Message: "internal server error", FieldErrors: []*model.FieldError{}}
+	}
+
+	fieldErrors := []*model.FieldError{}
+	if errs, ok := appErr.Extensions["fieldErrors"].([]model.FieldError); ok {
+		for i := range errs {
+			fieldErrors = append(fieldErrors, &errs[i])
+		}
+	}
+	return &model.BulkElementError{Code: string(appErr.Code), Message: appErr.Message, FieldErrors: fieldErrors}
+}
+
+// deleteInTx deletes an element within the transaction carried by ctx,
+// recomputing the rollups of the elements it was linked with. The returned
+// change holds the last state of the element and all of its values removed.
+// If expectedVersion is set and no longer matches the element, nothing is
+// deleted and a CONFLICT error holding the current state of the element is
+// returned, as mutate does. It requires write permission on the space of the
+// element.
+func (s *ElementService) deleteInTx(ctx context.Context, uri string, expectedVersion *int32) (*model.ElementChange, error) {
+	userSpaces, err := s.getUserSpaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	actor, err := s.getUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	// locks the element against concurrent writes
+	bumped, err := s.elementRepo.BumpVersion(ctx, uri, expectedVersion, userSpaces)
+	if err != nil {
+		return nil, err
+	}
+
+	before, params, err := s.elementRepo.GetByURI(ctx, uri, userSpaces)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.checkWritable(ctx, params.SpaceURI); err != nil {
+		return nil, err
+	}
+	if err = s.loadRelations(ctx, before, params); err != nil {
+		return nil, fmt.Errorf("failed to load element relations: %w", err)
+	}
+	if !bumped {
+		return nil, apperror.Conflict("element %s has been modified, current version is %d", before.URI, before.Version).
+			WithExtension("current", before)
+	}
+
+	links, err := s.link.ListLinks(ctx, uri, nil, userSpaces)
+	if err != nil {
+		return nil, err
+	}
+	// rollups of elements in spaces the user can't see depend on it as well
+	rollupLinks, err := s.link.ListAllLinks(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.elementRepo.Delete(ctx, uri, userSpaces); err != nil {
+		return nil, err
+	}
+
+	if err = s.refreshRollups(ctx, uri, params.TypeURI, rollupLinks, now.UnixMilli()); err != nil {
+		return nil, err
+	}
+
+	change := newElementChange(before, &model.Element{Title: before.Title}, actor, now)
+	change.Element = before
+	change.Deleted = true
+	change.Links, err = s.linkChanges(ctx, links, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.record(ctx, AuditActionElementDelete, change); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
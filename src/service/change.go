@@ -0,0 +1,58 @@
+package service
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/bamdadam/backend/graph/model"
+)
+
+// newElementChange builds the payload delivered to elementChanged subscribers
+// by diffing the title and field values of an element before and after a
+// write. Field values that are identical on both sides are left out of the
+// payload.
+func newElementChange(before, after *model.Element, actor *model.User, at time.Time) *model.ElementChange {
+	change := &model.ElementChange{
+		Element:     after,
+		FieldValues: []*model.ElementFieldValueChange{},
+		Links:       []*model.ElementLinkChange{},
+		Actor:       actor,
+		Timestamp:   at,
+	}
+
+	if before.Title != after.Title {
+		change.Title = &model.TitleChange{Before: before.Title, After: after.Title}
+	}
+
+	previous := make(map[string]*model.ElementFieldValue, len(before.FieldValues))
+	for _, fv := range before.FieldValues {
+		previous[fv.Field.URI] = fv
+	}
+
+	for _, fv := range after.FieldValues {
+		old, ok := previous[fv.Field.URI]
+		delete(previous, fv.Field.URI)
+		if ok && reflect.DeepEqual(old.Value, fv.Value) {
+			continue
+		}
+
+		fvChange := &model.ElementFieldValueChange{URI: fv.URI, Field: fv.Field, After: fv.Value}
+		if ok {
+			fvChange.Before = old.Value
+		}
+		change.FieldValues = append(change.FieldValues, fvChange)
+	}
+
+	// whatever is left in previous was removed by the write
+	for _, fv := range before.FieldValues {
+		if _, ok := previous[fv.Field.URI]; ok {
+			change.FieldValues = append(change.FieldValues, &model.ElementFieldValueChange{
+				URI:    fv.URI,
+				Field:  fv.Field,
+				Before: fv.Value,
+			})
+		}
+	}
+
+	return change
+}
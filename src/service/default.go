@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
+	models "github.com/bamdadam/backend/src/model"
+)
+
+// applyDefaults adds the default or generated values of the fields of an
+// element being created that inputs give no value for, not even null. They
+// are checked like given values afterwards. Sequences are advanced within the
+// transaction carried by ctx.
+func (s *ElementService) applyDefaults(ctx context.Context, def *models.TypeDefinition, inputs []*model.FieldValueInput,
+	actor *model.User, now time.Time) ([]*model.FieldValueInput, error) {
+	result := slices.Clone(inputs)
+	for _, f := range def.Fields {
+		if slices.ContainsFunc(inputs, func(in *model.FieldValueInput) bool { return in.FieldURI == f.URI }) {
+			continue
+		}
+
+		defaults, err := models.ParseFieldDefaults(f.FieldType, f.Options)
+		if err != nil {
+			return nil, apperror.Validation("invalid defaults of field %s: %w", f.URI, err)
+		}
+		if defaults.Empty() {
+			continue
+		}
+
+		value, err := s.defaultValue(ctx, f, defaults, actor, now)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &model.FieldValueInput{FieldURI: f.URI, Value: value})
+	}
+	return result, nil
+}
+
+// defaultValue returns the default of a field, or generates its value.
+func (s *ElementService) defaultValue(ctx context.Context, f *models.FieldDefinition, defaults *models.FieldDefaults,
+	actor *model.User, now time.Time) (any, error) {
+	switch defaults.Autogen {
+	case models.AutogenToday:
+		return model.FormatDateTime(now.UTC().Truncate(24 * time.Hour)), nil
+	case models.AutogenNow:
+		return model.FormatDateTime(now), nil
+	case models.AutogenCurrentUser:
+		if f.FieldType == model.FieldTypeEmail {
+			return actor.Email, nil
+		}
+		return actor.URI, nil
+	case models.AutogenSequence:
+		n, err := s.sequence.Next(ctx, f.URI)
+		if err != nil {
+			return nil, err
+		}
+		if f.FieldType == model.FieldTypeNumber {
+			return float64(n), nil
+		}
+		return fmt.Sprintf("%s%0*d", defaults.Prefix, defaults.Digits, n), nil
+	default:
+		return defaults.Default, nil
+	}
+}
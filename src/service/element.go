@@ -2,17 +2,18 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"time"
 
 	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
 	models "github.com/bamdadam/backend/src/model"
-	"github.com/bamdadam/backend/src/pubsub"
 	"github.com/bamdadam/backend/src/repository"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-var PaginationLimit int32 = 20
-
 type ElementService struct {
 	db *pgxpool.Pool
 
@@ -21,21 +22,39 @@ type ElementService struct {
 	elementRepo repository.ElementRepository
 	typeRepo    repository.TypeRepository
 	space       repository.SpaceRepository
+	field       repository.FieldRepository
 	fieldValue  repository.ElementFieldValueRepository
-	pubsub      *pubsub.ElementPubSub
+	link        repository.ElementLinkRepository
+	rollup      repository.RollupRepository
+	sequence    repository.FieldSequenceRepository
+	revision    repository.ElementRevisionRepository
+	audit       *AuditService
+	outbox      *OutboxService
+	// jobs queues background jobs, set by RegisterJobs
+	jobs   *JobService
+	limits Limits
 }
 
 func NewElementService(db *pgxpool.Pool, us *UserService, elementRepo repository.ElementRepository,
-	typeRepo repository.TypeRepository, spaceRepo repository.SpaceRepository,
-	fieldValueRepo repository.ElementFieldValueRepository, pubsub *pubsub.ElementPubSub) *ElementService {
+	typeRepo repository.TypeRepository, spaceRepo repository.SpaceRepository, fieldRepo repository.FieldRepository,
+	fieldValueRepo repository.ElementFieldValueRepository, linkRepo repository.ElementLinkRepository,
+	rollupRepo repository.RollupRepository, sequenceRepo repository.FieldSequenceRepository, revisionRepo repository.ElementRevisionRepository, audit *AuditService, outbox *OutboxService,
+	limits Limits) *ElementService {
 	return &ElementService{
 		db:          db,
 		UserService: us,
 		elementRepo: elementRepo,
 		typeRepo:    typeRepo,
 		space:       spaceRepo,
+		field:       fieldRepo,
 		fieldValue:  fieldValueRepo,
-		pubsub:      pubsub,
+		link:        linkRepo,
+		rollup:      rollupRepo,
+		sequence:    sequenceRepo,
+		revision:    revisionRepo,
+		audit:       audit,
+		outbox:      outbox,
+		limits:      limits,
 	}
 }
 
@@ -63,13 +82,11 @@ func (s *ElementService) List(ctx context.Context, params models.ListParams) (*m
 		return nil, fmt.Errorf("failed to list elements by uri: %w", err)
 	}
 
-	if err = s.validateFieldValueFilter(params.FieldValueFilter); err != nil {
-		return nil, fmt.Errorf("failed to validate filed value filter: %w", err)
+	if err = s.compileFilters(ctx, &params); err != nil {
+		return nil, err
 	}
 
-	if params.Limit <= 0 {
-		params.Limit = PaginationLimit
-	}
+	params.Limit = s.limits.pageSize(params.Limit)
 
 	elemsWithRelations, err := s.elementRepo.List(ctx, params, userSpaces)
 	if err != nil {
@@ -92,28 +109,348 @@ func (s *ElementService) List(ctx context.Context, params models.ListParams) (*m
 	return s.buildConnection(elements, hasNextPage), nil
 }
 
-func (s *ElementService) UpdateTitle(ctx context.Context, uri, title string) (*model.Element, error) {
+// Create creates an element with the given field values, filling in the
+// defaults of the fields without one. Values are checked against the types and
+// constraints of their fields. It requires write permission on the space.
+func (s *ElementService) Create(ctx context.Context, input model.CreateElementInput) (*model.Element, error) {
+	if err := s.checkWritable(ctx, input.SpaceURI); err != nil {
+		return nil, err
+	}
+
 	userSpaces, err := s.getUserSpaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create element: %w", err)
+	}
+
+	actor, err := s.getUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	def, err := s.spaceTypeDefinition(ctx, input.TypeURI, input.SpaceURI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create element: %w", err)
+	}
+
+	uri := newElementURI()
+	if input.URI != nil {
+		uri = *input.URI
+	}
+
+	var elem *model.Element
+	err = repository.RunInTx(ctx, s.db, func(ctx context.Context) error {
+		elem, err = s.createInTx(ctx, uri, input, def, actor, userSpaces, time.Now())
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create element: %w", err)
+	}
+
+	s.outbox.Notify()
+	return elem, nil
+}
+
+// spaceTypeDefinition returns the definition of a type elements of the space
+// can be created with. Types of other spaces are reported as not found.
+func (s *ElementService) spaceTypeDefinition(ctx context.Context, typeURI, spaceURI string) (*models.TypeDefinition, error) {
+	def, err := s.typeRepo.GetDefinition(ctx, typeURI)
+	if err != nil {
+		return nil, err
+	}
+	if def.SpaceURI != spaceURI {
+		return nil, apperror.NotFound("type not found: %s", typeURI)
+	}
+	return def, nil
+}
+
+// createInTx is the write of Create within the transaction carried by ctx.
+func (s *ElementService) createInTx(ctx context.Context, uri string, input model.CreateElementInput, def *models.TypeDefinition,
+	actor *model.User, userSpaces []string, now time.Time) (*model.Element, error) {
+	err := s.elementRepo.Create(ctx, uri, input.Title, input.TypeURI, input.SpaceURI, actor.URI, now.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+
+	inputs, err := s.applyDefaults(ctx, def, input.FieldValues, actor, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.setFieldValues(ctx, def, uri, input.SpaceURI, inputs, true, now.UnixMilli()); err != nil {
+		return nil, err
+	}
+
+	if err = s.refreshRollups(ctx, uri, input.TypeURI, nil, now.UnixMilli()); err != nil {
+		return nil, err
+	}
+
+	if err = s.revision.Create(ctx, uri, actor.URI, now.UnixMilli()); err != nil {
+		return nil, err
+	}
+
+	elem, params, err := s.elementRepo.GetByURI(ctx, uri, userSpaces)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.loadRelations(ctx, elem, params); err != nil {
+		return nil, fmt.Errorf("failed to load element relations: %w", err)
+	}
+
+	change := newElementChange(&model.Element{}, elem, actor, now)
+	if err = s.record(ctx, AuditActionElementCreate, change); err != nil {
+		return nil, err
+	}
+	return elem, nil
+}
+
+// Update changes the title and field values of an element. Only the given
+// field values are written, each checked against the type and constraints of
+// its field.
+func (s *ElementService) Update(ctx context.Context, input model.UpdateElementInput) (*model.Element, error) {
+	elem, err := s.mutate(ctx, input.URI, AuditActionElementUpdate, input.ExpectedVersion, s.updateElement(input))
 	if err != nil {
 		return nil, fmt.Errorf("failed to update element: %w", err)
 	}
+	return elem, nil
+}
+
+// updateElement returns the write of Update for mutate.
+func (s *ElementService) updateElement(input model.UpdateElementInput) func(ctx context.Context, userSpaces []string) error {
+	return func(ctx context.Context, userSpaces []string) error {
+		_, params, err := s.elementRepo.GetByURI(ctx, input.URI, userSpaces)
+		if err != nil {
+			return err
+		}
+
+		if input.Title != nil {
+			if _, _, err = s.elementRepo.UpdateTitle(ctx, input.URI, *input.Title, userSpaces); err != nil {
+				return err
+			}
+		}
+
+		def, err := s.typeRepo.GetDefinition(ctx, params.TypeURI)
+		if err != nil {
+			return err
+		}
+		return s.setFieldValues(ctx, def, input.URI, params.SpaceURI, input.FieldValues, false, time.Now().UnixMilli())
+	}
+}
 
-	elem, params, err := s.elementRepo.UpdateTitle(ctx, uri, title, userSpaces)
+func (s *ElementService) UpdateTitle(ctx context.Context, uri, title string, expectedVersion *int32) (*model.Element, error) {
+	elem, err := s.mutate(ctx, uri, AuditActionElementUpdateTitle, expectedVersion, func(ctx context.Context, userSpaces []string) error {
+		_, _, err := s.elementRepo.UpdateTitle(ctx, uri, title, userSpaces)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to update element: %w", err)
 	}
+	return elem, nil
+}
 
-	err = s.loadRelations(ctx, elem, params)
+// Revert restores the title and field values captured in a previous revision.
+// The restored state is recorded as a new revision, leaving history intact.
+// Restored values are checked like the values of an update, against the
+// current types and constraints of their fields. Values of fields no longer
+// part of the element's type are dropped. Links are not versioned and are left
+// as they are.
+func (s *ElementService) Revert(ctx context.Context, uri, revisionID string, expectedVersion *int32) (*model.Element, error) {
+	elem, err := s.mutate(ctx, uri, AuditActionElementRevert, expectedVersion, s.revertElement(uri, revisionID))
 	if err != nil {
-		return nil, fmt.Errorf("failed to load element relations: %w", err)
+		return nil, fmt.Errorf("failed to revert element: %w", err)
+	}
+	return elem, nil
+}
+
+// revertElement returns the write of Revert for mutate.
+func (s *ElementService) revertElement(uri, revisionID string) func(ctx context.Context, userSpaces []string) error {
+	return func(ctx context.Context, userSpaces []string) error {
+		_, params, err := s.elementRepo.GetByURI(ctx, uri, userSpaces)
+		if err != nil {
+			return err
+		}
+
+		rev, err := s.revision.Get(ctx, uri, revisionID)
+		if err != nil {
+			return err
+		}
+
+		restored, err := s.fieldValue.GetByRevisionID(ctx, uri, revisionID)
+		if err != nil {
+			return err
+		}
+
+		current, err := s.fieldValue.GetByElementURI(ctx, uri)
+		if err != nil {
+			return err
+		}
+
+		def, err := s.typeRepo.GetDefinition(ctx, params.TypeURI)
+		if err != nil {
+			return err
+		}
+
+		if _, _, err = s.elementRepo.UpdateTitle(ctx, uri, rev.Title, userSpaces); err != nil {
+			return err
+		}
+		return s.setFieldValues(ctx, def, uri, params.SpaceURI, revertInputs(def, restored, current), false, time.Now().UnixMilli())
+	}
+}
+
+// revertInputs returns the field values setting the writable fields of def
+// from their values in restored, clearing the ones with a current value but
+// none in restored.
+func revertInputs(def *models.TypeDefinition, restored, current []*model.ElementFieldValue) []*model.FieldValueInput {
+	values := make(map[string]any, len(restored))
+	for _, fv := range restored {
+		values[fv.Field.URI] = fv.Value
+	}
+	set := make(map[string]bool, len(current))
+	for _, fv := range current {
+		set[fv.Field.URI] = true
+	}
+
+	var inputs []*model.FieldValueInput
+	for _, f := range def.Fields {
+		if !writable(f.FieldType) {
+			continue
+		}
+		value, ok := values[f.URI]
+		if !ok && !set[f.URI] {
+			continue
+		}
+		inputs = append(inputs, &model.FieldValueInput{FieldURI: f.URI, Value: value})
+	}
+	return inputs
+}
+
+// GetAt returns an element as it was at the given time. Only the title and
+// field values are versioned, the other attributes of the element, its links
+// included, are returned as they are now.
+func (s *ElementService) GetAt(ctx context.Context, uri string, at time.Time) (*model.Element, error) {
+	elem, err := s.GetByURI(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	rev, err := s.revision.GetAt(ctx, uri, at.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get element revision: %w", err)
 	}
 
-	s.pubsub.Publish(elem)
+	// without any revision at or before the timestamp the element either has
+	// never been written since its creation, or did not exist yet
+	if rev == nil {
+		if elem.CreationDate.After(at) {
+			return nil, apperror.NotFound("element %s did not exist at %s", uri, model.FormatDateTime(at))
+		}
+		return elem, nil
+	}
+
+	elem.Title = rev.Title
+	elem.FieldValues, err = s.fieldValue.GetByRevisionID(ctx, uri, rev.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revision field values: %w", err)
+	}
+
+	if err = s.computeFormulas(ctx, elem, elem.Type.URI); err != nil {
+		return nil, err
+	}
 
 	return elem, nil
 }
 
+// ResultType returns the type of the values a FORMULA or ROLLUP field
+// computes, nil for other fields.
+func (s *ElementService) ResultType(ctx context.Context, field *model.Field) (*model.FieldType, error) {
+	switch field.FieldType {
+	case model.FieldTypeFormula:
+		return s.formulaResultType(ctx, field)
+	case model.FieldTypeRollup:
+		rollup, err := s.rollup.GetByFieldURI(ctx, field.URI)
+		if err != nil {
+			return nil, err
+		}
+		return &rollup.ResultType, nil
+	default:
+		return nil, nil
+	}
+}
+
+// Revisions lists the revisions of an element, newest first.
+func (s *ElementService) Revisions(ctx context.Context, uri string, first *int32, after *string) (*model.ElementRevisionConnection, error) {
+	var limit int32
+	if first != nil {
+		limit = *first
+	}
+	limit = s.limits.pageSize(limit)
+
+	revisions, err := s.revision.List(ctx, uri, limit, after)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list element revisions: %w", err)
+	}
+
+	hasNextPage := len(revisions) > int(limit)
+	if hasNextPage {
+		revisions = revisions[:limit]
+	}
+
+	edges := make([]*model.ElementRevisionEdge, len(revisions))
+	for i, rev := range revisions {
+		rev.Author, err = s.user.GetByURI(ctx, rev.AuthorURI)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get revision author: %w", err)
+		}
+
+		rev.FieldValues, err = s.fieldValue.GetByRevisionID(ctx, uri, rev.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get revision field values: %w", err)
+		}
+
+		edges[i] = &model.ElementRevisionEdge{Cursor: rev.ID, Node: rev.ElementRevision}
+	}
+
+	pageInfo := &model.PageInfo{HasNextPage: hasNextPage}
+	if len(edges) > 0 {
+		pageInfo.StartCursor = &edges[0].Cursor
+		pageInfo.EndCursor = &edges[len(edges)-1].Cursor
+	}
+
+	return &model.ElementRevisionConnection{
+		Edges:      edges,
+		PageInfo:   pageInfo,
+		TotalCount: int32(len(edges)),
+	}, nil
+}
+
+// UpdateElementSubscribe sends the element after each of its updates.
+// Deletions are not sent, ElementChangeSubscribe tells of them.
 func (s *ElementService) UpdateElementSubscribe(ctx context.Context, uri string) (<-chan *model.Element, error) {
+	changes, err := s.ElementChangeSubscribe(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	elements := make(chan *model.Element, 1)
+	go func() {
+		defer close(elements)
+		for change := range changes {
+			if change.Deleted {
+				continue
+			}
+			select {
+			case elements <- change.Element:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return elements, nil
+}
+
+// ElementChangeSubscribe sends the changes of an element as they are relayed
+// from the outbox, until ctx is done.
+func (s *ElementService) ElementChangeSubscribe(ctx context.Context, uri string) (<-chan *model.ElementChange, error) {
 	userSpaces, err := s.getUserSpaces(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to subscribe to element by uri: %w", err)
@@ -124,16 +461,156 @@ func (s *ElementService) UpdateElementSubscribe(ctx context.Context, uri string)
 		return nil, fmt.Errorf("failed to subscribe to element by uri: %w", err)
 	}
 
-	ch := s.pubsub.Subscribe(uri)
+	ch, err := s.outbox.Subscribe(ctx, uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to element by uri: %w", err)
+	}
 
 	go func() {
 		<-ctx.Done()
-		s.pubsub.Unsubscribe(uri, ch)
+		s.outbox.Unsubscribe(uri, ch)
 	}()
 
 	return ch, nil
 }
 
+// mutate runs write against a single element inside a transaction, records the
+// resulting state as a new revision along with an audit entry for action, and
+// appends the diff to the outbox for subscribers and webhooks. write is handed
+// the transactional context and the spaces the user has access to. If
+// expectedVersion is set and no longer matches the element, nothing is written
+// and a CONFLICT error holding the current state of the element is returned.
+// It requires write permission on the space of the element.
+func (s *ElementService) mutate(ctx context.Context, uri, action string, expectedVersion *int32, write func(ctx context.Context, userSpaces []string) error) (*model.Element, error) {
+	var change *model.ElementChange
+	err := repository.RunInTx(ctx, s.db, func(ctx context.Context) error {
+		var err error
+		change, err = s.mutateInTx(ctx, uri, action, expectedVersion, write)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.outbox.Notify()
+
+	return change.Element, nil
+}
+
+// mutateInTx is mutate within the transaction carried by ctx, returning the
+// change. Callers notify the outbox once the transaction has committed.
+func (s *ElementService) mutateInTx(ctx context.Context, uri, action string, expectedVersion *int32, write func(ctx context.Context, userSpaces []string) error) (*model.ElementChange, error) {
+	userSpaces, err := s.getUserSpaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	actor, err := s.getUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	bumped, err := s.elementRepo.BumpVersion(ctx, uri, expectedVersion, userSpaces)
+	if err != nil {
+		return nil, err
+	}
+
+	before, params, err := s.elementRepo.GetByURI(ctx, uri, userSpaces)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.checkWritable(ctx, params.SpaceURI); err != nil {
+		return nil, err
+	}
+
+	if !bumped {
+		if err = s.loadRelations(ctx, before, params); err != nil {
+			return nil, fmt.Errorf("failed to load element relations: %w", err)
+		}
+		return nil, apperror.Conflict("element %s has been modified, current version is %d", before.URI, before.Version).
+			WithExtension("current", before)
+	}
+
+	before.FieldValues, err = s.fieldValue.GetByElementURI(ctx, uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get field values: %w", err)
+	}
+
+	if err = s.computeFormulas(ctx, before, params.TypeURI); err != nil {
+		return nil, err
+	}
+
+	linksBefore, err := s.link.ListLinks(ctx, uri, nil, userSpaces)
+	if err != nil {
+		return nil, err
+	}
+	// rollups of elements in spaces the user can't see depend on it as well
+	rollupLinks, err := s.link.ListAllLinks(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.revision.CreateBaseline(ctx, uri); err != nil {
+		return nil, err
+	}
+
+	if err = write(ctx, userSpaces); err != nil {
+		return nil, err
+	}
+
+	if err = s.refreshRollups(ctx, uri, params.TypeURI, rollupLinks, now.UnixMilli()); err != nil {
+		return nil, err
+	}
+
+	if err = s.revision.Create(ctx, uri, actor.URI, now.UnixMilli()); err != nil {
+		return nil, err
+	}
+
+	after, params, err := s.elementRepo.GetByURI(ctx, uri, userSpaces)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.loadRelations(ctx, after, params); err != nil {
+		return nil, fmt.Errorf("failed to load element relations: %w", err)
+	}
+
+	linksAfter, err := s.link.ListLinks(ctx, uri, nil, userSpaces)
+	if err != nil {
+		return nil, err
+	}
+
+	change := newElementChange(before, after, actor, now)
+	change.Links, err = s.linkChanges(ctx, linksBefore, linksAfter)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.record(ctx, action, change); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// record writes the audit entry of a change and appends it to the outbox,
+// within the transaction carried by ctx.
+func (s *ElementService) record(ctx context.Context, action string, change *model.ElementChange) error {
+	elem := change.Element
+	if err := s.audit.Record(ctx, action, elem.URI, elem.Space.Tenant.URI, elementAuditDiff(change)); err != nil {
+		return err
+	}
+	return s.outbox.AppendElementChange(ctx, action, change)
+}
+
+// newElementURI generates the URI of an element created without one.
+func newElementURI() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "element:" + hex.EncodeToString(b)
+}
+
 // buildConnection transforms a slice of elements into a GraphQL-compliant connection structure
 // with edges, cursors, and pagination info. Each element's URI is used as its cursor.
 func (s *ElementService) buildConnection(elements []*model.Element, hasNextPage bool) *model.ElementConnection {
@@ -184,6 +661,23 @@ func (s *ElementService) loadRelations(ctx context.Context, elem *model.Element,
 		return fmt.Errorf("failed to get field values: %w", err)
 	}
 
+	return s.computeFormulas(ctx, elem, params.TypeURI)
+}
+
+// compileFilters validates the filters of params and compiles its relation
+// filter to a condition.
+func (s *ElementService) compileFilters(ctx context.Context, params *models.ListParams) error {
+	if err := s.validateFieldValueFilter(params.FieldValueFilter); err != nil {
+		return fmt.Errorf("failed to validate filed value filter: %w", err)
+	}
+
+	if params.RelationFilter != nil {
+		cond, err := s.relationCondition(ctx, params.RelationFilter)
+		if err != nil {
+			return err
+		}
+		params.Conditions = append(params.Conditions, cond)
+	}
 	return nil
 }
 
@@ -194,7 +688,7 @@ func (s *ElementService) validateFieldValueFilter(filter *model.FieldValueFilter
 	if filter != nil &&
 		((filter.Value != nil && filter.ValueType == nil) ||
 			(filter.Value == nil && filter.ValueType != nil)) {
-		return fmt.Errorf("value and value type not present together")
+		return apperror.Validation("value and value type not present together")
 	}
 	return nil
 }
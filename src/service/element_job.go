@@ -0,0 +1,120 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
+)
+
+// Kinds of the background jobs of elements.
+const (
+	JobKindUpdateElementsWhere = "element.update_where"
+	JobKindImportElements      = "element.import"
+)
+
+// updateWhereJob is the payload of JobKindUpdateElementsWhere jobs.
+type updateWhereJob struct {
+	SpaceURI string                   `json:"spaceUri"`
+	TypeURI  string                   `json:"typeUri"`
+	Filter   *model.ElementFilter     `json:"filter,omitempty"`
+	Set      []*model.FieldValueInput `json:"set"`
+	DryRun   bool                     `json:"dryRun"`
+}
+
+// importJob is the payload of JobKindImportElements jobs, holding the whole
+// uploaded file. Data is base64 encoded in the payload so that files which
+// aren't valid UTF-8 are kept as they are, File holds the file of jobs queued
+// before.
+type importJob struct {
+	TypeURI              string                       `json:"typeUri"`
+	SpaceURI             string                       `json:"spaceUri"`
+	Data                 []byte                       `json:"data,omitempty"`
+	File                 string                       `json:"file,omitempty"`
+	Mapping              []*model.ImportColumnMapping `json:"mapping,omitempty"`
+	MultiSelectDelimiter *string                      `json:"multiSelectDelimiter,omitempty"`
+	DryRun               *bool                        `json:"dryRun,omitempty"`
+}
+
+// RegisterJobs registers the handlers of the background jobs of elements,
+// which the Start methods queue.
+func (s *ElementService) RegisterJobs(jobs *JobService) {
+	s.jobs = jobs
+	jobs.Register(JobKindUpdateElementsWhere, s.runUpdateWhereJob)
+	jobs.Register(JobKindImportElements, s.runImportJob)
+}
+
+// StartUpdateWhere queues UpdateWhere as a background job, once the user is
+// known to be allowed to write to the space.
+func (s *ElementService) StartUpdateWhere(ctx context.Context, spaceURI, typeURI string, filter *model.ElementFilter,
+	set []*model.FieldValueInput, dryRun bool) (*model.Job, error) {
+	if err := s.checkWritable(ctx, spaceURI); err != nil {
+		return nil, err
+	}
+	if len(set) == 0 {
+		return nil, apperror.Validation("no field values to set")
+	}
+
+	return s.jobs.Enqueue(ctx, JobKindUpdateElementsWhere, updateWhereJob{
+		SpaceURI: spaceURI,
+		TypeURI:  typeURI,
+		Filter:   filter,
+		Set:      set,
+		DryRun:   dryRun,
+	})
+}
+
+// StartImport queues Import as a background job, once the user is known to be
+// allowed to write to the space. The uploaded file is stored with the job.
+func (s *ElementService) StartImport(ctx context.Context, input model.ImportElementsInput) (*model.Job, error) {
+	if err := s.checkWritable(ctx, input.SpaceURI); err != nil {
+		return nil, err
+	}
+
+	file, err := io.ReadAll(input.File.File)
+	if err != nil {
+		return nil, apperror.Validation("failed to read the uploaded file: %v", err)
+	}
+
+	return s.jobs.Enqueue(ctx, JobKindImportElements, importJob{
+		TypeURI:              input.TypeURI,
+		SpaceURI:             input.SpaceURI,
+		Data:                 file,
+		Mapping:              input.Mapping,
+		MultiSelectDelimiter: input.MultiSelectDelimiter,
+		DryRun:               input.DryRun,
+	})
+}
+
+func (s *ElementService) runUpdateWhereJob(ctx context.Context, payload []byte, progress JobProgress) (any, error) {
+	var job updateWhereJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job payload: %w", err)
+	}
+	return s.updateWhere(ctx, job.SpaceURI, job.TypeURI, job.Filter, job.Set, job.DryRun, progress)
+}
+
+func (s *ElementService) runImportJob(ctx context.Context, payload []byte, progress JobProgress) (any, error) {
+	var job importJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job payload: %w", err)
+	}
+
+	if job.Data == nil {
+		job.Data = []byte(job.File)
+	}
+
+	return s.importElements(ctx, model.ImportElementsInput{
+		TypeURI:              job.TypeURI,
+		SpaceURI:             job.SpaceURI,
+		File:                 graphql.Upload{File: bytes.NewReader(job.Data), Size: int64(len(job.Data))},
+		Mapping:              job.Mapping,
+		MultiSelectDelimiter: job.MultiSelectDelimiter,
+		DryRun:               job.DryRun,
+	}, progress)
+}
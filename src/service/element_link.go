@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
+	models "github.com/bamdadam/backend/src/model"
+)
+
+// Links returns the elements an element links to through its relation fields,
+// or through a single field if fieldURI is set. Linked elements the user
+// can't access are left out.
+func (s *ElementService) Links(ctx context.Context, uri string, fieldURI *string) ([]*model.ElementLink, error) {
+	userSpaces, err := s.getUserSpaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get element links: %w", err)
+	}
+
+	links, err := s.link.ListLinks(ctx, uri, fieldURI, userSpaces)
+	if err != nil {
+		return nil, err
+	}
+	return s.resolveLinks(ctx, links)
+}
+
+// Backlinks returns the elements linking to an element, or linking through a
+// single field if fieldURI is set. Linking elements the user can't access are
+// left out.
+func (s *ElementService) Backlinks(ctx context.Context, uri string, fieldURI *string) ([]*model.ElementLink, error) {
+	userSpaces, err := s.getUserSpaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get element backlinks: %w", err)
+	}
+
+	links, err := s.link.ListBacklinks(ctx, uri, fieldURI, userSpaces)
+	if err != nil {
+		return nil, err
+	}
+	return s.resolveLinks(ctx, links)
+}
+
+func (s *ElementService) resolveLinks(ctx context.Context, links []*models.ElementLink) ([]*model.ElementLink, error) {
+	fields := make(map[string]*model.Field)
+	result := make([]*model.ElementLink, 0, len(links))
+
+	for _, link := range links {
+		field, ok := fields[link.FieldURI]
+		if !ok {
+			var err error
+			field, err = s.field.GetByURI(ctx, link.FieldURI)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get link field: %w", err)
+			}
+			fields[link.FieldURI] = field
+		}
+
+		elem, err := s.GetByURI(ctx, link.ElementURI)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get linked element: %w", err)
+		}
+
+		result = append(result, &model.ElementLink{Field: field, Element: elem})
+	}
+
+	return result, nil
+}
+
+// SetLinks replaces the elements an element links to through a relation
+// field. Targets must be accessible elements of the field's target type.
+func (s *ElementService) SetLinks(ctx context.Context, uri, fieldURI string, targetURIs []string, expectedVersion *int32) (*model.Element, error) {
+	field, opts, err := s.relationField(ctx, fieldURI)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Cardinality == models.CardinalitySingle && len(targetURIs) > 1 {
+		return nil, apperror.Validation("field %s links to a single element, got %d", fieldURI, len(targetURIs))
+	}
+
+	elem, err := s.mutate(ctx, uri, AuditActionElementSetLinks, expectedVersion, func(ctx context.Context, userSpaces []string) error {
+		_, params, err := s.elementRepo.GetByURI(ctx, uri, userSpaces)
+		if err != nil {
+			return err
+		}
+		if params.TypeURI != field.Type.URI {
+			return apperror.Validation("field %s does not belong to the type of element %s", fieldURI, uri)
+		}
+
+		for i, target := range targetURIs {
+			if slices.Contains(targetURIs[:i], target) {
+				return apperror.Validation("element %s is linked more than once", target)
+			}
+			_, targetParams, err := s.elementRepo.GetByURI(ctx, target, userSpaces)
+			if err != nil {
+				return err
+			}
+			if targetParams.TypeURI != opts.TargetTypeURI {
+				return apperror.Validation("element %s is not of type %s", target, opts.TargetTypeURI)
+			}
+		}
+
+		return s.link.Set(ctx, uri, fieldURI, targetURIs, time.Now().UnixMilli())
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set element links: %w", err)
+	}
+	return elem, nil
+}
+
+// relationField loads a RELATION field together with its options.
+func (s *ElementService) relationField(ctx context.Context, fieldURI string) (*model.Field, *models.RelationOptions, error) {
+	field, err := s.field.GetByURI(ctx, fieldURI)
+	if err != nil {
+		return nil, nil, err
+	}
+	if field.FieldType != model.FieldTypeRelation {
+		return nil, nil, apperror.Validation("field %s is not a relation field", fieldURI)
+	}
+
+	opts, err := models.ParseRelationOptions(field.Options)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid options of field %s: %w", fieldURI, err)
+	}
+	return field, opts, nil
+}
+
+// relationCondition compiles a RelationFilter to a condition matching elements
+// linked to an element whose field value matches the nested filter.
+func (s *ElementService) relationCondition(ctx context.Context, filter *model.RelationFilter) (models.FieldCondition, error) {
+	if _, _, err := s.relationField(ctx, filter.FieldURI); err != nil {
+		return models.FieldCondition{}, err
+	}
+
+	related, err := fieldValueCondition(filter.FieldValueFilter)
+	if err != nil {
+		return models.FieldCondition{}, err
+	}
+
+	return models.FieldCondition{
+		FieldURI:  filter.FieldURI,
+		FieldType: model.FieldTypeRelation,
+		Related:   []models.FieldCondition{related},
+	}, nil
+}
+
+// fieldValueCondition converts an equality FieldValueFilter to a condition.
+// All of its attributes must be set.
+func fieldValueCondition(filter *model.FieldValueFilter) (models.FieldCondition, error) {
+	if filter.FieldURI == nil || filter.Value == nil || filter.ValueType == nil {
+		return models.FieldCondition{}, apperror.Validation("field value filter requires fieldUri, value and valueType")
+	}
+
+	cond := models.FieldCondition{FieldURI: *filter.FieldURI, Operator: models.FilterEq}
+	var err error
+
+	switch *filter.ValueType {
+	case model.FieldValueTypeText:
+		cond.FieldType, cond.Value = model.FieldTypeText, *filter.Value
+	case model.FieldValueTypeNumber:
+		cond.FieldType = model.FieldTypeNumber
+		cond.Value, err = strconv.ParseFloat(*filter.Value, 64)
+	case model.FieldValueTypeDate:
+		cond.FieldType = model.FieldTypeDate
+		var date time.Time
+		date, err = model.ParseDateTime(*filter.Value)
+		cond.Value = date.UnixMilli()
+	case model.FieldValueTypeBoolean:
+		cond.FieldType = model.FieldTypeBoolean
+		cond.Value, err = strconv.ParseBool(*filter.Value)
+	case model.FieldValueTypeJSON:
+		cond.FieldType, cond.Value = model.FieldTypeMultiSelect, *filter.Value
+	default:
+		return models.FieldCondition{}, apperror.Validation("unknown value type: %s", *filter.ValueType)
+	}
+	if err != nil {
+		return models.FieldCondition{}, apperror.Validation("invalid %s value: %w", *filter.ValueType, err)
+	}
+
+	return cond, nil
+}
+
+// linkChanges diffs the links of an element before and after a write, per
+// relation field.
+func (s *ElementService) linkChanges(ctx context.Context, before, after []*models.ElementLink) ([]*model.ElementLinkChange, error) {
+	group := func(links []*models.ElementLink) (map[string][]string, []string) {
+		byField := make(map[string][]string)
+		var order []string
+		for _, l := range links {
+			if _, ok := byField[l.FieldURI]; !ok {
+				order = append(order, l.FieldURI)
+			}
+			byField[l.FieldURI] = append(byField[l.FieldURI], l.ElementURI)
+		}
+		return byField, order
+	}
+
+	previous, previousOrder := group(before)
+	current, currentOrder := group(after)
+
+	changes := []*model.ElementLinkChange{}
+	for _, fieldURI := range append(currentOrder, previousOrder...) {
+		was, is := previous[fieldURI], current[fieldURI]
+		if slices.Equal(was, is) {
+			continue
+		}
+		// a field in both orders compares equal the second time around
+		delete(previous, fieldURI)
+		delete(current, fieldURI)
+
+		field, err := s.field.GetByURI(ctx, fieldURI)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get link field: %w", err)
+		}
+
+		if was == nil {
+			was = []string{}
+		}
+		if is == nil {
+			is = []string{}
+		}
+		changes = append(changes, &model.ElementLinkChange{Field: field, Before: was, After: is})
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
+	models "github.com/bamdadam/backend/src/model"
+)
+
+// ExportTokenTTL is how long the download links created by CreateToken stay
+// valid.
+var ExportTokenTTL = 5 * time.Minute
+
+// exportColumns are the columns every export starts with, followed by one per
+// field of the exported type.
+var exportColumns = []string{"uri", "title", "spaceUri", "author", "creationDate"}
+
+// processSecret signs export tokens when no secret is configured, tokens are
+// then only accepted by the process that created them.
+var processSecret = sync.OnceValue(func() []byte {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	return secret
+})
+
+// ExportService streams the elements of a type as CSV or NDJSON.
+type ExportService struct {
+	*ElementService
+
+	secret []byte
+}
+
+// NewExportService returns an ExportService signing download tokens with
+// secret, or with a secret of the process when it is empty.
+func NewExportService(es *ElementService, secret []byte) *ExportService {
+	if len(secret) == 0 {
+		secret = processSecret()
+	}
+	return &ExportService{ElementService: es, secret: secret}
+}
+
+// Export writes the elements of a type matching the filter of params to w,
+// with the permission checks and filters of List. Elements are read from a
+// server-side cursor and written as they arrive, in the order of their URI
+// with one column per field in the order of the type definition.
+func (s *ExportService) Export(ctx context.Context, params models.ExportParams, w io.Writer) error {
+	userSpaces, err := s.getUserSpaces(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to export elements: %w", err)
+	}
+
+	listParams, err := s.exportListParams(ctx, params, userSpaces)
+	if err != nil {
+		return err
+	}
+
+	formulas, err := s.formulas(ctx, params.TypeURI)
+	if err != nil {
+		return err
+	}
+
+	var stored []*models.FieldDefinition
+	for _, f := range formulas.def.Fields {
+		if f.FieldType != model.FieldTypeFormula {
+			stored = append(stored, f)
+		}
+	}
+
+	out := newExportWriter(params.Format, w, formulas.def.Fields)
+	if err = out.header(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	err = s.elementRepo.Export(ctx, listParams, stored, userSpaces, func(row *models.ExportRow) error {
+		if !formulas.set.Empty() {
+			for uri, value := range formulas.set.Eval(row.Values, now) {
+				row.Values[uri] = value
+			}
+		}
+		return out.row(row)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export elements: %w", err)
+	}
+	return out.flush()
+}
+
+// exportListParams validates params and compiles them to the parameters of
+// the query listing the exported elements. Types of spaces the user has no
+// access to are reported as not found.
+func (s *ExportService) exportListParams(ctx context.Context, params models.ExportParams, userSpaces []string) (models.ListParams, error) {
+	if !params.Format.IsValid() {
+		return models.ListParams{}, apperror.Validation("unknown export format: %s", params.Format)
+	}
+
+	def, err := s.typeRepo.GetDefinition(ctx, params.TypeURI)
+	if err != nil {
+		return models.ListParams{}, fmt.Errorf("failed to export elements: %w", err)
+	}
+	if !slices.Contains(userSpaces, def.SpaceURI) {
+		return models.ListParams{}, apperror.NotFound("type not found: %s", params.TypeURI)
+	}
+
+	listParams := models.ListParams{TypeURI: &params.TypeURI, SpaceURI: params.SpaceURI}
+	if params.Filter != nil {
+		listParams.FieldValueFilter = params.Filter.FieldValueFilter
+		listParams.RelationFilter = params.Filter.RelationFilter
+	}
+	if err := s.compileFilters(ctx, &listParams); err != nil {
+		return models.ListParams{}, err
+	}
+	return listParams, nil
+}
+
+// exportToken is the content of a download token.
+type exportToken struct {
+	UserID  string              `json:"userId"`
+	Params  models.ExportParams `json:"params"`
+	Expires int64               `json:"expires"`
+}
+
+// CreateToken validates params and returns a link exporting them on behalf of
+// the user making the request, for clients that can't set headers on
+// downloads.
+func (s *ExportService) CreateToken(ctx context.Context, params models.ExportParams) (*model.ExportDownload, error) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userSpaces, err := s.getUserSpaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export token: %w", err)
+	}
+
+	if _, err = s.exportListParams(ctx, params, userSpaces); err != nil {
+		return nil, err
+	}
+
+	expires := time.Now().Add(ExportTokenTTL)
+	payload, err := json.Marshal(exportToken{UserID: userID, Params: params, Expires: expires.UnixMilli()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export token: %w", err)
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(s.sign(payload))
+	return &model.ExportDownload{URL: "/export?token=" + url.QueryEscape(token), ExpiresAt: expires}, nil
+}
+
+// ParseToken returns the user and parameters of an export token created by
+// CreateToken, or an UNAUTHENTICATED error if it is invalid or has expired.
+func (s *ExportService) ParseToken(token string) (string, models.ExportParams, error) {
+	invalid := apperror.Unauthenticated("invalid or expired export token")
+
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", models.ExportParams{}, invalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", models.ExportParams{}, invalid
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, s.sign(payload)) {
+		return "", models.ExportParams{}, invalid
+	}
+
+	var t exportToken
+	if err = json.Unmarshal(payload, &t); err != nil || time.Now().UnixMilli() > t.Expires {
+		return "", models.ExportParams{}, invalid
+	}
+	return t.UserID, t.Params, nil
+}
+
+func (s *ExportService) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// exportWriter writes exported elements in one of the export formats.
+type exportWriter struct {
+	format model.ExportFormat
+	fields []*models.FieldDefinition
+	csv    *csv.Writer
+	json   *json.Encoder
+}
+
+func newExportWriter(format model.ExportFormat, w io.Writer, fields []*models.FieldDefinition) *exportWriter {
+	out := &exportWriter{format: format, fields: fields}
+	if format == model.ExportFormatCSV {
+		out.csv = csv.NewWriter(w)
+	} else {
+		out.json = json.NewEncoder(w)
+	}
+	return out
+}
+
+// header writes the column names of CSV exports, the names of the fields.
+func (w *exportWriter) header() error {
+	if w.csv == nil {
+		return nil
+	}
+	header := append([]string{}, exportColumns...)
+	for _, f := range w.fields {
+		header = append(header, f.Name)
+	}
+	return w.csv.Write(header)
+}
+
+// row writes an element. NDJSON lines hold the field values by field URI,
+// null for fields without a value.
+func (w *exportWriter) row(row *models.ExportRow) error {
+	creationDate := model.FormatDateTime(row.CreationDate)
+
+	if w.csv == nil {
+		values := make(map[string]any, len(w.fields))
+		for _, f := range w.fields {
+			values[f.URI] = row.Values[f.URI]
+		}
+		return w.json.Encode(map[string]any{
+			"uri":          row.URI,
+			"title":        row.Title,
+			"spaceUri":     row.SpaceURI,
+			"author":       row.AuthorURI,
+			"creationDate": creationDate,
+			"fieldValues":  values,
+		})
+	}
+
+	record := []string{row.URI, row.Title, row.SpaceURI, row.AuthorURI, creationDate}
+	for _, f := range w.fields {
+		record = append(record, csvCell(row.Values[f.URI]))
+	}
+	return w.csv.Write(record)
+}
+
+func (w *exportWriter) flush() error {
+	if w.csv == nil {
+		return nil
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+// csvCell formats a field value for CSV exports the way importElements reads
+// it back, lists being joined with DefaultMultiSelectDelimiter.
+func csvCell(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []string:
+		return strings.Join(v, DefaultMultiSelectDelimiter)
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = csvCell(item)
+		}
+		return strings.Join(items, DefaultMultiSelectDelimiter)
+	}
+	b, _ := json.Marshal(value)
+	return string(b)
+}
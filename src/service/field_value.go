@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
+	models "github.com/bamdadam/backend/src/model"
+)
+
+// setFieldValues validates and writes field values of an element of type def
+// in spaceURI. Every rejected value is collected into a single VALIDATION
+// error listing them in its fieldErrors extension, in which case nothing is
+// written. When creating, required fields without a value are rejected too.
+func (s *ElementService) setFieldValues(ctx context.Context, def *models.TypeDefinition, elementURI, spaceURI string,
+	inputs []*model.FieldValueInput, create bool, updatedDate int64) error {
+	fields := make(map[string]*models.FieldDefinition, len(def.Fields))
+	for _, f := range def.Fields {
+		fields[f.URI] = f
+	}
+
+	var errs []model.FieldError
+	values := make(map[string]any, len(inputs))
+	// seen holds the fields a value was given for, valid or not
+	seen := make(map[string]bool, len(inputs))
+	for _, in := range inputs {
+		f, ok := fields[in.FieldURI]
+		switch {
+		case !ok:
+			errs = append(errs, model.FieldError{FieldURI: in.FieldURI, Constraint: "field", Message: fmt.Sprintf("not a field of type %s", def.URI)})
+			continue
+		case seen[in.FieldURI]:
+			errs = append(errs, model.FieldError{FieldURI: in.FieldURI, Constraint: "field", Message: "set more than once"})
+			continue
+		}
+		seen[in.FieldURI] = true
+
+		value, fieldErrs, err := s.checkFieldValue(ctx, f, elementURI, spaceURI, in.Value)
+		if err != nil {
+			return err
+		}
+		if len(fieldErrs) > 0 {
+			errs = append(errs, fieldErrs...)
+			continue
+		}
+		values[f.URI] = value
+	}
+
+	if create {
+		for _, f := range def.Fields {
+			if f.Required && writable(f.FieldType) && !seen[f.URI] {
+				errs = append(errs, model.FieldError{FieldURI: f.URI, Constraint: "required", Message: "a value is required"})
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return newFieldErrors(errs)
+	}
+
+	for _, f := range def.Fields {
+		value, ok := values[f.URI]
+		if !ok {
+			continue
+		}
+		if err := s.fieldValue.Set(ctx, elementURI, f.URI, f.FieldType, value, updatedDate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkFieldValue converts a value given for a field to the representation it
+// is checked and stored in, then checks it against the field's constraints.
+// A nil value removes the field's value, which only required fields reject.
+func (s *ElementService) checkFieldValue(ctx context.Context, f *models.FieldDefinition, elementURI, spaceURI string, input any) (any, []model.FieldError, error) {
+	fail := func(constraint, format string, args ...any) (any, []model.FieldError, error) {
+		return nil, []model.FieldError{{FieldURI: f.URI, Constraint: constraint, Message: fmt.Sprintf(format, args...)}}, nil
+	}
+
+	if !writable(f.FieldType) {
+		if f.FieldType == model.FieldTypeRelation {
+			return fail("readOnly", "relation fields are set with setElementLinks")
+		}
+		return fail("readOnly", "%s fields are computed", strings.ToLower(string(f.FieldType)))
+	}
+
+	if input == nil {
+		if f.Required {
+			return fail("required", "a value is required")
+		}
+		return nil, nil, nil
+	}
+
+	value, err := convertFieldValue(f, input)
+	if err != nil {
+		return fail("type", "%s", err)
+	}
+
+	constraints, err := models.ParseFieldConstraints(f.FieldType, f.Constraints)
+	if err != nil {
+		return nil, nil, apperror.Validation("invalid constraints of field %s: %w", f.URI, err)
+	}
+
+	if errs := constraints.Check(f.URI, value); len(errs) > 0 {
+		return nil, errs, nil
+	}
+
+	if constraints.Unique != "" {
+		var scope *string
+		if constraints.Unique == models.UniqueSpace {
+			scope = &spaceURI
+		}
+		duplicate, err := s.fieldValue.HasDuplicate(ctx, f.URI, f.FieldType, value, elementURI, scope)
+		if err != nil {
+			return nil, nil, err
+		}
+		if duplicate {
+			if scope != nil {
+				return fail("unique", "another element in space %s has this value", spaceURI)
+			}
+			return fail("unique", "another element has this value")
+		}
+	}
+
+	return value, nil, nil
+}
+
+// writable tells whether values of fields of a type can be written directly.
+func writable(t model.FieldType) bool {
+	switch t {
+	case model.FieldTypeRelation, model.FieldTypeFormula, model.FieldTypeRollup:
+		return false
+	default:
+		return true
+	}
+}
+
+// convertFieldValue converts a value given in the representation field values
+// are returned in to a string for TEXT, URL, EMAIL and SELECT, a float64 for
+// NUMBER, a time.Time for DATE, a bool for BOOLEAN and a []string for
+// MULTI_SELECT fields.
+func convertFieldValue(f *models.FieldDefinition, input any) (any, error) {
+	switch f.FieldType {
+	case model.FieldTypeNumber:
+		switch v := input.(type) {
+		case float64:
+			return v, nil
+		case int64:
+			return float64(v), nil
+		case int:
+			return float64(v), nil
+		case json.Number:
+			return v.Float64()
+		}
+		return nil, fmt.Errorf("must be a number")
+	case model.FieldTypeBoolean:
+		if v, ok := input.(bool); ok {
+			return v, nil
+		}
+		return nil, fmt.Errorf("must be a boolean")
+	case model.FieldTypeMultiSelect:
+		items, ok := input.([]any)
+		if !ok {
+			return nil, fmt.Errorf("must be a list of options")
+		}
+		options := make([]string, len(items))
+		for i, item := range items {
+			option, err := selectOption(f, item)
+			if err != nil {
+				return nil, err
+			}
+			options[i] = option
+		}
+		return options, nil
+	}
+
+	v, ok := input.(string)
+	if !ok {
+		return nil, fmt.Errorf("must be a string")
+	}
+
+	switch f.FieldType {
+	case model.FieldTypeDate:
+		t, err := model.ParseDateTime(v)
+		if err != nil {
+			return nil, fmt.Errorf("must be a date: %w", err)
+		}
+		return t, nil
+	case model.FieldTypeSelect:
+		return selectOption(f, v)
+	case model.FieldTypeURL:
+		if u, err := url.Parse(v); err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("must be an absolute URL")
+		}
+	case model.FieldTypeEmail:
+		if addr, err := mail.ParseAddress(v); err != nil || addr.Address != v {
+			return nil, fmt.Errorf("must be an email address")
+		}
+	}
+	return v, nil
+}
+
+// selectOption checks that a value is one of the options of a SELECT or
+// MULTI_SELECT field.
+func selectOption(f *models.FieldDefinition, value any) (string, error) {
+	options, err := models.ParseSelectOptions(f.Options)
+	if err != nil {
+		return "", fmt.Errorf("field has invalid options: %w", err)
+	}
+
+	option, ok := value.(string)
+	if !ok || !slices.Contains(options, option) {
+		return "", fmt.Errorf("must be one of %s", strings.Join(options, ", "))
+	}
+	return option, nil
+}
+
+// newFieldErrors reports rejected field values as a VALIDATION error, listed
+// in its fieldErrors extension.
+func newFieldErrors(errs []model.FieldError) error {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.FieldURI + ": " + e.Message
+	}
+	return apperror.Validation("invalid field values: %s", strings.Join(msgs, "; ")).WithExtension("fieldErrors", errs)
+}
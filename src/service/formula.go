@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
+	"github.com/bamdadam/backend/src/formula"
+	models "github.com/bamdadam/backend/src/model"
+	"github.com/bamdadam/backend/src/repository"
+)
+
+// typeFormulas are the compiled formula fields of a type.
+type typeFormulas struct {
+	def *models.TypeDefinition
+	set *formula.Set
+
+	mu sync.Mutex
+	// fields holds the formula fields loaded so far, by URI.
+	fields map[string]*model.Field
+}
+
+type formulaCacheKey struct{}
+
+// formulaCache holds the formulas compiled during a request.
+type formulaCache struct {
+	mu    sync.Mutex
+	types map[string]*typeFormulas
+}
+
+// WithFormulaCache returns a context in which the formulas of each type are
+// compiled once, for requests reading many elements of a type. Types and
+// fields changing during the request are not picked up.
+func WithFormulaCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, formulaCacheKey{}, &formulaCache{types: make(map[string]*typeFormulas)})
+}
+
+// formulaResultType returns the type of the values a FORMULA field computes,
+// nil if its formula is invalid.
+func (s *ElementService) formulaResultType(ctx context.Context, field *model.Field) (*model.FieldType, error) {
+	formulas, err := s.formulas(ctx, field.Type.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	t, ok := formulas.set.ResultType(field.URI)
+	if !ok {
+		return nil, nil
+	}
+	return &t, nil
+}
+
+// formulas compiles the formula fields of a type, once per request in a
+// context of WithFormulaCache. Invalid formulas, including formulas
+// referencing each other in a cycle, are left out and reported as VALIDATION
+// errors of the request.
+func (s *ElementService) formulas(ctx context.Context, typeURI string) (*typeFormulas, error) {
+	cache, _ := ctx.Value(formulaCacheKey{}).(*formulaCache)
+	if cache != nil {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		if formulas, ok := cache.types[typeURI]; ok {
+			return formulas, nil
+		}
+	}
+
+	def, err := s.typeRepo.GetDefinition(ctx, typeURI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get type definition: %w", err)
+	}
+
+	formulas := &typeFormulas{
+		def:    def,
+		set:    formula.CompileType(def.Fields),
+		fields: make(map[string]*model.Field),
+	}
+	if cache == nil {
+		return formulas, nil
+	}
+
+	// errors are reported with the first read of the type only
+	cache.types[typeURI] = formulas
+	if graphql.HasOperationContext(ctx) {
+		for _, f := range def.Fields {
+			if err := formulas.set.Err(f.URI); err != nil {
+				graphql.AddError(ctx, apperror.Validation("type %s: %w", typeURI, err).WithExtension("field", f.URI))
+			}
+		}
+	}
+	return formulas, nil
+}
+
+// field returns a formula field, loading it on first use.
+func (f *typeFormulas) field(ctx context.Context, repo repository.FieldRepository, uri string) (*model.Field, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if field, ok := f.fields[uri]; ok {
+		return field, nil
+	}
+
+	field, err := repo.GetByURI(ctx, uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get formula field: %w", err)
+	}
+	f.fields[uri] = field
+	return field, nil
+}
+
+// computeFormulas appends the values of the formula fields of the element's
+// type to its field values. Formula values are computed on every read and
+// never stored, the values of invalid formulas are left out.
+func (s *ElementService) computeFormulas(ctx context.Context, elem *model.Element, typeURI string) error {
+	formulas, err := s.formulas(ctx, typeURI)
+	if err != nil {
+		return err
+	}
+	if formulas.set.Empty() {
+		return nil
+	}
+
+	values := make(map[string]any, len(elem.FieldValues))
+	for _, fv := range elem.FieldValues {
+		values[fv.Field.URI] = fv.Value
+	}
+	results := formulas.set.Eval(values, time.Now())
+
+	for _, f := range formulas.def.Fields {
+		value, ok := results[f.URI]
+		if !ok {
+			continue
+		}
+
+		field, err := formulas.field(ctx, s.field, f.URI)
+		if err != nil {
+			return err
+		}
+
+		elem.FieldValues = append(elem.FieldValues, &model.ElementFieldValue{
+			URI:   elem.URI + "#" + f.URI,
+			Value: value,
+			Field: field,
+		})
+	}
+	return nil
+}
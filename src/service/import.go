@@ -0,0 +1,324 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
+	models "github.com/bamdadam/backend/src/model"
+	"github.com/bamdadam/backend/src/repository"
+)
+
+// MaxImportRows is the maximum number of data rows an import accepts.
+var MaxImportRows = 10000
+
+// DefaultMultiSelectDelimiter separates the options of MULTI_SELECT values in
+// imported files.
+const DefaultMultiSelectDelimiter = ";"
+
+// titleColumn is the column holding the titles of imported elements.
+const titleColumn = "title"
+
+// importDateLayouts are the date formats accepted in imported files besides
+// the DateTime ones.
+var importDateLayouts = []string{time.DateTime, "2006-01-02T15:04:05", time.DateOnly}
+
+// importColumn is where the values of a column of an imported file go, the
+// title when field is nil.
+type importColumn struct {
+	index int
+	field *models.FieldDefinition
+}
+
+// Import creates an element of a type in a space for each row of a CSV file.
+// All rows are imported in a single transaction, each in a savepoint so that
+// rows failing validation are skipped and reported without affecting the
+// others. On dry runs the transaction is rolled back. It requires write
+// permission on the space.
+func (s *ElementService) Import(ctx context.Context, input model.ImportElementsInput) (*model.ImportElementsResult, error) {
+	return s.importElements(ctx, input, nil)
+}
+
+// importElements is Import, reporting the processed rows to progress when it
+// is set.
+func (s *ElementService) importElements(ctx context.Context, input model.ImportElementsInput, progress JobProgress) (*model.ImportElementsResult, error) {
+	if err := s.checkWritable(ctx, input.SpaceURI); err != nil {
+		return nil, err
+	}
+
+	userSpaces, err := s.getUserSpaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import elements: %w", err)
+	}
+
+	actor, err := s.getUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	def, err := s.spaceTypeDefinition(ctx, input.TypeURI, input.SpaceURI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import elements: %w", err)
+	}
+
+	delimiter := DefaultMultiSelectDelimiter
+	if input.MultiSelectDelimiter != nil && *input.MultiSelectDelimiter != "" {
+		delimiter = *input.MultiSelectDelimiter
+	}
+
+	r := csv.NewReader(input.File.File)
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, apperror.Validation("the file is empty")
+	}
+	if err != nil {
+		return nil, apperror.Validation("invalid CSV: %v", err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	columns, err := importColumns(def, header, input.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	// rows are read up to the limit, not to the end of oversized files
+	var rows [][]string
+	for {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, apperror.Validation("invalid CSV: %v", err)
+		}
+		if len(rows) == MaxImportRows {
+			return nil, apperror.Validation("at most %d rows can be imported at once", MaxImportRows)
+		}
+		rows = append(rows, row)
+	}
+
+	result := &model.ImportElementsResult{RowCount: int32(len(rows)), Errors: []*model.ImportRowError{}}
+	dryRun := input.DryRun != nil && *input.DryRun
+	now := time.Now()
+
+	err = repository.RunInTx(ctx, s.db, func(ctx context.Context) error {
+		for i, row := range rows {
+			if progress != nil {
+				if err := progress(i, len(rows)); err != nil {
+					return err
+				}
+			}
+			create, err := importRow(input, columns, row, delimiter)
+			if err == nil {
+				err = repository.RunInSavepoint(ctx, s.db, func(ctx context.Context) error {
+					_, err := s.createInTx(ctx, newElementURI(), create, def, actor, userSpaces, now)
+					return err
+				})
+			}
+			if err != nil {
+				if !isRowError(err) {
+					return err
+				}
+				result.Errors = append(result.Errors, &model.ImportRowError{Row: int32(i + 1), Error: s.bulkError(ctx, err)})
+				continue
+			}
+			result.ImportedCount++
+		}
+		if progress != nil {
+			if err := progress(len(rows), len(rows)); err != nil {
+				return err
+			}
+		}
+
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, fmt.Errorf("failed to import elements: %w", err)
+	}
+
+	if !dryRun {
+		s.outbox.Notify()
+	}
+	return result, nil
+}
+
+// isRowError tells whether an error is caused by the content of a row, and
+// not by the import as a whole.
+func isRowError(err error) bool {
+	switch apperror.CodeOf(err) {
+	case apperror.CodeValidation, apperror.CodeConflict, apperror.CodeNotFound:
+		return true
+	}
+	return false
+}
+
+// importColumns maps the columns of header to the fields of def, explicitly
+// through mapping or by the name of the fields. Columns that map to no field
+// and fields with several columns are rejected.
+func importColumns(def *models.TypeDefinition, header []string, mapping []*model.ImportColumnMapping) ([]importColumn, error) {
+	fields := make(map[string]*models.FieldDefinition, len(def.Fields))
+	for _, f := range def.Fields {
+		fields[f.URI] = f
+	}
+
+	explicit := make(map[string]*model.ImportColumnMapping, len(mapping))
+	for _, m := range mapping {
+		if !slices.Contains(header, m.Column) {
+			return nil, apperror.Validation("mapped column %q is not in the file", m.Column)
+		}
+		explicit[m.Column] = m
+	}
+
+	var columns []importColumn
+	hasTitle := false
+	used := make(map[string]string)
+	for i, name := range header {
+		var f *models.FieldDefinition
+		if m, ok := explicit[name]; ok {
+			if m.FieldURI == nil {
+				continue
+			}
+			if f = fields[*m.FieldURI]; f == nil {
+				return nil, apperror.Validation("column %q is mapped to %s, which is not a field of type %s", name, *m.FieldURI, def.URI)
+			}
+		} else if strings.EqualFold(strings.TrimSpace(name), titleColumn) {
+			if hasTitle {
+				return nil, apperror.Validation("more than one title column")
+			}
+			hasTitle = true
+			columns = append(columns, importColumn{index: i})
+			continue
+		} else {
+			for _, candidate := range def.Fields {
+				if strings.EqualFold(candidate.Name, strings.TrimSpace(name)) {
+					f = candidate
+					break
+				}
+			}
+			if f == nil {
+				return nil, apperror.Validation("column %q matches no field of type %s", name, def.URI)
+			}
+		}
+
+		if !writable(f.FieldType) {
+			return nil, apperror.Validation("column %q maps to %s, which is read-only", name, f.URI)
+		}
+		if other, ok := used[f.URI]; ok {
+			return nil, apperror.Validation("columns %q and %q both map to %s", other, name, f.URI)
+		}
+		used[f.URI] = name
+		columns = append(columns, importColumn{index: i, field: f})
+	}
+
+	if !hasTitle {
+		return nil, apperror.Validation("the file has no %s column", titleColumn)
+	}
+	return columns, nil
+}
+
+// importRow converts a row of an imported file to the input creating its
+// element. Empty cells are left out so that the field gets its default.
+func importRow(input model.ImportElementsInput, columns []importColumn, row []string, delimiter string) (model.CreateElementInput, error) {
+	create := model.CreateElementInput{TypeURI: input.TypeURI, SpaceURI: input.SpaceURI}
+
+	if slices.ContainsFunc(row, func(cell string) bool { return !utf8.ValidString(cell) }) {
+		return create, apperror.Validation("the row is not valid UTF-8, the file has to be saved as UTF-8")
+	}
+
+	var errs []model.FieldError
+	for _, c := range columns {
+		cell := strings.TrimSpace(row[c.index])
+		if c.field == nil {
+			create.Title = cell
+			continue
+		}
+		if cell == "" {
+			continue
+		}
+
+		value, err := importValue(c.field, cell, delimiter)
+		if err != nil {
+			errs = append(errs, model.FieldError{FieldURI: c.field.URI, Constraint: "type", Message: err.Error()})
+			continue
+		}
+		create.FieldValues = append(create.FieldValues, &model.FieldValueInput{FieldURI: c.field.URI, Value: value})
+	}
+
+	if create.Title == "" {
+		return create, apperror.Validation("the title is empty")
+	}
+	if len(errs) > 0 {
+		return create, newFieldErrors(errs)
+	}
+	return create, nil
+}
+
+// importValue converts the text of a cell to the representation field values
+// are given in, leaving the checks that don't depend on the format of the file
+// to setFieldValues. SELECT options are matched regardless of case.
+func importValue(f *models.FieldDefinition, cell, delimiter string) (any, error) {
+	switch f.FieldType {
+	case model.FieldTypeNumber:
+		n, err := strconv.ParseFloat(cell, 64)
+		if err != nil {
+			return nil, fmt.Errorf("must be a number")
+		}
+		return n, nil
+	case model.FieldTypeBoolean:
+		switch strings.ToLower(cell) {
+		case "true", "yes", "y", "1":
+			return true, nil
+		case "false", "no", "n", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("must be a boolean")
+	case model.FieldTypeDate:
+		if _, err := model.ParseDateTime(cell); err == nil {
+			return cell, nil
+		}
+		for _, layout := range importDateLayouts {
+			if t, err := time.Parse(layout, cell); err == nil {
+				return t.UTC().Format(model.DateTimeLayout), nil
+			}
+		}
+		return nil, fmt.Errorf("must be a date")
+	case model.FieldTypeSelect:
+		return importOption(f, cell), nil
+	case model.FieldTypeMultiSelect:
+		var options []any
+		for _, item := range strings.Split(cell, delimiter) {
+			if item = strings.TrimSpace(item); item != "" {
+				options = append(options, importOption(f, item))
+			}
+		}
+		return options, nil
+	}
+	return cell, nil
+}
+
+// importOption returns the option of a SELECT or MULTI_SELECT field equal to
+// value regardless of case, or value when there is none.
+func importOption(f *models.FieldDefinition, value string) string {
+	options, _ := models.ParseSelectOptions(f.Options)
+	for _, option := range options {
+		if strings.EqualFold(option, value) {
+			return option
+		}
+	}
+	return value
+}
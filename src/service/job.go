@@ -0,0 +1,434 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
+	models "github.com/bamdadam/backend/src/model"
+	"github.com/bamdadam/backend/src/repository"
+)
+
+var (
+	// JobMaxAttempts is how many times a job is run before it is failed.
+	JobMaxAttempts int32 = 3
+	// JobLease is how long a job stays claimed by a worker which stopped
+	// renewing its claim, e.g. because its replica died.
+	JobLease = 30 * time.Second
+	// JobRetryBackoff is the delay before the first retry of a failed job,
+	// doubled for every further attempt up to JobMaxRetryBackoff.
+	JobRetryBackoff    = 5 * time.Second
+	JobMaxRetryBackoff = 5 * time.Minute
+	// JobPollInterval is how often idle workers look for queued jobs and
+	// jobProgress subscriptions for changes.
+	JobPollInterval = time.Second
+)
+
+// errJobCancelled is the cause of the context of jobs asked to cancel.
+var errJobCancelled = errors.New("job cancelled")
+
+// JobProgress reports that done out of total items of a job have been
+// processed. It returns an error once the job has been cancelled, which the
+// job should return.
+type JobProgress func(done, total int) error
+
+// JobHandler runs a job of one kind with the payload it was queued with,
+// returning a result to be marshaled to JSON. Handlers run in the context of
+// the user who queued the job, and may be run again when they fail with an
+// internal error, so their writes should be transactional.
+type JobHandler func(ctx context.Context, payload []byte, progress JobProgress) (any, error)
+
+// JobService queues background jobs and runs them.
+type JobService struct {
+	*UserService
+
+	jobs     repository.JobRepository
+	handlers map[string]JobHandler
+}
+
+func NewJobService(us *UserService, jobRepo repository.JobRepository) *JobService {
+	return &JobService{
+		UserService: us,
+		jobs:        jobRepo,
+		handlers:    make(map[string]JobHandler),
+	}
+}
+
+// Register sets the handler of the jobs of kind. Handlers have to be
+// registered before Run is called.
+func (s *JobService) Register(kind string, handler JobHandler) {
+	s.handlers[kind] = handler
+}
+
+// Enqueue queues a job of kind for the requesting user.
+func (s *JobService) Enqueue(ctx context.Context, kind string, payload any) (*model.Job, error) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := s.handlers[kind]; !ok {
+		return nil, apperror.Internal("unknown job kind: %s", kind)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal job payload: %w", err)
+	}
+
+	now := time.Now().UnixMilli()
+	job := &models.Job{
+		ID:           newJobID(),
+		Kind:         kind,
+		Payload:      data,
+		UserURI:      userID,
+		Status:       models.JobQueued,
+		MaxAttempts:  JobMaxAttempts,
+		RunAfter:     now,
+		CreationDate: now,
+		UpdatedDate:  now,
+	}
+	if err = s.jobs.Create(ctx, job); err != nil {
+		return nil, err
+	}
+	return jobModel(job), nil
+}
+
+// Get returns a job of the requesting user.
+func (s *JobService) Get(ctx context.Context, id string) (*model.Job, error) {
+	job, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return jobModel(job), nil
+}
+
+func (s *JobService) get(ctx context.Context, id string) (*models.Job, error) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := s.jobs.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.UserURI != userID {
+		return nil, apperror.NotFound("job not found: %s", id)
+	}
+	return job, nil
+}
+
+// Cancel cancels a queued job of the requesting user. Running jobs are asked
+// to stop, which they do the next time they report progress or their worker
+// renews its claim.
+func (s *JobService) Cancel(ctx context.Context, id string) (*model.Job, error) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := s.jobs.Cancel(ctx, id, userID, time.Now().UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	return jobModel(job), nil
+}
+
+// Subscribe sends a job of the requesting user whenever it changes, until it
+// has ended. Jobs may run on any replica, so they are polled from the
+// database.
+func (s *JobService) Subscribe(ctx context.Context, id string) (<-chan *model.Job, error) {
+	job, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan *model.Job, 1)
+	ch <- jobModel(job)
+
+	go func() {
+		defer close(ch)
+
+		ticker := time.NewTicker(JobPollInterval)
+		defer ticker.Stop()
+
+		for !jobEnded(job.Status) {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			next, err := s.jobs.GetByID(ctx, id)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Failed to poll job %s: %v", id, err)
+				}
+				return
+			}
+			if next.UpdatedDate == job.UpdatedDate && next.Status == job.Status {
+				continue
+			}
+			job = next
+
+			select {
+			case ch <- jobModel(job):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// Run runs queued jobs with the given number of workers until ctx is
+// cancelled. Workers then stop claiming jobs and Run waits up to drainTimeout
+// for the running ones to end, after which they are interrupted and put back
+// in the queue for another replica to pick up.
+func (s *JobService) Run(ctx context.Context, workers int, drainTimeout time.Duration) {
+	runCtx, stop := context.WithCancel(context.WithoutCancel(ctx))
+	defer stop()
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work(ctx, runCtx)
+		}()
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return
+	case <-ctx.Done():
+	}
+
+	timer := time.NewTimer(drainTimeout)
+	defer timer.Stop()
+	select {
+	case <-drained:
+	case <-timer.C:
+		log.Printf("Interrupting jobs still running after %s", drainTimeout)
+		stop()
+		<-drained
+	}
+}
+
+// work claims and runs jobs one at a time until ctx is cancelled. Jobs run
+// with runCtx, which outlives ctx while jobs are drained.
+func (s *JobService) work(ctx, runCtx context.Context) {
+	for ctx.Err() == nil {
+		now := time.Now()
+		job, err := s.jobs.Claim(ctx, now.UnixMilli(), now.Add(JobLease).UnixMilli())
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to claim job: %v", err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(JobPollInterval):
+			}
+			continue
+		}
+		s.runJob(runCtx, job)
+	}
+}
+
+// runJob runs an attempt of a claimed job and records its outcome.
+func (s *JobService) runJob(runCtx context.Context, job *models.Job) {
+	// bookkeeping happens even when runCtx has been cancelled
+	bgCtx := context.WithoutCancel(runCtx)
+
+	if job.Attempts > job.MaxAttempts {
+		// claimed again after the worker of its last attempt was lost
+		s.finish(bgCtx, job, models.JobFailed, nil, "the worker running the job was lost")
+		return
+	}
+
+	handler, ok := s.handlers[job.Kind]
+	if !ok {
+		s.finish(bgCtx, job, models.JobFailed, nil, "unknown job kind: "+job.Kind)
+		return
+	}
+
+	ctx, cancel := context.WithCancelCause(runCtx)
+	defer cancel(nil)
+	ctx = context.WithValue(ctx, models.UserIDKey, job.UserURI)
+	ctx = context.WithValue(ctx, models.RequestIDKey, job.ID)
+
+	// progress holds the last progress reported by the job, written to the
+	// database with the heartbeats
+	var mu sync.Mutex
+	var done int32
+	var total *int32
+	heartbeat := func() {
+		mu.Lock()
+		progress, count := done, total
+		mu.Unlock()
+
+		now := time.Now()
+		cancelRequested, err := s.jobs.Heartbeat(bgCtx, job.ID, job.Attempts, progress, count, now.Add(JobLease).UnixMilli(), now.UnixMilli())
+		if err != nil {
+			log.Printf("Failed to update job %s: %v", job.ID, err)
+			return
+		}
+		if cancelRequested {
+			cancel(errJobCancelled)
+		}
+	}
+
+	stopHeartbeat := make(chan struct{})
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		ticker := time.NewTicker(min(JobLease/3, JobPollInterval))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopHeartbeat:
+				return
+			case <-ticker.C:
+				heartbeat()
+			}
+		}
+	}()
+
+	progress := func(d, t int) error {
+		count := int32(t)
+		mu.Lock()
+		done, total = int32(d), &count
+		mu.Unlock()
+		if context.Cause(ctx) == errJobCancelled {
+			return errJobCancelled
+		}
+		return ctx.Err()
+	}
+
+	result, err := runHandler(ctx, handler, job.Payload, progress)
+	close(stopHeartbeat)
+	<-heartbeatDone
+	heartbeat()
+
+	switch {
+	case err == nil:
+		data, err := json.Marshal(result)
+		if err != nil {
+			s.finish(bgCtx, job, models.JobFailed, nil, fmt.Sprintf("failed to marshal job result: %v", err))
+			return
+		}
+		s.finish(bgCtx, job, models.JobSucceeded, data, "")
+	case context.Cause(ctx) == errJobCancelled:
+		s.finish(bgCtx, job, models.JobCancelled, nil, "cancelled")
+	case runCtx.Err() != nil:
+		if err := s.jobs.Release(bgCtx, job.ID, job.Attempts, time.Now().UnixMilli()); err != nil {
+			log.Printf("Failed to release job %s: %v", job.ID, err)
+		}
+	default:
+		msg := jobError(job, err)
+		if !jobRetryable(err) || job.Attempts >= job.MaxAttempts {
+			s.finish(bgCtx, job, models.JobFailed, nil, msg)
+			return
+		}
+		backoff := min(JobRetryBackoff<<(job.Attempts-1), JobMaxRetryBackoff)
+		now := time.Now()
+		if err := s.jobs.Retry(bgCtx, job.ID, job.Attempts, msg, now.Add(backoff).UnixMilli(), now.UnixMilli()); err != nil {
+			log.Printf("Failed to retry job %s: %v", job.ID, err)
+		}
+	}
+}
+
+// runHandler runs a job handler, turning panics into errors so that a broken
+// job doesn't take down its worker.
+func runHandler(ctx context.Context, handler JobHandler, payload []byte, progress JobProgress) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(ctx, payload, progress)
+}
+
+func (s *JobService) finish(ctx context.Context, job *models.Job, status string, result []byte, msg string) {
+	var errMsg *string
+	if msg != "" {
+		errMsg = &msg
+	}
+	if err := s.jobs.Finish(ctx, job.ID, job.Attempts, status, result, errMsg, time.Now().UnixMilli()); err != nil {
+		log.Printf("Failed to finish job %s: %v", job.ID, err)
+	}
+}
+
+// jobRetryable tells whether a failed job may succeed when run again. Errors
+// caused by the job itself, such as validation errors, are final.
+func jobRetryable(err error) bool {
+	var appErr *apperror.Error
+	return !errors.As(err, &appErr) || appErr.Code == apperror.CodeInternal
+}
+
+// jobError is the error of a job as shown to its user. Internal errors are
+// logged and replaced by a generic message, as the error presenter does for
+// requests.
+func jobError(job *models.Job, err error) string {
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) || appErr.Code == apperror.CodeInternal {
+		log.Printf("job %s: internal error in attempt %d: %v", job.ID, job.Attempts, err)
+		return "internal server error"
+	}
+	return appErr.Message
+}
+
+func jobEnded(status string) bool {
+	return status == models.JobSucceeded || status == models.JobFailed || status == models.JobCancelled
+}
+
+func newJobID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "job:" + hex.EncodeToString(b)
+}
+
+func jobModel(job *models.Job) *model.Job {
+	m := &model.Job{
+		ID:          job.ID,
+		Kind:        job.Kind,
+		Status:      model.JobStatus(strings.ToUpper(job.Status)),
+		Progress:    job.Progress,
+		Total:       job.Total,
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		Error:       job.Error,
+		CreatedAt:   time.UnixMilli(job.CreationDate),
+	}
+	if job.Result != nil {
+		var result any
+		if err := json.Unmarshal(job.Result, &result); err == nil {
+			m.Result = result
+		}
+	}
+	if job.StartedDate != nil {
+		startedAt := time.UnixMilli(*job.StartedDate)
+		m.StartedAt = &startedAt
+	}
+	if job.FinishedDate != nil {
+		finishedAt := time.UnixMilli(*job.FinishedDate)
+		m.FinishedAt = &finishedAt
+	}
+	return m
+}
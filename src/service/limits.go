@@ -0,0 +1,43 @@
+package service
+
+// Limits bound the work of the requests served by the services, zero values
+// keeping the defaults.
+type Limits struct {
+	// PageSize is the page size of lists when clients don't ask for one.
+	PageSize int32
+	// MaxPageSize is the largest page size clients can ask for, larger ones
+	// are capped.
+	MaxPageSize int32
+	// MaxUpdateWhereRows is the maximum number of elements UpdateWhere
+	// updates at once. Filters matching more are rejected.
+	MaxUpdateWhereRows int
+}
+
+const (
+	defaultPageSize           int32 = 20
+	defaultMaxPageSize        int32 = 100
+	defaultMaxUpdateWhereRows       = 10000
+)
+
+// pageSize returns the page size for a requested limit, 0 or less asking for
+// the default one.
+func (l Limits) pageSize(limit int32) int32 {
+	maxSize := l.MaxPageSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxPageSize
+	}
+	if limit <= 0 {
+		limit = l.PageSize
+	}
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	return min(limit, maxSize)
+}
+
+func (l Limits) maxUpdateWhereRows() int {
+	if l.MaxUpdateWhereRows > 0 {
+		return l.MaxUpdateWhereRows
+	}
+	return defaultMaxUpdateWhereRows
+}
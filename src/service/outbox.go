@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bamdadam/backend/graph/model"
+	models "github.com/bamdadam/backend/src/model"
+	"github.com/bamdadam/backend/src/pubsub"
+	"github.com/bamdadam/backend/src/repository"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	// OutboxPollInterval is how often relays look for events committed by
+	// other replicas.
+	OutboxPollInterval = 250 * time.Millisecond
+	// OutboxRetention is how long events are kept once handed to webhooks.
+	OutboxRetention = 24 * time.Hour
+)
+
+// outboxBatchSize is the number of events handed to webhooks per transaction.
+const outboxBatchSize = 100
+
+// elementEvent is the payload of the outbox events of element changes.
+type elementEvent struct {
+	Action string               `json:"action"`
+	Change *model.ElementChange `json:"change"`
+}
+
+// OutboxService records events in the outbox within the transactions causing
+// them, and relays them to subscribers and webhooks once committed. Events are
+// relayed at least once, each with an ID consumers can deduplicate them by.
+type OutboxService struct {
+	db       *pgxpool.Pool
+	outbox   repository.OutboxRepository
+	webhooks *WebhookService
+	pubsub   *pubsub.ElementPubSub
+
+	// dispatch and tail wake up the relay loops when events are committed by
+	// this replica
+	dispatch chan struct{}
+	tail     chan struct{}
+
+	// tailing runs while there are subscribers
+	mu          sync.Mutex
+	subscribers int
+	stopTail    context.CancelFunc
+}
+
+func NewOutboxService(db *pgxpool.Pool, outboxRepo repository.OutboxRepository, webhooks *WebhookService, pubsub *pubsub.ElementPubSub) *OutboxService {
+	return &OutboxService{
+		db:       db,
+		outbox:   outboxRepo,
+		webhooks: webhooks,
+		pubsub:   pubsub,
+		dispatch: make(chan struct{}, 1),
+		tail:     make(chan struct{}, 1),
+	}
+}
+
+// AppendElementChange records a change of an element made by action in the
+// transaction carried by ctx, setting the ID of the change to that of the
+// event.
+func (s *OutboxService) AppendElementChange(ctx context.Context, action string, change *model.ElementChange) error {
+	change.ID = newEventID()
+
+	payload, err := json.Marshal(elementEvent{Action: action, Change: change})
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox event: %w", err)
+	}
+
+	return s.outbox.Append(ctx, &models.OutboxEvent{
+		EventID:      change.ID,
+		Topic:        models.OutboxElementChanged,
+		AggregateURI: change.Element.URI,
+		Payload:      payload,
+		CreationDate: time.Now().UnixMilli(),
+	})
+}
+
+// Notify tells the relays of this replica that events have been committed, so
+// that they don't wait for their next poll.
+func (s *OutboxService) Notify() {
+	for _, ch := range []chan struct{}{s.dispatch, s.tail} {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel receiving the changes of an element committed
+// from now on, by any replica.
+func (s *OutboxService) Subscribe(ctx context.Context, uri string) (chan *model.ElementChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.subscribers == 0 {
+		snapshot, err := s.outbox.Snapshot(ctx)
+		if err != nil {
+			return nil, err
+		}
+		tailCtx, cancel := context.WithCancel(context.Background())
+		s.stopTail = cancel
+		go s.runTail(tailCtx, snapshot)
+	}
+	s.subscribers++
+	return s.pubsub.Subscribe(uri), nil
+}
+
+func (s *OutboxService) Unsubscribe(uri string, ch chan *model.ElementChange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pubsub.Unsubscribe(uri, ch)
+	s.subscribers--
+	if s.subscribers == 0 {
+		s.stopTail()
+	}
+}
+
+// runTail publishes the events committed since snapshot was taken to the
+// subscribers of this replica until ctx is cancelled.
+func (s *OutboxService) runTail(ctx context.Context, snapshot string) {
+	ticker := time.NewTicker(OutboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.tail:
+		}
+
+		uris := s.pubsub.URIs()
+		if len(uris) == 0 {
+			continue
+		}
+
+		events, next, err := s.outbox.ListCommittedSince(ctx, snapshot, uris)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Failed to read outbox: %v", err)
+			}
+			continue
+		}
+		snapshot = next
+
+		for _, e := range events {
+			var event elementEvent
+			if err := json.Unmarshal(e.Payload, &event); err != nil {
+				log.Printf("Failed to decode outbox event %s: %v", e.EventID, err)
+				continue
+			}
+			s.pubsub.Publish(event.Change)
+		}
+	}
+}
+
+// Run hands committed events to webhooks until ctx is cancelled, along with
+// the relays of the other replicas, and purges the events past
+// OutboxRetention.
+func (s *OutboxService) Run(ctx context.Context) {
+	ticker := time.NewTicker(OutboxPollInterval)
+	defer ticker.Stop()
+
+	lastPurge := time.Now()
+	for {
+		for {
+			n, err := s.dispatchBatch(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Failed to relay outbox events: %v", err)
+				}
+				break
+			}
+			if n < outboxBatchSize {
+				break
+			}
+		}
+
+		if time.Since(lastPurge) > time.Hour {
+			lastPurge = time.Now()
+			deleted, err := s.outbox.DeleteDispatchedBefore(ctx, time.Now().Add(-OutboxRetention).UnixMilli())
+			if err != nil {
+				log.Printf("Outbox retention failed: %v", err)
+			} else if deleted > 0 {
+				log.Printf("Outbox retention purged %d events", deleted)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.dispatch:
+		}
+	}
+}
+
+// dispatchBatch queues the webhook deliveries of a batch of events in a
+// single transaction, so that events are marked dispatched exactly when their
+// deliveries are queued. It returns the number of events dispatched.
+func (s *OutboxService) dispatchBatch(ctx context.Context) (int, error) {
+	var n int
+	err := repository.RunInTx(ctx, s.db, func(ctx context.Context) error {
+		events, err := s.outbox.ClaimUndispatched(ctx, outboxBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		ids := make([]int64, len(events))
+		for i, e := range events {
+			ids[i] = e.ID
+
+			var event elementEvent
+			if err := json.Unmarshal(e.Payload, &event); err != nil {
+				log.Printf("Failed to decode outbox event %s: %v", e.EventID, err)
+				continue
+			}
+			if err := s.webhooks.Enqueue(ctx, e.EventID, event.Action, event.Change); err != nil {
+				return err
+			}
+		}
+
+		n = len(events)
+		return s.outbox.MarkDispatched(ctx, ids, time.Now().UnixMilli())
+	})
+	return n, err
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "event:" + hex.EncodeToString(b)
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"context"
+
+	models "github.com/bamdadam/backend/src/model"
+)
+
+// refreshRollups recomputes the rollup values depending on an element after
+// it was written: the element's own rollups and the rollups of the elements
+// it links to or is linked from, including the ones it linked to before the
+// write. Only the affected elements are recomputed. linksBefore has to hold
+// the links of every space, the rollups of elements the writer can't see
+// depend on the element as well.
+func (s *ElementService) refreshRollups(ctx context.Context, uri, typeURI string, linksBefore []*models.ElementLink, updatedDate int64) error {
+	rollups, err := s.rollup.ListAffected(ctx, typeURI)
+	if err != nil {
+		return err
+	}
+
+	for _, rollup := range rollups {
+		var previous []string
+		for _, link := range linksBefore {
+			if link.FieldURI == rollup.RelationFieldURI {
+				previous = append(previous, link.ElementURI)
+			}
+		}
+
+		if err = s.rollup.Refresh(ctx, rollup, uri, previous, updatedDate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
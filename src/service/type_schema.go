@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/bamdadam/backend/src/apperror"
+	models "github.com/bamdadam/backend/src/model"
+	"github.com/bamdadam/backend/src/repository"
+	"github.com/bamdadam/backend/src/typeschema"
+)
+
+// TypeSchemaService generates the per-tenant GraphQL schemas derived from the
+// types and fields of a tenant.
+type TypeSchemaService struct {
+	userTenant repository.UserTenantsRepository
+	typeRepo   repository.TypeRepository
+
+	mu sync.Mutex
+	// schemas holds the last schema built for each tenant.
+	schemas map[string]*cachedTypeSchema
+}
+
+// cachedTypeSchema is a schema along with the definitions it was built from.
+type cachedTypeSchema struct {
+	defs   []*models.TypeDefinition
+	schema *typeschema.Schema
+}
+
+func NewTypeSchemaService(userTenant repository.UserTenantsRepository, typeRepo repository.TypeRepository) *TypeSchemaService {
+	return &TypeSchemaService{
+		userTenant: userTenant,
+		typeRepo:   typeRepo,
+		schemas:    make(map[string]*cachedTypeSchema),
+	}
+}
+
+// Get returns the schema of a tenant the user is a member of. The definitions
+// of the tenant are loaded on every call and the schema is only rebuilt when
+// they changed, types and fields being edited outside of the API.
+func (s *TypeSchemaService) Get(ctx context.Context, tenantURI string) (*typeschema.Schema, error) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get type schema: %w", err)
+	}
+
+	role, err := s.userTenant.GetRole(ctx, userID, tenantURI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get type schema: %w", err)
+	}
+	if role == "" {
+		return nil, apperror.Forbidden("user %s is not a member of tenant %s", userID, tenantURI)
+	}
+
+	defs, err := s.typeRepo.ListDefinitions(ctx, tenantURI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load type definitions: %w", err)
+	}
+
+	s.mu.Lock()
+	cached := s.schemas[tenantURI]
+	s.mu.Unlock()
+	if cached != nil && reflect.DeepEqual(cached.defs, defs) {
+		return cached.schema, nil
+	}
+
+	schema, err := buildTypeSchema(tenantURI, defs)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.schemas[tenantURI] = &cachedTypeSchema{defs: defs, schema: schema}
+	s.mu.Unlock()
+	return schema, nil
+}
+
+// LoadTypeSchema builds the schema of a tenant without any access checks.
+func LoadTypeSchema(ctx context.Context, typeRepo repository.TypeRepository, tenantURI string) (*typeschema.Schema, error) {
+	defs, err := typeRepo.ListDefinitions(ctx, tenantURI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load type definitions: %w", err)
+	}
+	return buildTypeSchema(tenantURI, defs)
+}
+
+func buildTypeSchema(tenantURI string, defs []*models.TypeDefinition) (*typeschema.Schema, error) {
+	schema, err := typeschema.Build(tenantURI, defs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build type schema: %w", err)
+	}
+	return schema, nil
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
+	models "github.com/bamdadam/backend/src/model"
+	"github.com/bamdadam/backend/src/repository"
+)
+
+// updateWhereSampleSize is the number of updated elements returned by dry runs.
+const updateWhereSampleSize = 10
+
+// errDryRun rolls back the transaction of a dry run.
+var errDryRun = errors.New("dry run")
+
+// UpdateWhere sets the given field values on every element of a type in a
+// space matching filter, in a single transaction. It requires write permission
+// on the space. On dry runs the updates are rolled back and a sample of the
+// updated elements is returned.
+func (s *ElementService) UpdateWhere(ctx context.Context, spaceURI, typeURI string, filter *model.ElementFilter,
+	set []*model.FieldValueInput, dryRun bool) (*model.UpdateElementsWhereResult, error) {
+	return s.updateWhere(ctx, spaceURI, typeURI, filter, set, dryRun, nil)
+}
+
+// updateWhere is UpdateWhere, reporting the updated elements to progress when
+// it is set.
+func (s *ElementService) updateWhere(ctx context.Context, spaceURI, typeURI string, filter *model.ElementFilter,
+	set []*model.FieldValueInput, dryRun bool, progress JobProgress) (*model.UpdateElementsWhereResult, error) {
+	if err := s.checkWritable(ctx, spaceURI); err != nil {
+		return nil, err
+	}
+
+	userSpaces, err := s.getUserSpaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(set) == 0 {
+		return nil, apperror.Validation("no field values to set")
+	}
+
+	maxRows := s.limits.maxUpdateWhereRows()
+	params := models.ListParams{
+		Limit:    int32(maxRows),
+		TypeURI:  &typeURI,
+		SpaceURI: &spaceURI,
+	}
+	if filter != nil {
+		params.FieldValueFilter = filter.FieldValueFilter
+		params.RelationFilter = filter.RelationFilter
+	}
+	if err = s.compileFilters(ctx, &params); err != nil {
+		return nil, err
+	}
+
+	result := &model.UpdateElementsWhereResult{Sample: []*model.Element{}}
+
+	err = repository.RunInTx(ctx, s.db, func(ctx context.Context) error {
+		matches, err := s.elementRepo.List(ctx, params, userSpaces)
+		if err != nil {
+			return err
+		}
+		if len(matches) > maxRows {
+			return apperror.Validation("filter matches more than %d elements", maxRows)
+		}
+
+		for i, match := range matches {
+			input := model.UpdateElementInput{URI: match.Element.URI, FieldValues: set}
+			change, err := s.mutateInTx(ctx, input.URI, AuditActionElementUpdate, nil, s.updateElement(input))
+			if err != nil {
+				return err
+			}
+			if dryRun && len(result.Sample) < updateWhereSampleSize {
+				result.Sample = append(result.Sample, change.Element)
+			}
+			if progress != nil {
+				if err := progress(i+1, len(matches)); err != nil {
+					return err
+				}
+			}
+		}
+		result.AffectedCount = int32(len(matches))
+
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+
+	if !dryRun {
+		s.outbox.Notify()
+	}
+	return result, nil
+}
@@ -3,7 +3,11 @@ package service
 import (
 	"context"
 	"fmt"
+	"slices"
 
+	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
+	models "github.com/bamdadam/backend/src/model"
 	"github.com/bamdadam/backend/src/repository"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -29,7 +33,50 @@ func (s *UserService) getUserSpaces(ctx context.Context) ([]string, error) {
 	}
 
 	if len(us) == 0 {
-		return nil, fmt.Errorf("user has no permissions")
+		return nil, apperror.Forbidden("user has no permissions")
 	}
 	return us, nil
 }
+
+// getUser loads the user making the request.
+func (s *UserService) getUser(ctx context.Context) (*model.User, error) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return s.user.GetByURI(ctx, userID)
+}
+
+// canWrite tells whether the user making the request has been granted write
+// or admin permission on a space.
+func (s *UserService) canWrite(ctx context.Context, spaceURI string) (bool, error) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get user: %w", err)
+	}
+	spaces, err := s.userSpace.GetByUserAndVerbs(ctx, userID, []string{models.VerbWrite, models.VerbAdmin})
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(spaces, spaceURI), nil
+}
+
+// checkWritable fails unless the requesting user may write to a space.
+func (s *UserService) checkWritable(ctx context.Context, spaceURI string) error {
+	userSpaces, err := s.getUserSpaces(ctx)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(userSpaces, spaceURI) {
+		return apperror.NotFound("space %s not found", spaceURI)
+	}
+
+	writable, err := s.canWrite(ctx, spaceURI)
+	if err != nil {
+		return err
+	}
+	if !writable {
+		return apperror.Forbidden("no write permission on space %s", spaceURI)
+	}
+	return nil
+}
\ No newline at end of file
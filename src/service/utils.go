@@ -2,8 +2,8 @@ package service
 
 import (
 	"context"
-	"fmt"
 
+	"github.com/bamdadam/backend/src/apperror"
 	"github.com/bamdadam/backend/src/model"
 )
 
@@ -11,5 +11,5 @@ func getUserID(ctx context.Context) (string, error) {
 	if userID, ok := ctx.Value(model.UserIDKey).(string); ok {
 		return userID, nil
 	}
-	return "", fmt.Errorf("user not found")
+	return "", apperror.Unauthenticated("user not found")
 }
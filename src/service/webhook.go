@@ -0,0 +1,551 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
+	models "github.com/bamdadam/backend/src/model"
+	"github.com/bamdadam/backend/src/repository"
+)
+
+var (
+	// WebhookMaxAttempts is how many times a delivery is attempted before it
+	// is failed.
+	WebhookMaxAttempts int32 = 8
+	// WebhookRetryBackoff is the delay before the second attempt of a
+	// delivery, doubled for every further attempt up to
+	// WebhookMaxRetryBackoff.
+	WebhookRetryBackoff    = 10 * time.Second
+	WebhookMaxRetryBackoff = time.Hour
+	// WebhookTimeout is how long webhooks are given to respond.
+	WebhookTimeout = 10 * time.Second
+	// WebhookPollInterval is how often idle delivery workers look for pending
+	// deliveries.
+	WebhookPollInterval = time.Second
+)
+
+// webhookResponseBodyLimit is how much of the responses of webhooks is kept
+// in the delivery log.
+const webhookResponseBodyLimit = 1024
+
+// Headers of webhook calls.
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookEventIDHeader   = "X-Webhook-Event-ID"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// WebhookService manages the webhooks of spaces and delivers element events
+// to them.
+type WebhookService struct {
+	*UserService
+
+	webhooks repository.WebhookRepository
+	typeRepo repository.TypeRepository
+	client   *http.Client
+	// allowPrivateNetworks lets webhooks call loopback, private and
+	// link-local addresses.
+	allowPrivateNetworks bool
+	limits               Limits
+}
+
+// NewWebhookService returns a service delivering webhooks. Unless
+// allowPrivateNetworks is set, webhooks can't call loopback, private,
+// link-local or unspecified addresses, which would let space writers reach
+// internal services and read their responses in the delivery log.
+func NewWebhookService(us *UserService, webhookRepo repository.WebhookRepository, typeRepo repository.TypeRepository,
+	allowPrivateNetworks bool, limits Limits) *WebhookService {
+	return &WebhookService{
+		UserService:          us,
+		webhooks:             webhookRepo,
+		typeRepo:             typeRepo,
+		client:               newWebhookClient(allowPrivateNetworks),
+		allowPrivateNetworks: allowPrivateNetworks,
+		limits:               limits,
+	}
+}
+
+var errBlockedAddress = errors.New("webhooks can't call loopback, private or link-local addresses")
+
+// newWebhookClient returns the client calling webhooks. Redirects are not
+// followed, and addresses are checked when dialing so that host names
+// resolving to blocked addresses are refused too.
+func newWebhookClient(allowPrivateNetworks bool) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !allowPrivateNetworks {
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); ip == nil || blockedIP(ip) {
+					return errBlockedAddress
+				}
+				return nil
+			},
+		}
+		transport.DialContext = dialer.DialContext
+		// a proxy would be dialed instead of the webhook
+		transport.Proxy = nil
+	}
+
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func blockedIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+}
+
+// checkWebhookHost rejects webhook URLs naming a blocked address or
+// localhost. Other host names are checked when delivering, once resolved.
+func (s *WebhookService) checkWebhookHost(u *url.URL) error {
+	if s.allowPrivateNetworks {
+		return nil
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return apperror.Validation("url can't be a loopback, private or link-local address")
+	}
+	if ip := net.ParseIP(host); ip != nil && blockedIP(ip) {
+		return apperror.Validation("url can't be a loopback, private or link-local address")
+	}
+	return nil
+}
+
+// Create registers a webhook called on events of the elements of a space, or
+// of a type of the space when typeURI is set. It requires write permission on
+// the space.
+func (s *WebhookService) Create(ctx context.Context, spaceURI string, typeURI *string, rawURL string,
+	events []model.WebhookEvent, secret string) (*model.Webhook, error) {
+	if err := s.checkWritable(ctx, spaceURI); err != nil {
+		return nil, err
+	}
+
+	actor, err := s.getUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, apperror.Validation("url must be an absolute http or https URL")
+	}
+	if err := s.checkWebhookHost(u); err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, apperror.Validation("no events to subscribe to")
+	}
+	if secret == "" {
+		return nil, apperror.Validation("the secret is empty")
+	}
+
+	if typeURI != nil {
+		t, err := s.typeRepo.GetByURI(ctx, *typeURI)
+		if err != nil {
+			return nil, err
+		}
+		if t.Space.URI != spaceURI {
+			return nil, apperror.Validation("type %s is not in space %s", *typeURI, spaceURI)
+		}
+	}
+
+	webhook := &models.Webhook{
+		URI:          newWebhookURI(),
+		SpaceURI:     spaceURI,
+		TypeURI:      typeURI,
+		URL:          rawURL,
+		Secret:       secret,
+		AuthorURI:    actor.URI,
+		CreationDate: time.Now().UnixMilli(),
+	}
+	for _, e := range events {
+		if name := webhookEventName(e); !slices.Contains(webhook.Events, name) {
+			webhook.Events = append(webhook.Events, name)
+		}
+	}
+
+	if err = s.webhooks.Create(ctx, webhook); err != nil {
+		return nil, err
+	}
+	return webhookModel(webhook), nil
+}
+
+// List returns the webhooks of a space. It requires write permission on the
+// space.
+func (s *WebhookService) List(ctx context.Context, spaceURI string) ([]*model.Webhook, error) {
+	if err := s.checkWritable(ctx, spaceURI); err != nil {
+		return nil, err
+	}
+
+	webhooks, err := s.webhooks.ListBySpace(ctx, spaceURI)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.Webhook, len(webhooks))
+	for i, w := range webhooks {
+		result[i] = webhookModel(w)
+	}
+	return result, nil
+}
+
+// Delete removes a webhook along with its deliveries, pending ones included.
+func (s *WebhookService) Delete(ctx context.Context, uri string) error {
+	if _, err := s.get(ctx, uri); err != nil {
+		return err
+	}
+	return s.webhooks.Delete(ctx, uri)
+}
+
+// get returns a webhook the requesting user may manage.
+func (s *WebhookService) get(ctx context.Context, uri string) (*models.Webhook, error) {
+	webhook, err := s.webhooks.GetByURI(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.checkWritable(ctx, webhook.SpaceURI); err != nil {
+		if apperror.CodeOf(err) == apperror.CodeNotFound {
+			return nil, apperror.NotFound("webhook not found: %s", uri)
+		}
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// Deliveries returns the deliveries of a webhook, latest first, optionally
+// only those with a status.
+func (s *WebhookService) Deliveries(ctx context.Context, webhookURI string, status *model.WebhookDeliveryStatus,
+	limit int32, after *string) (*model.WebhookDeliveryConnection, error) {
+	if _, err := s.get(ctx, webhookURI); err != nil {
+		return nil, err
+	}
+
+	var afterID *int64
+	if after != nil {
+		id, err := strconv.ParseInt(*after, 10, 64)
+		if err != nil {
+			return nil, apperror.Validation("invalid cursor: %s", *after)
+		}
+		afterID = &id
+	}
+
+	var statusName *string
+	if status != nil {
+		name := strings.ToLower(string(*status))
+		statusName = &name
+	}
+
+	limit = s.limits.pageSize(limit)
+
+	deliveries, err := s.webhooks.ListDeliveries(ctx, webhookURI, statusName, limit, afterID)
+	if err != nil {
+		return nil, err
+	}
+
+	hasNextPage := len(deliveries) > int(limit)
+	if hasNextPage {
+		deliveries = deliveries[:limit]
+	}
+
+	edges := make([]*model.WebhookDeliveryEdge, len(deliveries))
+	for i, d := range deliveries {
+		node := deliveryModel(d)
+		edges[i] = &model.WebhookDeliveryEdge{Cursor: node.ID, Node: node}
+	}
+
+	pageInfo := &model.PageInfo{HasNextPage: hasNextPage}
+	if len(edges) > 0 {
+		pageInfo.StartCursor = &edges[0].Cursor
+		pageInfo.EndCursor = &edges[len(edges)-1].Cursor
+	}
+
+	return &model.WebhookDeliveryConnection{
+		Edges:      edges,
+		PageInfo:   pageInfo,
+		TotalCount: int32(len(edges)),
+	}, nil
+}
+
+// Redeliver sends the event of a delivery again, as a new delivery to the
+// same webhook.
+func (s *WebhookService) Redeliver(ctx context.Context, id string) (*model.WebhookDelivery, error) {
+	deliveryID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, apperror.NotFound("webhook delivery not found: %s", id)
+	}
+
+	delivery, err := s.webhooks.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = s.get(ctx, delivery.WebhookURI); err != nil {
+		if apperror.CodeOf(err) == apperror.CodeNotFound {
+			return nil, apperror.NotFound("webhook delivery not found: %s", id)
+		}
+		return nil, err
+	}
+
+	delivery, err = s.webhooks.Redeliver(ctx, deliveryID, time.Now().UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	return deliveryModel(delivery), nil
+}
+
+// Enqueue queues the deliveries of the outbox event of an element change to
+// the webhooks subscribed to it, within the transaction carried by ctx.
+func (s *WebhookService) Enqueue(ctx context.Context, eventID, action string, change *model.ElementChange) error {
+	event := models.WebhookElementUpdated
+	switch {
+	case action == AuditActionElementCreate:
+		event = models.WebhookElementCreated
+	case change.Deleted:
+		event = models.WebhookElementDeleted
+	}
+
+	elem := change.Element
+	fieldValues := make(map[string]any, len(elem.FieldValues))
+	if !change.Deleted {
+		for _, fv := range elem.FieldValues {
+			fieldValues[fv.Field.URI] = fv.Value
+		}
+	}
+
+	payload, err := json.Marshal(map[string]any{
+		"id":        eventID,
+		"event":     event,
+		"action":    action,
+		"timestamp": change.Timestamp.UTC().Format(time.RFC3339Nano),
+		"actor":     change.Actor.URI,
+		"element": map[string]any{
+			"uri":         elem.URI,
+			"title":       elem.Title,
+			"typeUri":     elem.Type.URI,
+			"spaceUri":    elem.Space.URI,
+			"version":     elem.Version,
+			"fieldValues": fieldValues,
+		},
+		"changes": elementAuditDiff(change),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	return s.webhooks.Enqueue(ctx, &models.WebhookEvent{
+		ID:       eventID,
+		Event:    event,
+		SpaceURI: elem.Space.URI,
+		TypeURI:  elem.Type.URI,
+		Payload:  payload,
+	}, time.Now().UnixMilli())
+}
+
+// Run sends pending deliveries with the given number of workers until ctx is
+// cancelled. Deliveries in flight are completed before Run returns.
+func (s *WebhookService) Run(ctx context.Context, workers int) {
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *WebhookService) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		now := time.Now()
+		// the lock outlasts the call, so that a delivery is only claimed
+		// again when its worker is gone
+		delivery, err := s.webhooks.ClaimDelivery(ctx, now.UnixMilli(), now.Add(2*WebhookTimeout).UnixMilli())
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to claim webhook delivery: %v", err)
+		}
+		if delivery == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(WebhookPollInterval):
+			}
+			continue
+		}
+		s.deliver(context.WithoutCancel(ctx), delivery)
+	}
+}
+
+// deliver attempts a claimed delivery and records the outcome, scheduling
+// the next attempt when it failed.
+func (s *WebhookService) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	delivery.ResponseStatus, delivery.ResponseBody, delivery.Error = nil, nil, nil
+
+	status, body, err := s.send(ctx, delivery)
+	now := time.Now()
+	switch {
+	case err != nil:
+		msg := err.Error()
+		delivery.Error = &msg
+	default:
+		delivery.ResponseStatus, delivery.ResponseBody = &status, &body
+		if status < 200 || status >= 300 {
+			msg := fmt.Sprintf("webhook responded with status %d", status)
+			delivery.Error = &msg
+		}
+	}
+
+	switch {
+	case delivery.Error == nil:
+		delivery.Status = models.DeliverySucceeded
+		deliveredDate := now.UnixMilli()
+		delivery.DeliveredDate = &deliveredDate
+	case delivery.Attempts >= WebhookMaxAttempts:
+		delivery.Status = models.DeliveryFailed
+	default:
+		backoff := min(WebhookRetryBackoff<<(delivery.Attempts-1), WebhookMaxRetryBackoff)
+		delivery.NextAttempt = now.Add(backoff).UnixMilli()
+	}
+
+	if err := s.webhooks.RecordAttempt(ctx, delivery, now.UnixMilli()); err != nil {
+		log.Printf("Failed to record webhook delivery %d: %v", delivery.ID, err)
+	}
+}
+
+// send POSTs the payload of a delivery to its webhook, signed with the secret
+// of the webhook, and returns the status and the start of the body of the
+// response.
+func (s *WebhookService) send(ctx context.Context, delivery *models.WebhookDelivery) (int32, string, error) {
+	if delivery.Attempts > WebhookMaxAttempts {
+		// claimed again after the worker of its last attempt was lost
+		return 0, "", fmt.Errorf("the worker delivering the event was lost")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, WebhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid webhook request: %w", err)
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, delivery.Event)
+	req.Header.Set(WebhookEventIDHeader, delivery.EventID)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseBodyLimit))
+	return int32(resp.StatusCode), strings.ToValidUTF8(string(body), ""), nil
+}
+
+// SignWebhookPayload returns the signature header of a webhook call made at
+// timestamp, in Unix seconds: "sha256=" followed by the hex-encoded
+// HMAC-SHA256 of the timestamp, a dot and the body, keyed with the secret of
+// the webhook.
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookEventName is the name of an event in payloads and headers, e.g.
+// element.created for ELEMENT_CREATED.
+func webhookEventName(e model.WebhookEvent) string {
+	return strings.Replace(strings.ToLower(string(e)), "_", ".", 1)
+}
+
+func webhookEvent(name string) model.WebhookEvent {
+	return model.WebhookEvent(strings.Replace(strings.ToUpper(name), ".", "_", 1))
+}
+
+func newWebhookURI() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "webhook:" + hex.EncodeToString(b)
+}
+
+func webhookModel(w *models.Webhook) *model.Webhook {
+	m := &model.Webhook{
+		URI:          w.URI,
+		SpaceURI:     w.SpaceURI,
+		TypeURI:      w.TypeURI,
+		URL:          w.URL,
+		Events:       make([]model.WebhookEvent, len(w.Events)),
+		AuthorURI:    w.AuthorURI,
+		CreationDate: time.UnixMilli(w.CreationDate),
+	}
+	for i, e := range w.Events {
+		m.Events[i] = webhookEvent(e)
+	}
+	return m
+}
+
+func deliveryModel(d *models.WebhookDelivery) *model.WebhookDelivery {
+	m := &model.WebhookDelivery{
+		ID:             strconv.FormatInt(d.ID, 10),
+		WebhookURI:     d.WebhookURI,
+		EventID:        d.EventID,
+		Event:          webhookEvent(d.Event),
+		Status:         model.WebhookDeliveryStatus(strings.ToUpper(d.Status)),
+		Attempts:       d.Attempts,
+		ResponseStatus: d.ResponseStatus,
+		ResponseBody:   d.ResponseBody,
+		Error:          d.Error,
+		CreationDate:   time.UnixMilli(d.CreationDate),
+	}
+	var payload any
+	if err := json.Unmarshal(d.Payload, &payload); err == nil {
+		m.Payload = payload
+	}
+	if d.Status == models.DeliveryPending {
+		nextAttemptAt := time.UnixMilli(d.NextAttempt)
+		m.NextAttemptAt = &nextAttemptAt
+	}
+	if d.DeliveredDate != nil {
+		deliveredAt := time.UnixMilli(*d.DeliveredDate)
+		m.DeliveredAt = &deliveredAt
+	}
+	return m
+}
@@ -0,0 +1,494 @@
+package typeschema
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/apperror"
+	models "github.com/bamdadam/backend/src/model"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"github.com/vektah/gqlparser/v2/validator"
+)
+
+// ElementLister is the part of the element service queries are executed
+// against, so permissions and pagination behave as in the main API.
+type ElementLister interface {
+	GetByURI(ctx context.Context, uri string) (*model.Element, error)
+	List(ctx context.Context, params models.ListParams) (*model.ElementConnection, error)
+	Links(ctx context.Context, uri string, fieldURI *string) ([]*model.ElementLink, error)
+}
+
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// Response holds the result of a query. Errors raised while resolving fields
+// are returned as is and still have to be presented to the client.
+type Response struct {
+	Data   json.RawMessage
+	Errors []error
+}
+
+// Execute runs a query against the schema. Only queries are supported, there
+// are no mutations or subscriptions on generated schemas, and neither is
+// introspection as clients can use the SDL instead.
+func (s *Schema) Execute(ctx context.Context, elements ElementLister, req Request) *Response {
+	doc, errs := gqlparser.LoadQuery(s.schema, req.Query)
+	if len(errs) > 0 {
+		resp := &Response{}
+		for _, err := range errs {
+			resp.Errors = append(resp.Errors, err)
+		}
+		return resp
+	}
+
+	op := doc.Operations.ForName(req.OperationName)
+	if op == nil {
+		return errorResponse(gqlerror.Errorf("operation %q not found", req.OperationName))
+	}
+	if op.Operation != ast.Query {
+		return errorResponse(gqlerror.Errorf("only queries are supported on generated schemas"))
+	}
+
+	vars, err := validator.VariableValues(s.schema, op, req.Variables)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	e := &executor{schema: s, elements: elements, vars: vars}
+	data := e.executeQuery(ctx, op.SelectionSet)
+
+	resp := &Response{Errors: e.errs}
+	if data != nil {
+		resp.Data, err = json.Marshal(data)
+		if err != nil {
+			return errorResponse(fmt.Errorf("failed to encode response: %w", err))
+		}
+	}
+	return resp
+}
+
+func errorResponse(errs ...error) *Response {
+	return &Response{Errors: errs}
+}
+
+type executor struct {
+	schema   *Schema
+	elements ElementLister
+	vars     map[string]any
+	errs     []error
+}
+
+func (e *executor) executeQuery(ctx context.Context, set ast.SelectionSet) *orderedMap {
+	data := &orderedMap{}
+	for _, f := range e.collectFields(set, "Query") {
+		path := ast.Path{ast.PathName(f.Alias)}
+
+		switch f.Name {
+		case "__typename":
+			data.set(f.Alias, "Query")
+			continue
+		case "__schema", "__type":
+			e.errs = append(e.errs, gqlerror.ErrorPathf(path, "introspection is not supported, use the schema SDL instead"))
+			data.set(f.Alias, nil)
+			continue
+		case "tenantUri":
+			data.set(f.Alias, e.schema.TenantURI)
+			continue
+		}
+
+		root := e.schema.roots[f.Name]
+		value, err := e.resolveRoot(ctx, root, f)
+		if err != nil {
+			e.errs = append(e.errs, gqlerror.WrapPath(path, err))
+			// list fields are non-null, so their failure nulls the whole
+			// result
+			if root.list {
+				return nil
+			}
+		}
+		data.set(f.Alias, value)
+	}
+	return data
+}
+
+func (e *executor) resolveRoot(ctx context.Context, root *rootField, f *ast.Field) (any, error) {
+	args := f.ArgumentMap(e.vars)
+
+	if !root.list {
+		elem, err := e.elements.GetByURI(ctx, args["uri"].(string))
+		if apperror.CodeOf(err) == apperror.CodeNotFound {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if elem.Type.URI != root.object.typeURI {
+			return nil, nil
+		}
+		return e.resolveElement(ctx, root.object, elem, f.SelectionSet, ast.Path{ast.PathName(f.Alias)}), nil
+	}
+
+	typeURI := root.object.typeURI
+	params := models.ListParams{TypeURI: &typeURI}
+	if first, ok := args["first"]; ok && first != nil {
+		n, err := toFloat(first)
+		if err != nil {
+			return nil, apperror.Validation("invalid first: %w", err)
+		}
+		params.Limit = int32(n)
+	}
+	if after, ok := args["after"].(string); ok {
+		params.After = &after
+	}
+	if filter, ok := args["filter"].(map[string]any); ok {
+		conditions, err := root.object.conditions(filter)
+		if err != nil {
+			return nil, err
+		}
+		params.Conditions = conditions
+	}
+
+	conn, err := e.elements.List(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]any, 0, len(conn.Edges))
+	for i, edge := range conn.Edges {
+		path := ast.Path{ast.PathName(f.Alias), ast.PathIndex(i)}
+		list = append(list, e.resolveElement(ctx, root.object, edge.Node, f.SelectionSet, path))
+	}
+	return list, nil
+}
+
+func (e *executor) resolveElement(ctx context.Context, obj *object, elem *model.Element, set ast.SelectionSet, path ast.Path) *orderedMap {
+	values := make(map[string]any, len(elem.FieldValues))
+	for _, fv := range elem.FieldValues {
+		values[fv.Field.URI] = fv.Value
+	}
+
+	result := &orderedMap{}
+	for _, f := range e.collectFields(set, obj.name) {
+		switch f.Name {
+		case "__typename":
+			result.set(f.Alias, obj.name)
+		case "uri":
+			result.set(f.Alias, elem.URI)
+		case "title":
+			result.set(f.Alias, elem.Title)
+		case "creationDate":
+			result.set(f.Alias, model.FormatDateTime(elem.CreationDate))
+		case "version":
+			result.set(f.Alias, elem.Version)
+		default:
+			field := obj.fields[f.Name]
+			if field.target == nil {
+				result.set(f.Alias, field.output(values[field.def.URI]))
+				continue
+			}
+
+			fieldPath := append(slices.Clone(path), ast.PathName(f.Alias))
+			linked, err := e.resolveLinks(ctx, field, elem, f.SelectionSet, fieldPath)
+			if err != nil {
+				e.errs = append(e.errs, gqlerror.WrapPath(fieldPath, err))
+			}
+			result.set(f.Alias, linked)
+		}
+	}
+	return result
+}
+
+// resolveLinks resolves a RELATION field to the linked elements, or to the
+// single linked element if the field links to one.
+func (e *executor) resolveLinks(ctx context.Context, field *field, elem *model.Element, set ast.SelectionSet, path ast.Path) (any, error) {
+	fieldURI := field.def.URI
+	links, err := e.elements.Links(ctx, elem.URI, &fieldURI)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]any, 0, len(links))
+	for _, link := range links {
+		if link.Element.Type.URI != field.target.typeURI {
+			continue
+		}
+		itemPath := path
+		if field.multiple {
+			itemPath = append(slices.Clone(path), ast.PathIndex(len(list)))
+		}
+		list = append(list, e.resolveElement(ctx, field.target, link.Element, set, itemPath))
+	}
+
+	if field.multiple {
+		return list, nil
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	return list[0], nil
+}
+
+// output converts a stored field value to its generated GraphQL type, mapping
+// select options to their enum values.
+func (f *field) output(value any) any {
+	if value == nil || f.enum == nil {
+		return value
+	}
+
+	if f.def.FieldType == model.FieldTypeMultiSelect {
+		options, ok := value.([]any)
+		if !ok {
+			return nil
+		}
+		out := make([]any, 0, len(options))
+		for _, opt := range options {
+			if s, ok := opt.(string); ok {
+				if v, ok := f.enum.byOption[s]; ok {
+					out = append(out, v)
+				}
+			}
+		}
+		return out
+	}
+
+	// options removed from the field since the value was written are
+	// returned as null
+	if s, ok := value.(string); ok {
+		if v, ok := f.enum.byOption[s]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// conditions compiles a filter input to conditions on field values.
+func (obj *object) conditions(filter map[string]any) ([]models.FieldCondition, error) {
+	var conditions []models.FieldCondition
+	for key, value := range filter {
+		if value == nil {
+			continue
+		}
+
+		name, op := parseFilterKey(key)
+		f, ok := obj.fields[name]
+		if !ok {
+			return nil, apperror.Validation("unknown filter %s", key)
+		}
+
+		if f.target != nil {
+			nested, ok := value.(map[string]any)
+			if !ok {
+				return nil, apperror.Validation("invalid value for filter %s: expected an object", key)
+			}
+			related, err := f.target.conditions(nested)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, models.FieldCondition{
+				FieldURI:  f.def.URI,
+				FieldType: f.def.FieldType,
+				Related:   related,
+			})
+			continue
+		}
+
+		var converted any
+		var err error
+		if op == models.FilterIn {
+			converted, err = f.inputList(value)
+		} else {
+			converted, err = f.input(value)
+		}
+		if err != nil {
+			return nil, apperror.Validation("invalid value for filter %s: %w", key, err)
+		}
+
+		conditions = append(conditions, models.FieldCondition{
+			FieldURI:  f.def.URI,
+			FieldType: f.valueType(),
+			Operator:  op,
+			Value:     converted,
+		})
+	}
+	return conditions, nil
+}
+
+// input converts a filter argument to the representation stored in the
+// database.
+func (f *field) input(value any) (any, error) {
+	if f.enum != nil {
+		s, _ := value.(string)
+		opt, ok := f.enum.byValue[s]
+		if !ok {
+			return nil, fmt.Errorf("unknown option %v", value)
+		}
+		return opt, nil
+	}
+
+	switch f.valueType() {
+	case model.FieldTypeNumber:
+		return toFloat(value)
+	case model.FieldTypeDate:
+		date, err := model.UnmarshalDateTime(value)
+		if err != nil {
+			return nil, err
+		}
+		return date.UnixMilli(), nil
+	case model.FieldTypeBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected a boolean, got %v", value)
+		}
+		return b, nil
+	default:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string, got %v", value)
+		}
+		return s, nil
+	}
+}
+
+func (f *field) inputList(value any) (any, error) {
+	items, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("expected a list, got %v", value)
+	}
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		v, err := f.input(item)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string, got %v", v)
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+func toFloat(value any) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case json.Number:
+		return v.Float64()
+	default:
+		return 0, fmt.Errorf("expected a number, got %v", value)
+	}
+}
+
+// collectFields flattens fragments in a selection set for the given object
+// type and merges the fields selected more than once under the same response
+// key, e.g. a: element { x } and a: element { y }. Validation has checked that
+// such fields are the same with the same arguments, so only their
+// sub-selections are combined.
+func (e *executor) collectFields(set ast.SelectionSet, typeName string) []*ast.Field {
+	var fields []*ast.Field
+	byKey := make(map[string]int)
+	for _, f := range e.flattenFields(set, typeName) {
+		i, ok := byKey[f.Alias]
+		if !ok {
+			byKey[f.Alias] = len(fields)
+			fields = append(fields, f)
+			continue
+		}
+		// parsed queries are shared, the merged field is a copy
+		merged := *fields[i]
+		merged.SelectionSet = append(slices.Clip(fields[i].SelectionSet), f.SelectionSet...)
+		fields[i] = &merged
+	}
+	return fields
+}
+
+// flattenFields returns the fields of a selection set and of its fragments
+// applying to the given object type, in order.
+func (e *executor) flattenFields(set ast.SelectionSet, typeName string) []*ast.Field {
+	var fields []*ast.Field
+	for _, sel := range set {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			if e.included(sel.Directives) {
+				fields = append(fields, sel)
+			}
+		case *ast.InlineFragment:
+			if e.included(sel.Directives) && (sel.TypeCondition == "" || sel.TypeCondition == typeName) {
+				fields = append(fields, e.flattenFields(sel.SelectionSet, typeName)...)
+			}
+		case *ast.FragmentSpread:
+			if e.included(sel.Directives) && sel.Definition.TypeCondition == typeName {
+				fields = append(fields, e.flattenFields(sel.Definition.SelectionSet, typeName)...)
+			}
+		}
+	}
+	return fields
+}
+
+func (e *executor) included(directives ast.DirectiveList) bool {
+	if d := directives.ForName("skip"); d != nil {
+		if skip, _ := d.ArgumentMap(e.vars)["if"].(bool); skip {
+			return false
+		}
+	}
+	if d := directives.ForName("include"); d != nil {
+		if include, _ := d.ArgumentMap(e.vars)["if"].(bool); !include {
+			return false
+		}
+	}
+	return true
+}
+
+// orderedMap is a JSON object that keeps the order fields were selected in,
+// as required by the GraphQL spec.
+type orderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+func (m *orderedMap) set(key string, value any) {
+	if m.values == nil {
+		m.values = make(map[string]any)
+	}
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
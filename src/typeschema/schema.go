@@ -0,0 +1,444 @@
+package typeschema
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/formula"
+	models "github.com/bamdadam/backend/src/model"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// Schema is a GraphQL schema generated from the types and fields of a tenant.
+// Every type becomes an object type with one field per element field, a filter
+// input and a pair of query fields, e.g. project(uri) and projects(filter).
+type Schema struct {
+	TenantURI string
+	// SDL is the schema in GraphQL schema definition language, for clients to
+	// generate code from.
+	SDL string
+
+	schema  *ast.Schema
+	objects map[string]*object
+	roots   map[string]*rootField
+	// rootOrder keeps the query fields in the order of the types.
+	rootOrder []string
+}
+
+type object struct {
+	name    string
+	typeURI string
+	fields  map[string]*field
+	// order keeps fields in the order of their definitions.
+	order []*field
+}
+
+type field struct {
+	name string
+	def  *models.FieldDefinition
+	enum *enum
+	// target is the object a RELATION field links to, multiple tells whether
+	// it links to a list of them.
+	target   *object
+	multiple bool
+	// resultType is the type of the values of a FORMULA or ROLLUP field.
+	resultType model.FieldType
+}
+
+// enum maps the options of a SELECT or MULTI_SELECT field to GraphQL enum
+// values and back.
+type enum struct {
+	name     string
+	values   []string
+	byValue  map[string]string
+	byOption map[string]string
+}
+
+type rootField struct {
+	object *object
+	list   bool
+}
+
+// Built-in element attributes exposed on every object type.
+var elementAttributes = []string{"uri", "title", "creationDate", "version"}
+
+var reservedTypeNames = []string{
+	"Query", "Mutation", "Subscription", "String", "Int", "Float", "Boolean", "ID", "DateTime",
+}
+
+// Build generates the schema of a tenant from its type definitions.
+func Build(tenantURI string, defs []*models.TypeDefinition) (*Schema, error) {
+	s := &Schema{
+		TenantURI: tenantURI,
+		objects:   make(map[string]*object),
+		roots:     make(map[string]*rootField),
+	}
+
+	typeNames := newNameSet(reservedTypeNames...)
+	rootNames := newNameSet("tenantUri")
+	var objects []*object
+	var enums []*enum
+	// objects are created up front so relation fields can refer to types
+	// defined after them
+	byTypeURI := make(map[string]*object, len(defs))
+	fieldDefs := make(map[string]*models.FieldDefinition)
+	for _, def := range defs {
+		for _, f := range def.Fields {
+			fieldDefs[f.URI] = f
+		}
+
+		obj := &object{
+			name:    typeNames.add(pascalCase(def.Name, "Type")),
+			typeURI: def.URI,
+			fields:  make(map[string]*field),
+		}
+		// Reserve the name of the filter input derived from the object.
+		typeNames.add(obj.name + "Filter")
+
+		byTypeURI[def.URI] = obj
+		s.objects[obj.name] = obj
+		objects = append(objects, obj)
+	}
+
+	for i, def := range defs {
+		obj := objects[i]
+
+		// invalid formula fields are left out
+		formulas := formula.CompileType(def.Fields)
+
+		fieldNames := newNameSet(elementAttributes...)
+		for _, fieldDef := range def.Fields {
+			f := &field{def: fieldDef}
+			switch fieldDef.FieldType {
+			case model.FieldTypeSelect, model.FieldTypeMultiSelect:
+				f.enum = newEnum(typeNames.add(obj.name+pascalCase(fieldDef.Name, "Option")), fieldDef.Options)
+				if f.enum != nil {
+					enums = append(enums, f.enum)
+				}
+			case model.FieldTypeRelation:
+				opts, err := models.ParseRelationOptions(fieldDef.Options)
+				if err != nil {
+					continue
+				}
+				// relations to types of other tenants can't be expressed
+				if f.target = byTypeURI[opts.TargetTypeURI]; f.target == nil {
+					continue
+				}
+				f.multiple = opts.Cardinality == models.CardinalityMultiple
+			case model.FieldTypeFormula:
+				var ok bool
+				if f.resultType, ok = formulas.ResultType(fieldDef.URI); !ok {
+					continue
+				}
+			case model.FieldTypeRollup:
+				if f.resultType = rollupResultType(fieldDef, fieldDefs); f.resultType == "" {
+					continue
+				}
+			}
+			f.name = fieldNames.add(camelCase(fieldDef.Name, "field"))
+			obj.fields[f.name] = f
+			obj.order = append(obj.order, f)
+		}
+
+		single := rootNames.add(camelCase(def.Name, "type"))
+		list := rootNames.add(plural(single))
+		s.roots[single] = &rootField{object: obj}
+		s.roots[list] = &rootField{object: obj, list: true}
+		s.rootOrder = append(s.rootOrder, single, list)
+	}
+
+	s.SDL = s.writeSDL(objects, enums)
+
+	schema, err := gqlparser.LoadSchema(&ast.Source{Name: tenantURI + ".graphqls", Input: s.SDL})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load generated schema: %w", err)
+	}
+	s.schema = schema
+
+	return s, nil
+}
+
+func (s *Schema) writeSDL(objects []*object, enums []*enum) string {
+	var b strings.Builder
+
+	b.WriteString("scalar DateTime\n")
+
+	for _, obj := range objects {
+		fmt.Fprintf(&b, "\n%s\ntype %s {\n", strconv.Quote("Elements of type "+obj.typeURI), obj.name)
+		b.WriteString("  uri: ID!\n  title: String!\n  creationDate: DateTime!\n  version: Int!\n")
+		for _, f := range obj.order {
+			fmt.Fprintf(&b, "  %s\n  %s: %s\n", strconv.Quote(f.def.URI), f.name, f.outputType())
+		}
+		b.WriteString("}\n")
+
+		// An input object needs at least one field, types without fields
+		// can't be filtered.
+		if !obj.filterable() {
+			continue
+		}
+		fmt.Fprintf(&b, "\ninput %sFilter {\n", obj.name)
+		for _, f := range obj.order {
+			for _, op := range f.filterOperators() {
+				fmt.Fprintf(&b, "  %s: %s\n", filterKey(f.name, op), f.filterType(op))
+			}
+		}
+		b.WriteString("}\n")
+	}
+
+	for _, e := range enums {
+		fmt.Fprintf(&b, "\nenum %s {\n", e.name)
+		for _, v := range e.values {
+			fmt.Fprintf(&b, "  %s\n  %s\n", strconv.Quote(e.byValue[v]), v)
+		}
+		b.WriteString("}\n")
+	}
+
+	b.WriteString("\ntype Query {\n  tenantUri: ID!\n")
+	for _, name := range s.rootOrder {
+		root := s.roots[name]
+		switch {
+		case root.list && !root.object.filterable():
+			fmt.Fprintf(&b, "  %s(first: Int, after: ID): [%s!]!\n", name, root.object.name)
+		case root.list:
+			fmt.Fprintf(&b, "  %s(filter: %sFilter, first: Int, after: ID): [%s!]!\n", name, root.object.name, root.object.name)
+		default:
+			fmt.Fprintf(&b, "  %s(uri: ID!): %s\n", name, root.object.name)
+		}
+	}
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+// filterable tells whether the object has a filter input, that is whether any
+// of its fields can be filtered on.
+func (obj *object) filterable() bool {
+	for _, f := range obj.order {
+		if len(f.filterOperators()) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// hasValueFields tells whether the object has fields with stored values,
+// other than relations and formulas. Relation filters only nest into such
+// objects, which keeps the check from recursing through relation cycles.
+func (obj *object) hasValueFields() bool {
+	for _, f := range obj.order {
+		if f.target == nil && f.def.FieldType != model.FieldTypeFormula {
+			return true
+		}
+	}
+	return false
+}
+
+// valueType is the type of the values of the field, the result type for
+// computed fields.
+func (f *field) valueType() model.FieldType {
+	if f.resultType != "" {
+		return f.resultType
+	}
+	return f.def.FieldType
+}
+
+// rollupResultType returns the type of the values of a ROLLUP field, or ""
+// if its options are invalid.
+func rollupResultType(def *models.FieldDefinition, fieldDefs map[string]*models.FieldDefinition) model.FieldType {
+	opts, err := models.ParseRollupOptions(def.Options)
+	if err != nil {
+		return ""
+	}
+
+	var targetType model.FieldType
+	if opts.TargetFieldURI != "" {
+		target, ok := fieldDefs[opts.TargetFieldURI]
+		if !ok {
+			return ""
+		}
+		targetType = target.FieldType
+	}
+
+	t, err := opts.ResultType(targetType)
+	if err != nil {
+		return ""
+	}
+	return t
+}
+
+func (f *field) scalarType() string {
+	if f.enum != nil {
+		return f.enum.name
+	}
+	if f.target != nil {
+		return f.target.name
+	}
+	switch f.valueType() {
+	case model.FieldTypeNumber:
+		return "Float"
+	case model.FieldTypeDate:
+		return "DateTime"
+	case model.FieldTypeBoolean:
+		return "Boolean"
+	default:
+		return "String"
+	}
+}
+
+// outputType is nullable even for required fields, existing elements may
+// predate a field.
+func (f *field) outputType() string {
+	if f.def.FieldType == model.FieldTypeMultiSelect || f.multiple {
+		return "[" + f.scalarType() + "!]"
+	}
+	return f.scalarType()
+}
+
+func (f *field) filterOperators() []models.FilterOperator {
+	if f.def.FieldType == model.FieldTypeFormula {
+		// formula values are computed on read, there is nothing to filter on
+		return nil
+	}
+
+	switch f.valueType() {
+	case model.FieldTypeNumber, model.FieldTypeDate:
+		return []models.FilterOperator{models.FilterEq, models.FilterGt, models.FilterGte, models.FilterLt, models.FilterLte}
+	case model.FieldTypeBoolean:
+		return []models.FilterOperator{models.FilterEq}
+	case model.FieldTypeSelect:
+		return []models.FilterOperator{models.FilterEq, models.FilterIn}
+	case model.FieldTypeMultiSelect:
+		return []models.FilterOperator{models.FilterContains}
+	case model.FieldTypeRelation:
+		// linked elements are filtered with the filter input of their type
+		if !f.target.hasValueFields() {
+			return nil
+		}
+		return []models.FilterOperator{models.FilterEq}
+	default:
+		return []models.FilterOperator{models.FilterEq, models.FilterIn, models.FilterContains}
+	}
+}
+
+func (f *field) filterType(op models.FilterOperator) string {
+	if f.target != nil {
+		return f.target.name + "Filter"
+	}
+	if op == models.FilterIn {
+		return "[" + f.scalarType() + "!]"
+	}
+	return f.scalarType()
+}
+
+// filterKey is the name of the filter input field for a field and operator,
+// e.g. budget_gt. Equality uses the bare field name.
+func filterKey(name string, op models.FilterOperator) string {
+	if op == models.FilterEq {
+		return name
+	}
+	return name + "_" + string(op)
+}
+
+// parseFilterKey is the inverse of filterKey. Generated field names never
+// contain underscores, so the suffix is unambiguous.
+func parseFilterKey(key string) (string, models.FilterOperator) {
+	name, op, found := strings.Cut(key, "_")
+	if !found {
+		return key, models.FilterEq
+	}
+	return name, models.FilterOperator(op)
+}
+
+func newEnum(name string, options *string) *enum {
+	opts, err := models.ParseSelectOptions(options)
+	if err != nil || len(opts) == 0 {
+		return nil
+	}
+
+	e := &enum{name: name, byValue: make(map[string]string), byOption: make(map[string]string)}
+	values := newNameSet("true", "false", "null")
+	for _, opt := range opts {
+		if _, ok := e.byOption[opt]; ok {
+			continue
+		}
+		v := values.add(screamingSnakeCase(opt))
+		e.values = append(e.values, v)
+		e.byValue[v] = opt
+		e.byOption[opt] = v
+	}
+	return e
+}
+
+// nameSet hands out unique names, suffixing a number on collisions.
+type nameSet map[string]bool
+
+func newNameSet(reserved ...string) nameSet {
+	ns := make(nameSet)
+	for _, name := range reserved {
+		ns[name] = true
+	}
+	return ns
+}
+
+func (ns nameSet) add(name string) string {
+	unique := name
+	for i := 2; ns[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	ns[unique] = true
+	return unique
+}
+
+// words splits a display name like "Start Date" or "e-mail" into its
+// alphanumeric words.
+func words(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r))
+	})
+}
+
+func pascalCase(s, fallback string) string {
+	var b strings.Builder
+	for _, w := range words(s) {
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+	return validName(b.String(), fallback)
+}
+
+func camelCase(s, fallback string) string {
+	name := pascalCase(s, "")
+	if name == "" {
+		return fallback
+	}
+	return validName(strings.ToLower(name[:1])+name[1:], fallback)
+}
+
+func screamingSnakeCase(s string) string {
+	return validName(strings.ToUpper(strings.Join(words(s), "_")), "OPTION")
+}
+
+// validName makes sure a name is not empty and doesn't start with a digit.
+func validName(name, fallback string) string {
+	if name == "" {
+		return fallback
+	}
+	if unicode.IsDigit(rune(name[0])) {
+		return fallback + name
+	}
+	return name
+}
+
+func plural(s string) string {
+	switch {
+	case strings.HasSuffix(s, "y") && len(s) > 1 && !strings.ContainsRune("aeiou", rune(s[len(s)-2])):
+		return s[:len(s)-1] + "ies"
+	case strings.HasSuffix(s, "s"), strings.HasSuffix(s, "x"), strings.HasSuffix(s, "ch"), strings.HasSuffix(s, "sh"):
+		return s + "es"
+	default:
+		return s + "s"
+	}
+}
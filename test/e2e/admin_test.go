@@ -0,0 +1,178 @@
+package e2e
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bamdadam/backend/src/apperror"
+	"github.com/bamdadam/backend/src/repository"
+	"github.com/bamdadam/backend/src/service"
+	"github.com/jackc/pgx/v5"
+)
+
+func TestAdmin(t *testing.T) {
+	ctx := context.Background()
+
+	tenantRepo := repository.NewTenantRepository(testDB)
+	admin := service.NewAdminService(
+		testDB,
+		tenantRepo,
+		repository.NewSpaceRepository(testDB, tenantRepo),
+		repository.NewUserRepository(testDB),
+		repository.NewUserTenantsRepository(testDB),
+		repository.NewUserSpacesRepository(testDB),
+		repository.NewElementRepository(testDB),
+		repository.NewAuditLogRepository(testDB),
+		"cli:test-operator",
+	)
+
+	t.Cleanup(func() {
+		for _, q := range []string{
+			`DELETE FROM elements WHERE uri = 'element:test-24'`,
+			`DELETE FROM user_space_permissions WHERE user_uri = 'user:test-admin'`,
+			`DELETE FROM user_spaces WHERE user_uri = 'user:test-admin'`,
+			`DELETE FROM user_tenants WHERE user_uri = 'user:test-admin'`,
+			`DELETE FROM users WHERE uri = 'user:test-admin'`,
+			`DELETE FROM spaces WHERE uri = 'space:test-admin'`,
+			`DELETE FROM tenants WHERE uri = 'tenant:test-admin'`,
+		} {
+			testDB.Exec(ctx, q)
+		}
+	})
+
+	allowed := func(t *testing.T, elementURI string) map[string]bool {
+		t.Helper()
+		access, err := admin.ExplainAccess(ctx, "user:test-admin", elementURI)
+		if err != nil {
+			t.Fatalf("Failed to explain access: %v", err)
+		}
+		checks := make(map[string]bool)
+		for _, c := range access.Checks {
+			checks[c.Action] = c.Allowed
+		}
+		return checks
+	}
+	// audited returns the actions audited for a target since the test began.
+	started := time.Now().UnixMilli()
+	audited := func(t *testing.T, targetURI string) []string {
+		t.Helper()
+		rows, err := testDB.Query(ctx, `
+			SELECT action FROM audit_log
+			WHERE user_uri = 'cli:test-operator' AND target_uri = $1 AND creation_date >= $2 AND client_ip IS NULL
+			ORDER BY id
+		`, targetURI, started)
+		if err != nil {
+			t.Fatalf("Failed to query audit log: %v", err)
+		}
+		actions, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			t.Fatalf("Failed to read audit log: %v", err)
+		}
+		return actions
+	}
+	const (
+		readElement   = "read the element"
+		writeElements = "write elements, jobs and webhooks of the space"
+	)
+
+	t.Run("users are created", func(t *testing.T) {
+		if _, err := admin.CreateUser(ctx, "user:test-admin", "test-admin@example.com", "Test Admin"); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+
+		_, err := admin.CreateUser(ctx, "", "test-admin@example.com", "Test Admin")
+		if code := apperror.CodeOf(err); code != apperror.CodeConflict {
+			t.Errorf("Expected a CONFLICT error for a duplicate email, got %v", err)
+		}
+	})
+
+	t.Run("grants give access to a space", func(t *testing.T) {
+		if err := admin.Grant(ctx, "user:test-admin", "space:test-1", []string{"read"}); err != nil {
+			t.Fatalf("Failed to grant: %v", err)
+		}
+		checks := allowed(t, "element:test-1")
+		if !checks[readElement] || checks[writeElements] {
+			t.Errorf("Expected read access only, got %v", checks)
+		}
+
+		users, err := admin.ListUsers(ctx, "tenant:test-1")
+		if err != nil {
+			t.Fatalf("Failed to list users: %v", err)
+		}
+		found := false
+		for _, u := range users {
+			found = found || u.URI == "user:test-admin"
+		}
+		if !found {
+			t.Error("Expected the user to be a member of the tenant of the space")
+		}
+
+		if err := admin.Grant(ctx, "user:test-admin", "space:test-1", []string{"write"}); err != nil {
+			t.Fatalf("Failed to grant: %v", err)
+		}
+		if checks := allowed(t, "element:test-1"); !checks[writeElements] {
+			t.Errorf("Expected write access, got %v", checks)
+		}
+	})
+
+	t.Run("unknown verbs are rejected", func(t *testing.T) {
+		err := admin.Grant(ctx, "user:test-admin", "space:test-1", []string{"fly"})
+		if code := apperror.CodeOf(err); code != apperror.CodeValidation {
+			t.Errorf("Expected a VALIDATION error, got %v", err)
+		}
+	})
+
+	t.Run("revoking without verbs takes the access away", func(t *testing.T) {
+		if err := admin.Revoke(ctx, "user:test-admin", "space:test-1", []string{"write"}); err != nil {
+			t.Fatalf("Failed to revoke: %v", err)
+		}
+		if checks := allowed(t, "element:test-1"); !checks[readElement] || checks[writeElements] {
+			t.Errorf("Expected read access only, got %v", checks)
+		}
+
+		if err := admin.Revoke(ctx, "user:test-admin", "space:test-1", nil); err != nil {
+			t.Fatalf("Failed to revoke: %v", err)
+		}
+		if checks := allowed(t, "element:test-1"); checks[readElement] {
+			t.Errorf("Expected no access, got %v", checks)
+		}
+
+		want := []string{service.AuditActionSpaceGrant, service.AuditActionSpaceGrant, service.AuditActionSpaceRevoke, service.AuditActionSpaceRevoke}
+		if got := audited(t, "user:test-admin"); !slices.Equal(got, want) {
+			t.Errorf("Expected the audited actions %v, got %v", want, got)
+		}
+	})
+
+	t.Run("deactivated tenants are inaccessible", func(t *testing.T) {
+		if _, err := admin.CreateTenant(ctx, "tenant:test-admin", "Test Admin Tenant"); err != nil {
+			t.Fatalf("Failed to create tenant: %v", err)
+		}
+		if _, err := admin.CreateSpace(ctx, "space:test-admin", "Test Admin Space", "tenant:test-admin"); err != nil {
+			t.Fatalf("Failed to create space: %v", err)
+		}
+		_, err := testDB.Exec(ctx, `INSERT INTO elements (uri, title, type_uri, space_uri, creation_date, author) VALUES ('element:test-24', 'Test Element 24', 'type:test-1', 'space:test-admin', 0, $1)`, testUserID)
+		if err != nil {
+			t.Fatalf("Failed to create element: %v", err)
+		}
+		if err := admin.Grant(ctx, "user:test-admin", "space:test-admin", []string{"write"}); err != nil {
+			t.Fatalf("Failed to grant: %v", err)
+		}
+		if checks := allowed(t, "element:test-24"); !checks[readElement] || !checks[writeElements] {
+			t.Errorf("Expected write access, got %v", checks)
+		}
+
+		if err := admin.DeactivateTenant(ctx, "tenant:test-admin"); err != nil {
+			t.Fatalf("Failed to deactivate tenant: %v", err)
+		}
+		if checks := allowed(t, "element:test-24"); checks[readElement] || checks[writeElements] {
+			t.Errorf("Expected no access, got %v", checks)
+		}
+
+		want := []string{service.AuditActionTenantCreate, service.AuditActionTenantDeactivate}
+		if got := audited(t, "tenant:test-admin"); !slices.Equal(got, want) {
+			t.Errorf("Expected the audited actions %v, got %v", want, got)
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package e2e
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAuditLogRecordsElementMutations(t *testing.T) {
+	createTestElement(t, "element:test-7", "Test Element 7", "Audited text")
+
+	resp := executeGraphQL(t, `
+		mutation UpdateElementTitle($input: UpdateElementTitleInput!) {
+			updateElementTitle(input: $input) { uri }
+		}
+	`, map[string]any{"input": map[string]any{"uri": "element:test-7", "title": "Audited Title"}})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("GraphQL errors: %v", resp.Errors)
+	}
+
+	query := `
+		query AuditLog($tenantUri: ID!, $filter: AuditLogFilter) {
+			auditLog(tenantUri: $tenantUri, filter: $filter, first: 5) {
+				edges {
+					node {
+						userUri
+						action
+						targetUri
+						tenantUri
+						requestId
+						clientIp
+						diff
+					}
+				}
+			}
+		}
+	`
+
+	resp = executeGraphQL(t, query, map[string]any{
+		"tenantUri": "tenant:test-1",
+		"filter":    map[string]any{"targetUri": "element:test-7"},
+	})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("GraphQL errors: %v", resp.Errors)
+	}
+
+	data := struct {
+		AuditLog struct {
+			Edges []struct {
+				Node struct {
+					UserURI   string  `json:"userUri"`
+					Action    string  `json:"action"`
+					TargetURI string  `json:"targetUri"`
+					TenantURI string  `json:"tenantUri"`
+					RequestID *string `json:"requestId"`
+					ClientIP  *string `json:"clientIp"`
+					Diff      struct {
+						Title struct {
+							Before string `json:"before"`
+							After  string `json:"after"`
+						} `json:"title"`
+					} `json:"diff"`
+				} `json:"node"`
+			} `json:"edges"`
+		} `json:"auditLog"`
+	}{}
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		t.Fatalf("Failed to unmarshal data: %v", err)
+	}
+
+	// entries are newest first, earlier runs against the same database may
+	// have left entries for the same target behind
+	if len(data.AuditLog.Edges) < 1 {
+		t.Fatal("Expected an audit entry, got none")
+	}
+
+	entry := data.AuditLog.Edges[0].Node
+	if entry.UserURI != testUserID {
+		t.Errorf("Expected user %q, got %q", testUserID, entry.UserURI)
+	}
+	if entry.Action != "element.update_title" {
+		t.Errorf("Expected action 'element.update_title', got %q", entry.Action)
+	}
+	if entry.TenantURI != "tenant:test-1" {
+		t.Errorf("Expected tenant 'tenant:test-1', got %q", entry.TenantURI)
+	}
+	if entry.RequestID == nil || *entry.RequestID == "" {
+		t.Error("Expected request ID to be recorded")
+	}
+	if entry.ClientIP == nil || *entry.ClientIP == "" {
+		t.Error("Expected client IP to be recorded")
+	}
+	if entry.Diff.Title.Before != "Test Element 7" || entry.Diff.Title.After != "Audited Title" {
+		t.Errorf("Expected title diff 'Test Element 7' -> 'Audited Title', got %+v", entry.Diff.Title)
+	}
+}
+
+func TestAuditLogRequiresTenantAdmin(t *testing.T) {
+	resp := executeGraphQL(t, `
+		query AuditLog($tenantUri: ID!) {
+			auditLog(tenantUri: $tenantUri) { totalCount }
+		}
+	`, map[string]any{"tenantUri": "tenant:acme"})
+
+	if len(resp.Errors) == 0 {
+		t.Fatal("Expected non-admin to be denied access to the audit log")
+	}
+	if resp.Errors[0].Extensions["code"] != "FORBIDDEN" {
+		t.Errorf("Expected error code FORBIDDEN, got %v", resp.Errors[0].Extensions["code"])
+	}
+}
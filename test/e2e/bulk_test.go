@@ -0,0 +1,227 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+type bulkResult struct {
+	URI     string `json:"uri"`
+	Element *struct {
+		Title string `json:"title"`
+	} `json:"element"`
+	Error *struct {
+		Code        string `json:"code"`
+		Message     string `json:"message"`
+		FieldErrors []struct {
+			FieldURI   string `json:"fieldUri"`
+			Constraint string `json:"constraint"`
+		} `json:"fieldErrors"`
+	} `json:"error"`
+}
+
+func TestBulkUpdateElements(t *testing.T) {
+	ctx := context.Background()
+
+	createTestElement(t, "element:test-15", "Test Element 15", "Bulk text")
+	createTestElement(t, "element:test-16", "Test Element 16", "Bulk text")
+
+	bulkUpdate := func(t *testing.T, atomic bool) map[string]bulkResult {
+		t.Helper()
+
+		resp := executeGraphQL(t, `
+			mutation BulkUpdate($inputs: [UpdateElementInput!]!, $atomic: Boolean) {
+				bulkUpdateElements(inputs: $inputs, atomic: $atomic) {
+					uri
+					element { title }
+					error { code message fieldErrors { fieldUri constraint } }
+				}
+			}
+		`, map[string]any{
+			"atomic": atomic,
+			"inputs": []any{
+				map[string]any{"uri": "element:test-15", "title": "Bulk Element 15"},
+				map[string]any{"uri": "element:test-16", "fieldValues": []any{
+					map[string]any{"fieldUri": "field:test-3", "value": "not a number"},
+				}},
+			},
+		})
+		if len(resp.Errors) > 0 {
+			t.Fatalf("GraphQL errors: %v", resp.Errors)
+		}
+
+		data := struct {
+			BulkUpdateElements []bulkResult `json:"bulkUpdateElements"`
+		}{}
+		if err := json.Unmarshal(resp.Data, &data); err != nil {
+			t.Fatalf("Failed to unmarshal data: %v", err)
+		}
+
+		results := make(map[string]bulkResult)
+		for _, r := range data.BulkUpdateElements {
+			results[r.URI] = r
+		}
+		if len(results) != 2 {
+			t.Fatalf("Expected a result per element, got %v", data.BulkUpdateElements)
+		}
+		return results
+	}
+
+	title := func(uri string) string {
+		var title string
+		testDB.QueryRow(ctx, `SELECT title FROM elements WHERE uri = $1`, uri).Scan(&title)
+		return title
+	}
+
+	t.Run("atomic batches roll back entirely", func(t *testing.T) {
+		results := bulkUpdate(t, true)
+
+		failed := results["element:test-16"]
+		if failed.Error == nil || failed.Error.Code != "VALIDATION" {
+			t.Fatalf("Expected a VALIDATION error for element:test-16, got %+v", failed.Error)
+		}
+		if len(failed.Error.FieldErrors) != 1 || failed.Error.FieldErrors[0].FieldURI != "field:test-3" {
+			t.Errorf("Expected a field error for field:test-3, got %+v", failed.Error.FieldErrors)
+		}
+
+		aborted := results["element:test-15"]
+		if aborted.Error == nil || aborted.Error.Code != "ABORTED" || aborted.Element != nil {
+			t.Errorf("Expected element:test-15 to be ABORTED, got %+v", aborted)
+		}
+		if got := title("element:test-15"); got != "Test Element 15" {
+			t.Errorf("Expected the title to be rolled back, got %q", got)
+		}
+	})
+
+	t.Run("non-atomic batches apply what they can", func(t *testing.T) {
+		results := bulkUpdate(t, false)
+
+		if results["element:test-16"].Error == nil {
+			t.Error("Expected an error for element:test-16")
+		}
+
+		updated := results["element:test-15"]
+		if updated.Error != nil || updated.Element == nil || updated.Element.Title != "Bulk Element 15" {
+			t.Errorf("Expected element:test-15 to be updated, got %+v", updated)
+		}
+		if got := title("element:test-15"); got != "Bulk Element 15" {
+			t.Errorf("Expected the title to be committed, got %q", got)
+		}
+	})
+}
+
+func TestBulkDeleteElements(t *testing.T) {
+	ctx := context.Background()
+
+	createTestElement(t, "element:test-17", "Test Element 17", "Bulk text")
+
+	resp := executeGraphQL(t, `
+		mutation BulkDelete($uris: [ID!]!) {
+			bulkDeleteElements(uris: $uris) {
+				uri
+				element { title }
+				error { code message }
+			}
+		}
+	`, map[string]any{"uris": []string{"element:test-17", "element:test-missing"}})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("GraphQL errors: %v", resp.Errors)
+	}
+
+	data := struct {
+		BulkDeleteElements []bulkResult `json:"bulkDeleteElements"`
+	}{}
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		t.Fatalf("Failed to unmarshal data: %v", err)
+	}
+	if len(data.BulkDeleteElements) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(data.BulkDeleteElements))
+	}
+
+	deleted, missing := data.BulkDeleteElements[0], data.BulkDeleteElements[1]
+	if deleted.Error != nil || deleted.Element != nil {
+		t.Errorf("Expected element:test-17 to be deleted, got %+v", deleted)
+	}
+	if missing.Error == nil || missing.Error.Code != "NOT_FOUND" {
+		t.Errorf("Expected NOT_FOUND for a missing element, got %+v", missing.Error)
+	}
+
+	var count int
+	testDB.QueryRow(ctx, `SELECT count(*) FROM elements WHERE uri = 'element:test-17'`).Scan(&count)
+	if count != 0 {
+		t.Error("Expected element:test-17 to be removed")
+	}
+}
+
+func TestBulkDeleteExpectedVersions(t *testing.T) {
+	ctx := context.Background()
+
+	createTestElement(t, "element:test-bulk-version", "Test Bulk Version", "Bulk text")
+
+	var version int
+	if err := testDB.QueryRow(ctx, `SELECT version FROM elements WHERE uri = 'element:test-bulk-version'`).Scan(&version); err != nil {
+		t.Fatalf("Failed to read version: %v", err)
+	}
+
+	resp := executeGraphQL(t, `
+		mutation { updateElementTitle(input: { uri: "element:test-bulk-version", title: "Changed Meanwhile" }) { uri } }
+	`, nil)
+	if len(resp.Errors) > 0 {
+		t.Fatalf("GraphQL errors: %v", resp.Errors)
+	}
+
+	bulkDelete := func(t *testing.T, expectedVersion int) bulkResult {
+		t.Helper()
+
+		resp := executeGraphQL(t, `
+			mutation BulkDelete($inputs: [DeleteElementInput!]) {
+				bulkDeleteElements(inputs: $inputs) { uri element { title } error { code message } }
+			}
+		`, map[string]any{"inputs": []map[string]any{{"uri": "element:test-bulk-version", "expectedVersion": expectedVersion}}})
+		if len(resp.Errors) > 0 {
+			t.Fatalf("GraphQL errors: %v", resp.Errors)
+		}
+
+		data := struct {
+			BulkDeleteElements []bulkResult `json:"bulkDeleteElements"`
+		}{}
+		if err := json.Unmarshal(resp.Data, &data); err != nil {
+			t.Fatalf("Failed to unmarshal data: %v", err)
+		}
+		if len(data.BulkDeleteElements) != 1 {
+			t.Fatalf("Expected 1 result, got %d", len(data.BulkDeleteElements))
+		}
+		return data.BulkDeleteElements[0]
+	}
+	count := func() int {
+		var count int
+		testDB.QueryRow(ctx, `SELECT count(*) FROM elements WHERE uri = 'element:test-bulk-version'`).Scan(&count)
+		return count
+	}
+
+	t.Run("stale versions conflict", func(t *testing.T) {
+		if result := bulkDelete(t, version); result.Error == nil || result.Error.Code != "CONFLICT" {
+			t.Errorf("Expected a CONFLICT for a stale version, got %+v", result.Error)
+		}
+		if count() != 1 {
+			t.Error("Expected the element to be kept")
+		}
+	})
+
+	t.Run("current versions are deleted", func(t *testing.T) {
+		if result := bulkDelete(t, version+1); result.Error != nil {
+			t.Errorf("Expected the element to be deleted, got %+v", result.Error)
+		}
+		if count() != 0 {
+			t.Error("Expected the element to be removed")
+		}
+	})
+
+	t.Run("inputs and uris are exclusive", func(t *testing.T) {
+		resp := executeGraphQL(t, `
+			mutation { bulkDeleteElements(inputs: [{ uri: "element:test-missing" }], uris: ["element:test-missing"]) { uri } }
+		`, nil)
+		expectCode(t, resp, "VALIDATION")
+	})
+}
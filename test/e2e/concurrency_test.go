@@ -0,0 +1,74 @@
+package e2e
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const updateTitleWithVersionMutation = `
+	mutation UpdateElementTitle($input: UpdateElementTitleInput!) {
+		updateElementTitle(input: $input) { uri title version }
+	}
+`
+
+func TestUpdateElementTitleVersionConflict(t *testing.T) {
+	createTestElement(t, "element:test-6", "Test Element 6", "Concurrent text")
+
+	resp := executeGraphQL(t, `query Element($uri: ID!) { element(uri: $uri) { version } }`,
+		map[string]any{"uri": "element:test-6"})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("GraphQL errors: %v", resp.Errors)
+	}
+
+	data := struct {
+		Element struct {
+			Version int `json:"version"`
+		} `json:"element"`
+	}{}
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		t.Fatalf("Failed to unmarshal data: %v", err)
+	}
+	version := data.Element.Version
+
+	resp = executeGraphQL(t, updateTitleWithVersionMutation, map[string]any{
+		"input": map[string]any{"uri": "element:test-6", "title": "First Writer", "expectedVersion": version},
+	})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("GraphQL errors: %v", resp.Errors)
+	}
+
+	updated := struct {
+		UpdateElementTitle struct {
+			Version int `json:"version"`
+		} `json:"updateElementTitle"`
+	}{}
+	if err := json.Unmarshal(resp.Data, &updated); err != nil {
+		t.Fatalf("Failed to unmarshal data: %v", err)
+	}
+	if updated.UpdateElementTitle.Version != version+1 {
+		t.Errorf("Expected version %d after update, got %d", version+1, updated.UpdateElementTitle.Version)
+	}
+
+	resp = executeGraphQL(t, updateTitleWithVersionMutation, map[string]any{
+		"input": map[string]any{"uri": "element:test-6", "title": "Second Writer", "expectedVersion": version},
+	})
+	if len(resp.Errors) != 1 {
+		t.Fatalf("Expected a single conflict error, got %v", resp.Errors)
+	}
+
+	ext := resp.Errors[0].Extensions
+	if ext["code"] != "CONFLICT" {
+		t.Errorf("Expected error code CONFLICT, got %v", ext["code"])
+	}
+
+	current, ok := ext["current"].(map[string]any)
+	if !ok {
+		t.Fatalf("Expected current element in error extensions, got %v", ext["current"])
+	}
+	if current["title"] != "First Writer" {
+		t.Errorf("Expected current title 'First Writer', got %v", current["title"])
+	}
+	if current["version"] != float64(version+1) {
+		t.Errorf("Expected current version %d, got %v", version+1, current["version"])
+	}
+}
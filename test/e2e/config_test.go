@@ -0,0 +1,234 @@
+package e2e
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/bamdadam/backend/graph/model"
+	"github.com/bamdadam/backend/src/config"
+	"github.com/bamdadam/backend/src/middleware"
+	models "github.com/bamdadam/backend/src/model"
+	"github.com/bamdadam/backend/src/server"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestConfig(t *testing.T) {
+	t.Run("environment overrides file", func(t *testing.T) {
+		path := writeConfig(t, `
+server:
+  addr: ":9090"
+  shutdown_timeout: 45s
+pagination:
+  default: 10
+  max: 50
+cors:
+  allowed_origins: ["https://app.example.com"]
+`)
+		t.Setenv("LISTEN_ADDR", ":9191")
+		t.Setenv("CORS_ALLOWED_ORIGINS", "https://a.example.com, https://b.example.com")
+
+		cfg, err := config.Load(path)
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if cfg.Server.Addr != ":9191" {
+			t.Errorf("Expected addr :9191 from the environment, got %s", cfg.Server.Addr)
+		}
+		if cfg.Server.ShutdownTimeout != 45*time.Second {
+			t.Errorf("Expected shutdown timeout 45s from the file, got %s", cfg.Server.ShutdownTimeout)
+		}
+		if cfg.Pagination.Default != 10 || cfg.Pagination.Max != 50 {
+			t.Errorf("Expected pagination 10/50 from the file, got %d/%d", cfg.Pagination.Default, cfg.Pagination.Max)
+		}
+		if len(cfg.CORS.AllowedOrigins) != 2 || cfg.CORS.AllowedOrigins[1] != "https://b.example.com" {
+			t.Errorf("Expected the origins of the environment, got %v", cfg.CORS.AllowedOrigins)
+		}
+		if cfg.GraphQL.QueryCacheSize != config.Default().GraphQL.QueryCacheSize {
+			t.Errorf("Expected the default query cache size, got %d", cfg.GraphQL.QueryCacheSize)
+		}
+	})
+
+	t.Run("unknown key is rejected", func(t *testing.T) {
+		path := writeConfig(t, "server:\n  adress: \":9090\"\n")
+		if _, err := config.Load(path); err == nil || !strings.Contains(err.Error(), "adress") {
+			t.Errorf("Expected an unknown field error, got %v", err)
+		}
+	})
+
+	t.Run("invalid environment variable", func(t *testing.T) {
+		t.Setenv("JOB_WORKERS", "many")
+		if _, err := config.Load(""); err == nil || !strings.Contains(err.Error(), "JOB_WORKERS") {
+			t.Errorf("Expected an error naming JOB_WORKERS, got %v", err)
+		}
+	})
+
+	t.Run("every problem is reported", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Server.Env = "production"
+		cfg.Pagination.Max = 5
+		cfg.Auth.Mode = config.AuthModeDefaultUser
+		cfg.CORS.AllowedOrigins = []string{"app.example.com"}
+		cfg.Server.TrustedProxies = []string{"10.0.0.0/8", "proxy.internal"}
+
+		err := cfg.Validate()
+		if err == nil {
+			t.Fatal("Expected validation errors")
+		}
+		for _, want := range []string{"pagination.max", "auth.default_user", "production", "cors.allowed_origins", "proxy.internal"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected %q in %v", want, err)
+			}
+		}
+	})
+
+	t.Run("report redacts secrets", func(t *testing.T) {
+		t.Setenv("DATABASE_URL", "postgres://app:hunter2@db:5432/app")
+		t.Setenv("EXPORT_TOKEN_SECRET", "s3cret")
+
+		cfg, err := config.Load("")
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		report := cfg.Report()
+		if strings.Contains(report, "hunter2") || strings.Contains(report, "s3cret") {
+			t.Errorf("Expected secrets to be redacted:\n%s", report)
+		}
+		if !strings.Contains(report, "env EXPORT_TOKEN_SECRET") {
+			t.Errorf("Expected the source of export.token_secret:\n%s", report)
+		}
+	})
+}
+
+func TestMaxPaginationLimit(t *testing.T) {
+	query := `query Elements($limit: Int) { elements(limit: $limit) { edges { node { uri } } pageInfo { hasNextPage } totalCount } }`
+
+	var capped struct {
+		Elements model.ElementConnection `json:"elements"`
+	}
+	c := client.New(middleware.Auth(server.NewGraphQLHandler(testDB, server.Options{MaxPaginationLimit: 1})))
+	if err := c.Post(query, &capped, client.Var("limit", 10), client.AddHeader(middleware.AuthHeader, testUserID)); err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if len(capped.Elements.Edges) != 1 {
+		t.Errorf("Expected the page to be capped at 1 element, got %d", len(capped.Elements.Edges))
+	}
+	if capped.Elements.TotalCount > 1 && !capped.Elements.PageInfo.HasNextPage {
+		t.Error("Expected a next page")
+	}
+
+	// The limit of one server doesn't leak into the others of the process
+	resp := executeGraphQL(t, query, map[string]any{"limit": 10})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("GraphQL errors: %v", resp.Errors)
+	}
+	data := struct {
+		model.ElementConnection `json:"elements"`
+	}{}
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		t.Fatalf("Failed to unmarshal data: %v", err)
+	}
+	if want := min(data.TotalCount, 10); int32(len(data.Edges)) != want {
+		t.Errorf("Expected %d elements from the default server, got %d", want, len(data.Edges))
+	}
+}
+
+func TestCORS(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	srv := httptest.NewServer(middleware.CORS([]string{"https://app.example.com"}, 10*time.Minute, next))
+	defer srv.Close()
+
+	request := func(method, origin string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL, nil)
+		req.Header.Set("Origin", origin)
+		if method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	t.Run("preflight of allowed origin", func(t *testing.T) {
+		resp := request(http.MethodOptions, "https://app.example.com")
+		if resp.StatusCode != http.StatusNoContent {
+			t.Errorf("Expected status 204, got %d", resp.StatusCode)
+		}
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+			t.Errorf("Expected the origin to be allowed, got %q", got)
+		}
+		if !strings.Contains(resp.Header.Get("Access-Control-Allow-Headers"), middleware.AuthHeader) {
+			t.Errorf("Expected %s to be allowed, got %q", middleware.AuthHeader, resp.Header.Get("Access-Control-Allow-Headers"))
+		}
+		if got := resp.Header.Get("Access-Control-Max-Age"); got != "600" {
+			t.Errorf("Expected max age 600, got %q", got)
+		}
+	})
+
+	t.Run("other origin", func(t *testing.T) {
+		resp := request(http.MethodPost, "https://evil.example.com")
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("Expected no CORS headers, got %q", got)
+		}
+	})
+}
+
+func TestClientIP(t *testing.T) {
+	cfg := config.Default()
+	cfg.Server.TrustedProxies = []string{"10.0.0.0/8", "192.168.1.1"}
+
+	var got string
+	handler := middleware.RequestInfo(cfg.Server.TrustedProxyPrefixes(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = r.Context().Value(models.ClientIPKey).(string)
+	}))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{"without proxy", "203.0.113.5:4000", nil, "203.0.113.5"},
+		{"forged by a client", "203.0.113.5:4000", []string{"198.51.100.1"}, "203.0.113.5"},
+		{"through a trusted proxy", "10.1.2.3:4000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"forged behind a trusted proxy", "10.1.2.3:4000", []string{"1.1.1.1, 198.51.100.1"}, "198.51.100.1"},
+		{"through trusted proxies", "10.1.2.3:4000", []string{"198.51.100.1, 192.168.1.1", "10.9.9.9"}, "198.51.100.1"},
+		{"only trusted hops", "10.1.2.3:4000", []string{"10.4.4.4"}, "10.4.4.4"},
+		{"invalid hop", "10.1.2.3:4000", []string{"198.51.100.1, unknown"}, "10.1.2.3"},
+		{"IPv4 mapped proxy", "[::ffff:10.1.2.3]:4000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"IPv6 client", "10.1.2.3:4000", []string{"2001:db8::1"}, "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, forwarded := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", forwarded)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("Expected client IP %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
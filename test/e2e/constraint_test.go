@@ -0,0 +1,197 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestFieldConstraints(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UnixMilli()
+
+	fields := []string{
+		fmt.Sprintf(`INSERT INTO fields (uri, name, field_type, type_uri, creation_date, author, options, required, constraints) VALUES ('field:test-9', 'Test SKU Field', 'text', 'type:test-1', %d, '%s', null, false, '{"unique":"type","pattern":"^SKU-[0-9]+$"}')`, now, testUserID),
+		fmt.Sprintf(`INSERT INTO fields (uri, name, field_type, type_uri, creation_date, author, options, required, constraints) VALUES ('field:test-10', 'Test Price Field', 'number', 'type:test-1', %d, '%s', null, false, '{"min":0,"max":1000}')`, now, testUserID),
+	}
+	t.Cleanup(func() {
+		testDB.Exec(ctx, `DELETE FROM elements WHERE uri IN ('element:test-11', 'element:test-12')`)
+		testDB.Exec(ctx, `DELETE FROM fields WHERE uri IN ('field:test-9', 'field:test-10')`)
+	})
+	for _, q := range fields {
+		if _, err := testDB.Exec(ctx, q); err != nil {
+			t.Fatalf("Failed to insert field: %v", err)
+		}
+	}
+
+	createMutation := `
+		mutation CreateElement($input: CreateElementInput!) {
+			createElement(input: $input) {
+				uri
+				title
+				fieldValues { field { uri } value }
+			}
+		}
+	`
+
+	value := func(uri string, v any) map[string]any {
+		return map[string]any{"fieldUri": uri, "value": v}
+	}
+
+	// fieldErrors returns the rejected fields of a VALIDATION error by
+	// constraint.
+	fieldErrors := func(t *testing.T, errs []map[string]any) map[string]string {
+		t.Helper()
+
+		byField := make(map[string]string)
+		for _, e := range errs {
+			byField[e["fieldUri"].(string)] = e["constraint"].(string)
+		}
+		return byField
+	}
+
+	validationErrors := func(t *testing.T, query string, variables map[string]any) []map[string]any {
+		t.Helper()
+
+		resp := executeGraphQL(t, query, variables)
+		if len(resp.Errors) == 0 {
+			t.Fatal("Expected a validation error")
+		}
+		gqlErr := resp.Errors[0]
+		if gqlErr.Extensions["code"] != "VALIDATION" {
+			t.Fatalf("Expected VALIDATION, got %v: %s", gqlErr.Extensions["code"], gqlErr.Message)
+		}
+
+		raw, _ := json.Marshal(gqlErr.Extensions["fieldErrors"])
+		var errs []map[string]any
+		if err := json.Unmarshal(raw, &errs); err != nil || len(errs) == 0 {
+			t.Fatalf("Expected fieldErrors in extensions, got %v", gqlErr.Extensions)
+		}
+		return errs
+	}
+
+	t.Run("creates a valid element", func(t *testing.T) {
+		resp := executeGraphQL(t, createMutation, map[string]any{"input": map[string]any{
+			"uri":      "element:test-11",
+			"typeUri":  "type:test-1",
+			"spaceUri": "space:test-1",
+			"title":    "Constrained Element",
+			"fieldValues": []any{
+				value("field:test-1", "Constrained"),
+				value("field:test-3", 7),
+				value("field:test-9", "SKU-1"),
+				value("field:test-10", 99.5),
+			},
+		}})
+		if len(resp.Errors) > 0 {
+			t.Fatalf("GraphQL errors: %v", resp.Errors)
+		}
+
+		data := struct {
+			CreateElement struct {
+				URI         string `json:"uri"`
+				FieldValues []struct {
+					Field struct {
+						URI string `json:"uri"`
+					} `json:"field"`
+					Value any `json:"value"`
+				} `json:"fieldValues"`
+			} `json:"createElement"`
+		}{}
+		if err := json.Unmarshal(resp.Data, &data); err != nil {
+			t.Fatalf("Failed to unmarshal data: %v", err)
+		}
+
+		values := make(map[string]any)
+		for _, fv := range data.CreateElement.FieldValues {
+			values[fv.Field.URI] = fv.Value
+		}
+		if values["field:test-9"] != "SKU-1" || values["field:test-10"] != 99.5 {
+			t.Errorf("Expected the given field values, got %v", values)
+		}
+	})
+
+	t.Run("reports every rejected value", func(t *testing.T) {
+		errs := validationErrors(t, createMutation, map[string]any{"input": map[string]any{
+			"uri":      "element:test-12",
+			"typeUri":  "type:test-1",
+			"spaceUri": "space:test-1",
+			"title":    "Invalid Element",
+			"fieldValues": []any{
+				value("field:test-1", "Invalid"),
+				value("field:test-9", "SKU-1"),
+				value("field:test-10", -1),
+			},
+		}})
+
+		got := fieldErrors(t, errs)
+		want := map[string]string{
+			"field:test-3":  "required",
+			"field:test-9":  "unique",
+			"field:test-10": "min",
+		}
+		for field, constraint := range want {
+			if got[field] != constraint {
+				t.Errorf("Expected %s to fail %s, got %v", field, constraint, got)
+			}
+		}
+
+		var count int
+		testDB.QueryRow(ctx, `SELECT count(*) FROM elements WHERE uri = 'element:test-12'`).Scan(&count)
+		if count != 0 {
+			t.Error("Expected the rejected element not to be created")
+		}
+	})
+
+	updateMutation := `
+		mutation UpdateElement($input: UpdateElementInput!) {
+			updateElement(input: $input) {
+				fieldValues { field { uri } value }
+			}
+		}
+	`
+
+	t.Run("checks updated values", func(t *testing.T) {
+		errs := validationErrors(t, updateMutation, map[string]any{"input": map[string]any{
+			"uri": "element:test-11",
+			"fieldValues": []any{
+				value("field:test-9", "sku 1"),
+				value("field:test-10", 1001),
+				value("field:test-3", nil),
+			},
+		}})
+
+		got := fieldErrors(t, errs)
+		want := map[string]string{
+			"field:test-3":  "required",
+			"field:test-9":  "pattern",
+			"field:test-10": "max",
+		}
+		for field, constraint := range want {
+			if got[field] != constraint {
+				t.Errorf("Expected %s to fail %s, got %v", field, constraint, got)
+			}
+		}
+	})
+
+	t.Run("allows keeping a unique value", func(t *testing.T) {
+		resp := executeGraphQL(t, updateMutation, map[string]any{"input": map[string]any{
+			"uri": "element:test-11",
+			"fieldValues": []any{
+				value("field:test-9", "SKU-1"),
+				value("field:test-10", nil),
+			},
+		}})
+		if len(resp.Errors) > 0 {
+			t.Fatalf("GraphQL errors: %v", resp.Errors)
+		}
+
+		var count int
+		testDB.QueryRow(ctx, `SELECT count(*) FROM element_field_values WHERE element_uri = 'element:test-11' AND field_uri = 'field:test-10'`).Scan(&count)
+		if count != 0 {
+			t.Error("Expected the cleared value to be removed")
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDateTimeScalar(t *testing.T) {
+	createTestElement(t, "element:test-8", "Test Element 8", "Dated text")
+
+	dueDate := time.Date(2024, time.January, 15, 10, 30, 0, 0, time.UTC)
+	if _, err := testDB.Exec(context.Background(),
+		`INSERT INTO element_field_values (uri, element_uri, field_uri, value_date, creation_date, updated_date) VALUES ('efv:test-8-4', 'element:test-8', 'field:test-4', $1, 0, 0)`,
+		dueDate.UnixMilli(),
+	); err != nil {
+		t.Fatalf("Failed to insert date field value: %v", err)
+	}
+
+	resp := executeGraphQL(t, `
+		query Element($uri: ID!) {
+			element(uri: $uri) {
+				creationDate
+				fieldValues { field { uri } value }
+			}
+		}
+	`, map[string]any{"uri": "element:test-8"})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("GraphQL errors: %v", resp.Errors)
+	}
+
+	data := struct {
+		Element struct {
+			CreationDate string `json:"creationDate"`
+			FieldValues  []struct {
+				Field struct {
+					URI string `json:"uri"`
+				} `json:"field"`
+				Value any `json:"value"`
+			} `json:"fieldValues"`
+		} `json:"element"`
+	}{}
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		t.Fatalf("Failed to unmarshal data: %v", err)
+	}
+
+	creationDate, err := time.Parse(time.RFC3339, data.Element.CreationDate)
+	if err != nil {
+		t.Fatalf("Expected RFC 3339 creation date, got %q", data.Element.CreationDate)
+	}
+	if time.Since(creationDate) > time.Minute {
+		t.Errorf("Expected a recent creation date, got %s", creationDate)
+	}
+
+	var found bool
+	for _, fv := range data.Element.FieldValues {
+		if fv.Field.URI != "field:test-4" {
+			continue
+		}
+		found = true
+		if fv.Value != "2024-01-15T10:30:00.000Z" {
+			t.Errorf("Expected date value '2024-01-15T10:30:00.000Z', got %v", fv.Value)
+		}
+	}
+	if !found {
+		t.Error("Expected the date field value to be returned")
+	}
+
+	// DateTime inputs accept RFC 3339 as well as epoch milliseconds.
+	for _, ts := range []any{time.Now().Add(time.Minute).Format(time.RFC3339), time.Now().Add(time.Minute).UnixMilli()} {
+		resp := executeGraphQL(t, `
+			query ElementAt($uri: ID!, $timestamp: DateTime!) {
+				elementAt(uri: $uri, timestamp: $timestamp) { uri }
+			}
+		`, map[string]any{"uri": "element:test-8", "timestamp": ts})
+		if len(resp.Errors) > 0 {
+			t.Errorf("GraphQL errors for timestamp %v: %v", ts, resp.Errors)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestFieldDefaults(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UnixMilli()
+
+	fields := []string{
+		fmt.Sprintf(`INSERT INTO fields (uri, name, field_type, type_uri, creation_date, author, options, required) VALUES ('field:test-11', 'Test Default Select', 'select', 'type:test-1', %d, '%s', '{"options":["low","high"],"default":"high"}', false)`, now, testUserID),
+		fmt.Sprintf(`INSERT INTO fields (uri, name, field_type, type_uri, creation_date, author, options, required) VALUES ('field:test-12', 'Test Default Date', 'date', 'type:test-1', %d, '%s', '{"autogen":"today"}', false)`, now, testUserID),
+		fmt.Sprintf(`INSERT INTO fields (uri, name, field_type, type_uri, creation_date, author, options, required) VALUES ('field:test-13', 'Test Sequence', 'text', 'type:test-1', %d, '%s', '{"autogen":"sequence","prefix":"T-","digits":3}', false)`, now, testUserID),
+		fmt.Sprintf(`INSERT INTO fields (uri, name, field_type, type_uri, creation_date, author, options, required) VALUES ('field:test-14', 'Test Owner', 'text', 'type:test-1', %d, '%s', '{"autogen":"currentUser"}', false)`, now, testUserID),
+	}
+	t.Cleanup(func() {
+		testDB.Exec(ctx, `DELETE FROM elements WHERE uri IN ('element:test-13', 'element:test-14')`)
+		testDB.Exec(ctx, `DELETE FROM fields WHERE uri IN ('field:test-11', 'field:test-12', 'field:test-13', 'field:test-14')`)
+	})
+	for _, q := range fields {
+		if _, err := testDB.Exec(ctx, q); err != nil {
+			t.Fatalf("Failed to insert field: %v", err)
+		}
+	}
+
+	create := func(t *testing.T, uri string, extra ...map[string]any) map[string]any {
+		t.Helper()
+
+		fieldValues := []any{
+			map[string]any{"fieldUri": "field:test-1", "value": "Defaults"},
+			map[string]any{"fieldUri": "field:test-3", "value": 1},
+		}
+		for _, fv := range extra {
+			fieldValues = append(fieldValues, fv)
+		}
+
+		resp := executeGraphQL(t, `
+			mutation CreateElement($input: CreateElementInput!) {
+				createElement(input: $input) {
+					fieldValues { field { uri } value }
+				}
+			}
+		`, map[string]any{"input": map[string]any{
+			"uri":         uri,
+			"typeUri":     "type:test-1",
+			"spaceUri":    "space:test-1",
+			"title":       "Defaults Element",
+			"fieldValues": fieldValues,
+		}})
+		if len(resp.Errors) > 0 {
+			t.Fatalf("GraphQL errors: %v", resp.Errors)
+		}
+
+		data := struct {
+			CreateElement struct {
+				FieldValues []struct {
+					Field struct {
+						URI string `json:"uri"`
+					} `json:"field"`
+					Value any `json:"value"`
+				} `json:"fieldValues"`
+			} `json:"createElement"`
+		}{}
+		if err := json.Unmarshal(resp.Data, &data); err != nil {
+			t.Fatalf("Failed to unmarshal data: %v", err)
+		}
+
+		values := make(map[string]any)
+		for _, fv := range data.CreateElement.FieldValues {
+			values[fv.Field.URI] = fv.Value
+		}
+		return values
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour).Format("2006-01-02T15:04:05.000Z")
+
+	values := create(t, "element:test-13")
+	want := map[string]any{
+		"field:test-11": "high",
+		"field:test-12": today,
+		"field:test-13": "T-001",
+		"field:test-14": testUserID,
+	}
+	for field, v := range want {
+		if values[field] != v {
+			t.Errorf("Expected %s to default to %v, got %v", field, v, values[field])
+		}
+	}
+
+	// given values, even null, take precedence over defaults while sequences
+	// keep counting
+	values = create(t, "element:test-14",
+		map[string]any{"fieldUri": "field:test-11", "value": nil},
+		map[string]any{"fieldUri": "field:test-14", "value": "user:someone-else"},
+	)
+	if v, ok := values["field:test-11"]; ok {
+		t.Errorf("Expected no value for field:test-11, got %v", v)
+	}
+	if values["field:test-13"] != "T-002" {
+		t.Errorf("Expected the next sequence number T-002, got %v", values["field:test-13"])
+	}
+	if values["field:test-14"] != "user:someone-else" {
+		t.Errorf("Expected the given owner, got %v", values["field:test-14"])
+	}
+}
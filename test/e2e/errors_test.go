@@ -0,0 +1,66 @@
+package e2e
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestErrorCodes(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]any
+		code      string
+	}{
+		{
+			name:      "unknown element",
+			query:     `query Element($uri: ID!) { element(uri: $uri) { uri } }`,
+			variables: map[string]any{"uri": "element:does-not-exist"},
+			code:      "NOT_FOUND",
+		},
+		{
+			name:      "element in inaccessible space",
+			query:     `query Element($uri: ID!) { element(uri: $uri) { uri } }`,
+			variables: map[string]any{"uri": "element:test-3"},
+			code:      "NOT_FOUND",
+		},
+		{
+			name:      "invalid filter value",
+			query:     `query Elements($filter: FieldValueFilter) { elements(fieldValueFilter: $filter) { totalCount } }`,
+			variables: map[string]any{"filter": map[string]any{"fieldUri": "field:test-3", "value": "abc", "valueType": "NUMBER"}},
+			code:      "VALIDATION",
+		},
+		{
+			name:      "invalid timestamp",
+			query:     `query ElementAt($uri: ID!, $ts: DateTime!) { elementAt(uri: $uri, timestamp: $ts) { uri } }`,
+			variables: map[string]any{"uri": "element:test-1", "ts": "yesterday"},
+			code:      "VALIDATION",
+		},
+		{
+			name:      "audit log of tenant without admin role",
+			query:     `query AuditLog($tenantUri: ID!) { auditLog(tenantUri: $tenantUri) { totalCount } }`,
+			variables: map[string]any{"tenantUri": "tenant:acme"},
+			code:      "FORBIDDEN",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := executeGraphQL(t, tt.query, tt.variables)
+			if len(resp.Errors) != 1 {
+				t.Fatalf("Expected a single error, got %v", resp.Errors)
+			}
+
+			gqlErr := resp.Errors[0]
+			if gqlErr.Extensions["code"] != tt.code {
+				t.Errorf("Expected error code %s, got %v", tt.code, gqlErr.Extensions["code"])
+			}
+			if gqlErr.Extensions["requestId"] == "" {
+				t.Error("Expected a request ID in error extensions")
+			}
+			if strings.Contains(gqlErr.Message, "no rows in result set") {
+				t.Errorf("Expected database details to be hidden, got %q", gqlErr.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package e2e
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exportRequest downloads an export from path, authenticated as the test user
+// unless authenticate is false.
+func exportRequest(t *testing.T, path string, authenticate bool) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest("GET", testServer.URL+path, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	if authenticate {
+		req.Header.Set("X-User-ID", testUserID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to execute request: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestExportElements(t *testing.T) {
+	t.Run("csv has a column per field", func(t *testing.T) {
+		resp := exportRequest(t, "/export?typeUri=type:test-1&format=csv", true)
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			t.Fatalf("Expected status 200, got %d: %s", resp.StatusCode, body)
+		}
+		if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+			t.Errorf("Expected a CSV content type, got %q", ct)
+		}
+
+		records, err := csv.NewReader(resp.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("Failed to read CSV: %v", err)
+		}
+		header := records[0]
+		if strings.Join(header[:5], ",") != "uri,title,spaceUri,author,creationDate" {
+			t.Errorf("Unexpected leading columns: %v", header)
+		}
+
+		column := map[string]int{}
+		for i, name := range header {
+			column[name] = i
+		}
+		found := false
+		for _, record := range records[1:] {
+			if record[0] != "element:test-1" {
+				continue
+			}
+			found = true
+			if got := record[column["Test Text Field"]]; got != "Hello World" {
+				t.Errorf("Expected the text value in its column, got %q", got)
+			}
+			if got := record[column["Test Number Field"]]; got != "42.5" {
+				t.Errorf("Expected the number value in its column, got %q", got)
+			}
+		}
+		if !found {
+			t.Error("Expected element:test-1 to be exported")
+		}
+	})
+
+	t.Run("ndjson applies the filter", func(t *testing.T) {
+		filter := `{"fieldValueFilter": {"fieldUri": "field:test-1", "value": "Hello World", "valueType": "TEXT"}}`
+		resp := exportRequest(t, "/export?typeUri=type:test-1&format=ndjson&filter="+url.QueryEscape(filter), true)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+
+		var lines []map[string]any
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			var line map[string]any
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				t.Fatalf("Failed to parse line %q: %v", scanner.Text(), err)
+			}
+			lines = append(lines, line)
+		}
+		if len(lines) != 1 || lines[0]["uri"] != "element:test-1" {
+			t.Fatalf("Expected only element:test-1, got %v", lines)
+		}
+		values, _ := lines[0]["fieldValues"].(map[string]any)
+		if values["field:test-3"] != 42.5 {
+			t.Errorf("Expected field:test-3 to be 42.5, got %v", values["field:test-3"])
+		}
+	})
+
+	t.Run("requests need a user", func(t *testing.T) {
+		if resp := exportRequest(t, "/export?typeUri=type:test-1", false); resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected status 401, got %d", resp.StatusCode)
+		}
+		if resp := exportRequest(t, "/export?token=invalid", false); resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 for an invalid token, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("download tokens authenticate the link", func(t *testing.T) {
+		resp := executeGraphQL(t, `
+			mutation { createExportToken(typeUri: "type:test-1", format: NDJSON) { url expiresAt } }
+		`, nil)
+		if len(resp.Errors) > 0 {
+			t.Fatalf("GraphQL errors: %v", resp.Errors)
+		}
+
+		data := struct {
+			CreateExportToken struct {
+				URL string `json:"url"`
+			} `json:"createExportToken"`
+		}{}
+		if err := json.Unmarshal(resp.Data, &data); err != nil {
+			t.Fatalf("Failed to unmarshal data: %v", err)
+		}
+
+		download := exportRequest(t, data.CreateExportToken.URL, false)
+		if download.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", download.StatusCode)
+		}
+		if ct := download.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+			t.Errorf("Expected an NDJSON content type, got %q", ct)
+		}
+	})
+}
+
+func TestExportTypeOfOtherSpace(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UnixMilli()
+
+	queries := []string{
+		fmt.Sprintf(`INSERT INTO types (uri, name, space_uri, creation_date, author) VALUES ('type:test-hidden', 'Hidden Type', 'space:test-2', %d, '%s')`, now, testUserID),
+		fmt.Sprintf(`INSERT INTO fields (uri, name, field_type, type_uri, creation_date, author, options, required) VALUES ('field:test-hidden', 'Hidden Field', 'text', 'type:test-hidden', %d, '%s', null, false)`, now, testUserID),
+	}
+	t.Cleanup(func() {
+		testDB.Exec(ctx, `DELETE FROM fields WHERE uri = 'field:test-hidden'`)
+		testDB.Exec(ctx, `DELETE FROM types WHERE uri = 'type:test-hidden'`)
+	})
+	for _, q := range queries {
+		if _, err := testDB.Exec(ctx, q); err != nil {
+			t.Fatalf("Failed to execute query %q: %v", q, err)
+		}
+	}
+
+	t.Run("exports are not found", func(t *testing.T) {
+		resp := exportRequest(t, "/export?typeUri=type:test-hidden&format=csv", true)
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d: %s", resp.StatusCode, body)
+		}
+		if strings.Contains(string(body), "Hidden Field") {
+			t.Errorf("Expected the fields of the type to stay hidden, got %s", body)
+		}
+	})
+
+	t.Run("download tokens are not created", func(t *testing.T) {
+		resp := executeGraphQL(t, `
+			mutation { createExportToken(typeUri: "type:test-hidden", format: CSV) { url } }
+		`, nil)
+		expectCode(t, resp, "NOT_FOUND")
+	})
+}
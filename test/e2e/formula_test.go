@@ -0,0 +1,125 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormulaFields(t *testing.T) {
+	ctx := context.Background()
+
+	if _, err := testDB.Exec(ctx, fmt.Sprintf(
+		`INSERT INTO fields (uri, name, field_type, type_uri, creation_date, author, options, required) VALUES ('field:test-6', 'Test Formula Field', 'formula', 'type:test-1', %d, '%s', '{"expression": "test_number_field * 2"}', false)`,
+		time.Now().UnixMilli(), testUserID,
+	)); err != nil {
+		t.Fatalf("Failed to insert formula field: %v", err)
+	}
+	t.Cleanup(func() {
+		testDB.Exec(ctx, `DELETE FROM fields WHERE uri = 'field:test-6'`)
+	})
+
+	query := `
+		query Element($uri: ID!) {
+			element(uri: $uri) {
+				fieldValues {
+					field { uri resultType }
+					value
+					typedValue { ... on NumberValue { number } }
+				}
+			}
+		}
+	`
+	resp := executeGraphQL(t, query, map[string]any{"uri": "element:test-1"})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("GraphQL errors: %v", resp.Errors)
+	}
+
+	data := struct {
+		Element struct {
+			FieldValues []struct {
+				Field struct {
+					URI        string  `json:"uri"`
+					ResultType *string `json:"resultType"`
+				} `json:"field"`
+				Value      any            `json:"value"`
+				TypedValue map[string]any `json:"typedValue"`
+			} `json:"fieldValues"`
+		} `json:"element"`
+	}{}
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		t.Fatalf("Failed to unmarshal data: %v", err)
+	}
+
+	var found bool
+	for _, fv := range data.Element.FieldValues {
+		if fv.Field.URI != "field:test-6" {
+			continue
+		}
+		found = true
+		if fv.Field.ResultType == nil || *fv.Field.ResultType != "NUMBER" {
+			t.Errorf("Expected result type NUMBER, got %v", fv.Field.ResultType)
+		}
+		if fv.Value != 85.0 {
+			t.Errorf("Expected formula value 85, got %v", fv.Value)
+		}
+		if fv.TypedValue["number"] != 85.0 {
+			t.Errorf("Expected typed number 85, got %v", fv.TypedValue)
+		}
+	}
+	if !found {
+		t.Fatal("Expected the formula field value to be returned")
+	}
+
+	// A formula referencing itself is left out and reported, the other
+	// formulas of the type are still computed.
+	if _, err := testDB.Exec(ctx, fmt.Sprintf(
+		`INSERT INTO fields (uri, name, field_type, type_uri, creation_date, author, options, required) VALUES ('field:test-7', 'Test Other Formula Field', 'formula', 'type:test-1', %d, '%s', '{"expression": "test_number_field + 1"}', false)`,
+		time.Now().UnixMilli(), testUserID,
+	)); err != nil {
+		t.Fatalf("Failed to insert formula field: %v", err)
+	}
+	t.Cleanup(func() {
+		testDB.Exec(ctx, `DELETE FROM fields WHERE uri = 'field:test-7'`)
+	})
+	if _, err := testDB.Exec(ctx,
+		`UPDATE fields SET options = '{"expression": "test_formula_field + 1"}' WHERE uri = 'field:test-6'`,
+	); err != nil {
+		t.Fatalf("Failed to update formula field: %v", err)
+	}
+
+	resp = executeGraphQL(t, query, map[string]any{"uri": "element:test-1"})
+	if len(resp.Errors) != 1 {
+		t.Fatalf("Expected a single error for a cyclic formula, got %v", resp.Errors)
+	}
+	if code := resp.Errors[0].Extensions["code"]; code != "VALIDATION" {
+		t.Errorf("Expected error code VALIDATION, got %v", code)
+	}
+	if field := resp.Errors[0].Extensions["field"]; field != "field:test-6" {
+		t.Errorf("Expected the error to name field:test-6, got %v", field)
+	}
+	if !strings.Contains(resp.Errors[0].Message, "cycle") {
+		t.Errorf("Expected the error to mention the cycle, got %q", resp.Errors[0].Message)
+	}
+
+	data.Element.FieldValues = nil
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		t.Fatalf("Failed to unmarshal data: %v", err)
+	}
+	values := make(map[string]any)
+	for _, fv := range data.Element.FieldValues {
+		values[fv.Field.URI] = fv.Value
+	}
+	if _, ok := values["field:test-6"]; ok {
+		t.Error("Expected the value of the cyclic formula to be left out")
+	}
+	if values["field:test-7"] != 43.5 {
+		t.Errorf("Expected the other formula to compute 43.5, got %v", values["field:test-7"])
+	}
+	if _, ok := values["field:test-1"]; !ok {
+		t.Error("Expected the stored field values to be returned")
+	}
+}
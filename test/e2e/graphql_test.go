@@ -15,6 +15,7 @@ import (
 	"github.com/99designs/gqlgen/graphql"
 	"github.com/bamdadam/backend/graph/model"
 	"github.com/bamdadam/backend/src/middleware"
+	"github.com/bamdadam/backend/src/migrate"
 	"github.com/bamdadam/backend/src/server"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -23,6 +24,8 @@ var (
 	testServer *httptest.Server
 	testDB     *pgxpool.Pool
 	testUserID = "user:test-user-1"
+	// testReaderID can only read space:test-1
+	testReaderID = "user:test-reader"
 )
 
 func TestMain(m *testing.M) {
@@ -45,6 +48,16 @@ func TestMain(m *testing.M) {
 		os.Exit(1)
 	}
 
+	migrator, err := migrate.New(testDB)
+	if err != nil {
+		fmt.Printf("Failed to load migrations: %v\n", err)
+		os.Exit(1)
+	}
+	if _, err := migrator.Up(ctx, 0); err != nil {
+		fmt.Printf("Failed to migrate test database: %v\n", err)
+		os.Exit(1)
+	}
+
 	if err := setupTestData(ctx); err != nil {
 		fmt.Printf("Failed to setup test data: %v\n", err)
 		os.Exit(1)
@@ -52,8 +65,17 @@ func TestMain(m *testing.M) {
 
 	testServer = setupTestServer()
 
+	workersCtx, stopWorkers := context.WithCancel(ctx)
+	workersDone := make(chan struct{})
+	go func() {
+		server.RunWorkers(workersCtx, testDB, testOptions)
+		close(workersDone)
+	}()
+
 	code := m.Run()
 
+	stopWorkers()
+	<-workersDone
 	testServer.Close()
 	cleanupTestData(ctx)
 	testDB.Close()
@@ -61,10 +83,15 @@ func TestMain(m *testing.M) {
 	os.Exit(code)
 }
 
+// testOptions lets webhooks call the receivers the tests start on loopback.
+var testOptions = server.Options{WebhookAllowPrivateNetworks: true}
+
 func setupTestServer() *httptest.Server {
-	graphqlHandler := server.NewGraphQLHandler(testDB)
+	graphqlHandler := server.NewGraphQLHandler(testDB, testOptions)
 	mux := http.NewServeMux()
-	mux.Handle("/graphql", middleware.Auth(graphqlHandler))
+	mux.Handle("/graphql", middleware.RequestInfo(nil, middleware.Auth(graphqlHandler)))
+	mux.Handle("/export", middleware.RequestInfo(nil, server.NewExportHandler(testDB, server.Options{})))
+	mux.Handle("/tenants/{tenantUri}/graphql", middleware.RequestInfo(nil, middleware.Auth(server.NewTypeSchemaHandler(testDB, server.Options{}))))
 
 	return httptest.NewServer(mux)
 }
@@ -80,13 +107,21 @@ func setupTestData(ctx context.Context) error {
 		fmt.Sprintf(`INSERT INTO spaces (uri, name, tenant_uri, creation_date) VALUES ('space:test-1', 'Test Space', 'tenant:test-1', %d) ON CONFLICT (uri) DO NOTHING`, now),
 		fmt.Sprintf(`INSERT INTO spaces (uri, name, tenant_uri, creation_date) VALUES ('space:test-2', 'Test Space', 'tenant:test-1', %d) ON CONFLICT (uri) DO NOTHING`, now),
 
+		`INSERT INTO user_tenants (user_uri, tenant_uri, role) VALUES ('user:test-user-1', 'tenant:test-1', 'admin') ON CONFLICT DO NOTHING`,
 		`INSERT INTO user_spaces (user_uri, space_uri) VALUES ('user:test-user-1', 'space:test-1') ON CONFLICT DO NOTHING`,
+		`INSERT INTO user_space_permissions (user_uri, space_uri, verb_uri) VALUES ('user:test-user-1', 'space:test-1', 'verb:write') ON CONFLICT DO NOTHING`,
+
+		fmt.Sprintf(`INSERT INTO users (uri, email, display_name) VALUES ('%s', 'test-reader@example.com', 'Test Reader') ON CONFLICT (uri) DO NOTHING`, testReaderID),
+		fmt.Sprintf(`INSERT INTO user_tenants (user_uri, tenant_uri, role) VALUES ('%s', 'tenant:test-1', 'member') ON CONFLICT DO NOTHING`, testReaderID),
+		fmt.Sprintf(`INSERT INTO user_spaces (user_uri, space_uri) VALUES ('%s', 'space:test-1') ON CONFLICT DO NOTHING`, testReaderID),
+		fmt.Sprintf(`INSERT INTO user_space_permissions (user_uri, space_uri, verb_uri) VALUES ('%s', 'space:test-1', 'verb:read') ON CONFLICT DO NOTHING`, testReaderID),
 
 		fmt.Sprintf(`INSERT INTO types (uri, name, space_uri, creation_date, author) VALUES ('type:test-1', 'Test Type', 'space:test-1', %d, '%s') ON CONFLICT (uri) DO NOTHING`, now, testUserID),
 
 		fmt.Sprintf(`INSERT INTO fields (uri, name, field_type, type_uri, creation_date, author, options, required) VALUES ('field:test-1', 'Test Text Field', 'text', 'type:test-1', %d, '%s', null, true) ON CONFLICT (uri) DO NOTHING`, now, testUserID),
 		fmt.Sprintf(`INSERT INTO fields (uri, name, field_type, type_uri, creation_date, author, options, required) VALUES ('field:test-2', 'Test Select Field', 'select', 'type:test-1', %d, '%s', '["option1","option2"]', false) ON CONFLICT (uri) DO NOTHING`, now, testUserID),
 		fmt.Sprintf(`INSERT INTO fields (uri, name, field_type, type_uri, creation_date, author, options, required) VALUES ('field:test-3', 'Test Number Field', 'number', 'type:test-1', %d, '%s', null, true) ON CONFLICT (uri) DO NOTHING`, now, testUserID),
+		fmt.Sprintf(`INSERT INTO fields (uri, name, field_type, type_uri, creation_date, author, options, required) VALUES ('field:test-4', 'Test Date Field', 'date', 'type:test-1', %d, '%s', null, false) ON CONFLICT (uri) DO NOTHING`, now, testUserID),
 
 		fmt.Sprintf(`INSERT INTO elements (uri, title, type_uri, space_uri, creation_date, author) VALUES ('element:test-1', 'Test Element 1', 'type:test-1', 'space:test-1', %d, '%s') ON CONFLICT (uri) DO NOTHING`, now, testUserID),
 		fmt.Sprintf(`INSERT INTO elements (uri, title, type_uri, space_uri, creation_date, author) VALUES ('element:test-2', 'Test Element 2', 'type:test-1', 'space:test-1', %d, '%s') ON CONFLICT (uri) DO NOTHING`, now, testUserID),
@@ -115,14 +150,18 @@ func setupTestData(ctx context.Context) error {
 
 func cleanupTestData(ctx context.Context) {
 	queries := []string{
+		`DELETE FROM outbox WHERE aggregate_uri LIKE 'element:test-%'`,
 		`DELETE FROM element_field_values WHERE uri LIKE 'efv:test-%'`,
 		`DELETE FROM elements WHERE uri LIKE 'element:test-%'`,
 		`DELETE FROM fields WHERE uri LIKE 'field:test-%'`,
 		`DELETE FROM types WHERE uri LIKE 'type:test-%'`,
-		`DELETE FROM user_spaces WHERE user_uri = 'user:test-user-1'`,
+		`DELETE FROM jobs WHERE user_uri = 'user:test-user-1'`,
+		`DELETE FROM user_space_permissions WHERE user_uri IN ('user:test-user-1', 'user:test-reader')`,
+		`DELETE FROM user_spaces WHERE user_uri IN ('user:test-user-1', 'user:test-reader')`,
+		`DELETE FROM user_tenants WHERE user_uri IN ('user:test-user-1', 'user:test-reader')`,
 		`DELETE FROM spaces WHERE uri LIKE 'space:test-%'`,
 		`DELETE FROM tenants WHERE uri LIKE 'tenant:test-%'`,
-		`DELETE FROM users WHERE uri = 'user:test-user-1'`,
+		`DELETE FROM users WHERE uri IN ('user:test-user-1', 'user:test-reader')`,
 	}
 
 	for _, q := range queries {
@@ -130,8 +169,39 @@ func cleanupTestData(ctx context.Context) {
 	}
 }
 
+// createTestElement inserts an element with a single text field value that is
+// removed again when the test finishes, so tests mutating it don't affect the
+// counts asserted elsewhere.
+func createTestElement(t *testing.T, uri, title, text string) {
+	t.Helper()
+
+	ctx := context.Background()
+	now := time.Now().UnixMilli()
+
+	queries := []string{
+		fmt.Sprintf(`INSERT INTO elements (uri, title, type_uri, space_uri, creation_date, author) VALUES ('%s', '%s', 'type:test-1', 'space:test-1', %d, '%s')`, uri, title, now, testUserID),
+		fmt.Sprintf(`INSERT INTO element_field_values (uri, element_uri, field_uri, value_text, creation_date, updated_date) VALUES ('efv:%s-1', '%s', 'field:test-1', '%s', %d, %d)`, uri, uri, text, now, now),
+	}
+
+	t.Cleanup(func() {
+		testDB.Exec(ctx, `DELETE FROM elements WHERE uri = $1`, uri)
+	})
+
+	for _, q := range queries {
+		if _, err := testDB.Exec(ctx, q); err != nil {
+			t.Fatalf("Failed to execute query %q: %v", q, err)
+		}
+	}
+}
+
 func executeGraphQL(t *testing.T, query string, variables map[string]any) graphql.Response {
 	t.Helper()
+	return executeGraphQLAs(t, testUserID, query, variables)
+}
+
+// executeGraphQLAs runs a query as the given user.
+func executeGraphQLAs(t *testing.T, userID, query string, variables map[string]any) graphql.Response {
+	t.Helper()
 
 	reqBody := graphql.RawParams{
 		Query:     query,
@@ -149,7 +219,7 @@ func executeGraphQL(t *testing.T, query string, variables map[string]any) graphq
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-User-ID", testUserID)
+	req.Header.Set("X-User-ID", userID)
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
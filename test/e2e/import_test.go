@@ -0,0 +1,220 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+const importCSV = `title,Test Text Field,Test Number Field,Test Select Field,Notes
+Imported Element 1,Imported text,12.5,OPTION2,ignored
+Imported Element 2,,not a number,option1,ignored
+`
+
+type importResult struct {
+	RowCount      int `json:"rowCount"`
+	ImportedCount int `json:"importedCount"`
+	Errors        []struct {
+		Row   int `json:"row"`
+		Error struct {
+			Code        string `json:"code"`
+			FieldErrors []struct {
+				FieldURI   string `json:"fieldUri"`
+				Constraint string `json:"constraint"`
+			} `json:"fieldErrors"`
+		} `json:"error"`
+	} `json:"errors"`
+}
+
+// executeUpload runs a GraphQL request with file uploaded as the variable at
+// path, following the GraphQL multipart request spec.
+func executeUpload(t *testing.T, query string, variables map[string]any, path, file string) graphql.Response {
+	t.Helper()
+	return executeUploadAs(t, testUserID, query, variables, path, file)
+}
+
+// executeUploadAs is executeUpload as the given user.
+func executeUploadAs(t *testing.T, userID, query string, variables map[string]any, path, file string) graphql.Response {
+	t.Helper()
+
+	operations, err := json.Marshal(graphql.RawParams{Query: query, Variables: variables})
+	if err != nil {
+		t.Fatalf("Failed to marshal request: %v", err)
+	}
+
+	var body strings.Builder
+	w := multipart.NewWriter(&body)
+	w.WriteField("operations", string(operations))
+	w.WriteField("map", `{"0": ["`+path+`"]}`)
+	part, err := w.CreateFormFile("0", "import.csv")
+	if err != nil {
+		t.Fatalf("Failed to create form file: %v", err)
+	}
+	part.Write([]byte(file))
+	w.Close()
+
+	req, err := http.NewRequest("POST", testServer.URL+"/graphql", strings.NewReader(body.String()))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	req.Header.Set("X-User-ID", userID)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to execute request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var gqlResp graphql.Response
+	if err := json.NewDecoder(resp.Body).Decode(&gqlResp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return gqlResp
+}
+
+func TestImportElements(t *testing.T) {
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		testDB.Exec(ctx, `DELETE FROM elements WHERE space_uri = 'space:test-1' AND title LIKE 'Imported Element %'`)
+	})
+
+	importElements := func(t *testing.T, dryRun bool) importResult {
+		t.Helper()
+
+		resp := executeUpload(t, `
+			mutation Import($input: ImportElementsInput!) {
+				importElements(input: $input) {
+					rowCount
+					importedCount
+					errors { row error { code fieldErrors { fieldUri constraint } } }
+				}
+			}
+		`, map[string]any{"input": map[string]any{
+			"typeUri":  "type:test-1",
+			"spaceUri": "space:test-1",
+			"file":     nil,
+			"mapping":  []any{map[string]any{"column": "Notes", "fieldUri": nil}},
+			"dryRun":   dryRun,
+		}}, "variables.input.file", importCSV)
+		if len(resp.Errors) > 0 {
+			t.Fatalf("GraphQL errors: %v", resp.Errors)
+		}
+
+		data := struct {
+			ImportElements importResult `json:"importElements"`
+		}{}
+		if err := json.Unmarshal(resp.Data, &data); err != nil {
+			t.Fatalf("Failed to unmarshal data: %v", err)
+		}
+
+		result := data.ImportElements
+		if result.RowCount != 2 || result.ImportedCount != 1 {
+			t.Errorf("Expected 1 of 2 rows to be imported, got %+v", result)
+		}
+		if len(result.Errors) != 1 || result.Errors[0].Row != 2 || result.Errors[0].Error.Code != "VALIDATION" {
+			t.Fatalf("Expected a VALIDATION error for row 2, got %+v", result.Errors)
+		}
+		if fe := result.Errors[0].Error.FieldErrors; len(fe) != 1 || fe[0].FieldURI != "field:test-3" || fe[0].Constraint != "type" {
+			t.Errorf("Expected a type error for field:test-3, got %+v", fe)
+		}
+		return result
+	}
+
+	imported := func() int {
+		var count int
+		testDB.QueryRow(ctx, `SELECT count(*) FROM elements WHERE space_uri = 'space:test-1' AND title LIKE 'Imported Element %'`).Scan(&count)
+		return count
+	}
+
+	t.Run("dry runs import nothing", func(t *testing.T) {
+		importElements(t, true)
+		if got := imported(); got != 0 {
+			t.Errorf("Expected no element to be created, got %d", got)
+		}
+	})
+
+	t.Run("valid rows are imported", func(t *testing.T) {
+		importElements(t, false)
+		if got := imported(); got != 1 {
+			t.Fatalf("Expected 1 element to be created, got %d", got)
+		}
+
+		var option string
+		testDB.QueryRow(ctx, `SELECT v.value_text FROM element_field_values v JOIN elements e ON e.uri = v.element_uri
+			WHERE e.title = 'Imported Element 1' AND v.field_uri = 'field:test-2'`).Scan(&option)
+		if option != "option2" {
+			t.Errorf("Expected the select option to be matched regardless of case, got %q", option)
+		}
+	})
+
+	t.Run("readers can't import", func(t *testing.T) {
+		resp := executeUploadAs(t, testReaderID, `
+			mutation Import($input: ImportElementsInput!) { importElements(input: $input) { rowCount } }
+		`, map[string]any{"input": map[string]any{
+			"typeUri":  "type:test-1",
+			"spaceUri": "space:test-1",
+			"file":     nil,
+			"mapping":  []any{map[string]any{"column": "Notes", "fieldUri": nil}},
+		}}, "variables.input.file", importCSV)
+		expectCode(t, resp, "FORBIDDEN")
+	})
+
+	t.Run("rows that aren't UTF-8 are reported by background imports", func(t *testing.T) {
+		// the first row is Latin-1 encoded
+		file := "title,Test Text Field,Test Number Field\nImported Element Caf\xe9,Latin-1,1\nImported Element UTF-8,Caf\u00e9,2\n"
+
+		resp := executeUpload(t, `
+			mutation Import($input: ImportElementsInput!) { startImportElements(input: $input) { id } }
+		`, map[string]any{"input": map[string]any{
+			"typeUri":  "type:test-1",
+			"spaceUri": "space:test-1",
+			"file":     nil,
+		}}, "variables.input.file", file)
+		if len(resp.Errors) > 0 {
+			t.Fatalf("GraphQL errors: %v", resp.Errors)
+		}
+		data := struct {
+			StartImportElements jobResponse `json:"startImportElements"`
+		}{}
+		if err := json.Unmarshal(resp.Data, &data); err != nil {
+			t.Fatalf("Failed to unmarshal data: %v", err)
+		}
+
+		var job *jobResponse
+		deadline := time.Now().Add(10 * time.Second)
+		for time.Now().Before(deadline) {
+			job, _ = queryJob(t, data.StartImportElements.ID)
+			if job != nil && (job.Status == "SUCCEEDED" || job.Status == "FAILED") {
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		if job == nil || job.Status != "SUCCEEDED" {
+			t.Fatalf("Expected the job to succeed, got %+v", job)
+		}
+
+		var result importResult
+		raw, _ := json.Marshal(job.Result)
+		if err := json.Unmarshal(raw, &result); err != nil {
+			t.Fatalf("Failed to unmarshal result: %v", err)
+		}
+		if result.ImportedCount != 1 || len(result.Errors) != 1 || result.Errors[0].Row != 1 || result.Errors[0].Error.Code != "VALIDATION" {
+			t.Errorf("Expected row 1 to be rejected and row 2 imported, got %+v", result)
+		}
+
+		var text string
+		testDB.QueryRow(ctx, `SELECT v.value_text FROM element_field_values v JOIN elements e ON e.uri = v.element_uri
+			WHERE e.title = 'Imported Element UTF-8' AND v.field_uri = 'field:test-1'`).Scan(&text)
+		if text != "Caf\u00e9" {
+			t.Errorf("Expected the text to be kept as it is, got %q", text)
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type jobResponse struct {
+	ID       string         `json:"id"`
+	Status   string         `json:"status"`
+	Progress int            `json:"progress"`
+	Total    *int           `json:"total"`
+	Error    *string        `json:"error"`
+	Result   map[string]any `json:"result"`
+}
+
+func queryJob(t *testing.T, id string) (*jobResponse, string) {
+	t.Helper()
+
+	resp := executeGraphQL(t, `
+		query Job($id: ID!) {
+			job(id: $id) { id status progress total error result }
+		}
+	`, map[string]any{"id": id})
+
+	if len(resp.Errors) > 0 {
+		code, _ := resp.Errors[0].Extensions["code"].(string)
+		return nil, code
+	}
+
+	data := struct {
+		Job jobResponse `json:"job"`
+	}{}
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		t.Fatalf("Failed to unmarshal data: %v", err)
+	}
+	return &data.Job, ""
+}
+
+func TestJobs(t *testing.T) {
+	ctx := context.Background()
+
+	createTestElement(t, "element:test-20", "Test Element 20", "Job text")
+	createTestElement(t, "element:test-21", "Test Element 21", "Job text")
+
+	t.Run("update where runs in the background", func(t *testing.T) {
+		resp := executeGraphQL(t, `
+			mutation {
+				startUpdateElementsWhere(
+					spaceUri: "space:test-1"
+					typeUri: "type:test-1"
+					filter: { fieldValueFilter: { fieldUri: "field:test-1", value: "Job text", valueType: TEXT } }
+					set: [{ fieldUri: "field:test-2", value: "option2" }]
+				) { id status }
+			}
+		`, nil)
+		if len(resp.Errors) > 0 {
+			t.Fatalf("GraphQL errors: %v", resp.Errors)
+		}
+
+		data := struct {
+			StartUpdateElementsWhere jobResponse `json:"startUpdateElementsWhere"`
+		}{}
+		if err := json.Unmarshal(resp.Data, &data); err != nil {
+			t.Fatalf("Failed to unmarshal data: %v", err)
+		}
+		id := data.StartUpdateElementsWhere.ID
+
+		var job *jobResponse
+		deadline := time.Now().Add(10 * time.Second)
+		for time.Now().Before(deadline) {
+			job, _ = queryJob(t, id)
+			if job != nil && (job.Status == "SUCCEEDED" || job.Status == "FAILED") {
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		if job == nil || job.Status != "SUCCEEDED" {
+			t.Fatalf("Expected the job to succeed, got %+v", job)
+		}
+		if job.Result["affectedCount"] != float64(2) {
+			t.Errorf("Expected 2 affected elements, got %v", job.Result["affectedCount"])
+		}
+		if job.Total == nil || job.Progress != *job.Total {
+			t.Errorf("Expected the job to be fully processed, got %d of %v", job.Progress, job.Total)
+		}
+	})
+
+	t.Run("queued jobs can be cancelled", func(t *testing.T) {
+		now := time.Now().UnixMilli()
+		_, err := testDB.Exec(ctx, fmt.Sprintf(`INSERT INTO jobs (id, kind, payload, user_uri, status, max_attempts, run_after, creation_date, updated_date)
+			VALUES ('job:test-1', 'element.update_where', '{}', '%s', 'queued', 3, %d, %d, %d)`, testUserID, now+time.Hour.Milliseconds(), now, now))
+		if err != nil {
+			t.Fatalf("Failed to insert job: %v", err)
+		}
+
+		resp := executeGraphQL(t, `mutation { cancelJob(id: "job:test-1") { status } }`, nil)
+		if len(resp.Errors) > 0 {
+			t.Fatalf("GraphQL errors: %v", resp.Errors)
+		}
+
+		job, _ := queryJob(t, "job:test-1")
+		if job == nil || job.Status != "CANCELLED" {
+			t.Errorf("Expected the job to be cancelled, got %+v", job)
+		}
+	})
+
+	t.Run("unknown jobs are not found", func(t *testing.T) {
+		if _, code := queryJob(t, "job:unknown"); code != "NOT_FOUND" {
+			t.Errorf("Expected error code NOT_FOUND, got %q", code)
+		}
+	})
+}
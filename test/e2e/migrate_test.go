@@ -0,0 +1,178 @@
+package e2e
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bamdadam/backend/src/migrate"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestMigrations(t *testing.T) {
+	ctx := context.Background()
+
+	migrator, err := migrate.New(testDB)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	t.Run("every migration is applied", func(t *testing.T) {
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			t.Fatalf("Failed to get migration status: %v", err)
+		}
+		if len(statuses) == 0 {
+			t.Fatal("Expected migrations")
+		}
+		for _, s := range statuses {
+			if !s.Applied || s.Unknown {
+				t.Errorf("Expected migration %d_%s to be applied, got %+v", s.Version, s.Name, s)
+			}
+		}
+	})
+
+	t.Run("concurrent runs apply migrations once", func(t *testing.T) {
+		reverted, err := migrator.Down(ctx, 1)
+		if err != nil {
+			t.Fatalf("Failed to revert migration: %v", err)
+		}
+		if len(reverted) != 1 {
+			t.Fatalf("Expected 1 reverted migration, got %d", len(reverted))
+		}
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var applied []*migrate.Migration
+		for range 3 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				migrations, err := migrator.Up(ctx, 0)
+				if err != nil {
+					t.Errorf("Migration failed: %v", err)
+				}
+				mu.Lock()
+				applied = append(applied, migrations...)
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+
+		if len(applied) != 1 || applied[0].Version != reverted[0].Version {
+			t.Errorf("Expected migration %d to be applied once, got %d", reverted[0].Version, len(applied))
+		}
+	})
+
+	t.Run("databases of the baseline schema are upgraded", func(t *testing.T) {
+		db := baselineDatabase(t)
+
+		queries := []string{
+			`INSERT INTO users (uri, email, display_name) VALUES ('user:baseline', 'baseline@example.com', 'Baseline')`,
+			`INSERT INTO tenants (uri, name, status, creation_date) VALUES ('tenant:baseline', 'Baseline', 'active', 0)`,
+			`INSERT INTO user_tenants (user_uri, tenant_uri) VALUES ('user:baseline', 'tenant:baseline')`,
+		}
+		for _, q := range queries {
+			if _, err := db.Exec(ctx, q); err != nil {
+				t.Fatalf("Failed to execute query %q: %v", q, err)
+			}
+		}
+
+		migrator, err := migrate.New(db)
+		if err != nil {
+			t.Fatalf("Failed to load migrations: %v", err)
+		}
+		applied, err := migrator.Up(ctx, 0)
+		if err != nil {
+			t.Fatalf("Migration failed: %v", err)
+		}
+		if len(applied) == 0 || applied[0].Version != migrate.BaselineVersion+1 {
+			t.Errorf("Expected the migrations after the baseline to be applied, got %d", len(applied))
+		}
+
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			t.Fatalf("Failed to get migration status: %v", err)
+		}
+		for _, s := range statuses {
+			if !s.Applied {
+				t.Errorf("Expected migration %d_%s to be applied", s.Version, s.Name)
+			}
+		}
+
+		var role string
+		if err := db.QueryRow(ctx, `SELECT role FROM user_tenants WHERE user_uri = 'user:baseline'`).Scan(&role); err != nil || role != "member" {
+			t.Errorf("Expected existing members to get the member role, got %q (%v)", role, err)
+		}
+		var verbs int
+		db.QueryRow(ctx, `SELECT count(*) FROM permission_verbs`).Scan(&verbs)
+		if verbs != 4 {
+			t.Errorf("Expected 4 permission verbs, got %d", verbs)
+		}
+		var columns int
+		db.QueryRow(ctx, `SELECT count(*) FROM information_schema.columns
+			WHERE (table_name, column_name) IN (('elements', 'version'), ('fields', 'constraints'))`).Scan(&columns)
+		if columns != 2 {
+			t.Errorf("Expected the columns added after the baseline, got %d of 2", columns)
+		}
+
+		queries = []string{
+			`INSERT INTO spaces (uri, name, tenant_uri, creation_date) VALUES ('space:baseline', 'Baseline', 'tenant:baseline', 0)`,
+			`INSERT INTO types (uri, name, space_uri, creation_date, author) VALUES ('type:baseline', 'Baseline', 'space:baseline', 0, 'user:baseline')`,
+			`INSERT INTO fields (uri, name, field_type, type_uri, creation_date, author) VALUES ('field:baseline', 'Total', 'formula', 'type:baseline', 0, 'user:baseline')`,
+		}
+		for _, q := range queries {
+			if _, err := db.Exec(ctx, q); err != nil {
+				t.Errorf("Failed to execute query %q on the upgraded schema: %v", q, err)
+			}
+		}
+	})
+}
+
+// baselineDatabase creates a database holding the schema of the former
+// container init scripts, which are the migrations up to the baseline, without
+// any migration recorded. It is dropped when the test finishes.
+func baselineDatabase(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	ctx := context.Background()
+
+	const name = "technical_assessment_baseline_test"
+	testDB.Exec(ctx, `DROP DATABASE IF EXISTS `+name+` WITH (FORCE)`)
+	if _, err := testDB.Exec(ctx, `CREATE DATABASE `+name); err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+
+	config := testDB.Config().Copy()
+	config.ConnConfig.Database = name
+	db, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		testDB.Exec(ctx, `DROP DATABASE IF EXISTS `+name+` WITH (FORCE)`)
+	})
+
+	files, err := filepath.Glob("../../src/migrate/migrations/*.up.sql")
+	if err != nil {
+		t.Fatalf("Failed to list migrations: %v", err)
+	}
+	for _, file := range files {
+		version, _, _ := strings.Cut(filepath.Base(file), "_")
+		if v, _ := strconv.ParseInt(version, 10, 64); v > migrate.BaselineVersion {
+			continue
+		}
+		script, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", file, err)
+		}
+		if _, err := db.Exec(ctx, string(script)); err != nil {
+			t.Fatalf("Failed to run %s: %v", file, err)
+		}
+	}
+	return db
+}
@@ -0,0 +1,59 @@
+package e2e
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestOutbox(t *testing.T) {
+	ctx := context.Background()
+
+	createTestElement(t, "element:test-23", "Test Element 23", "Outbox text")
+
+	events := func() (total, dispatched int) {
+		testDB.QueryRow(ctx, `SELECT count(*), count(dispatched_date) FROM outbox WHERE aggregate_uri = 'element:test-23'`).Scan(&total, &dispatched)
+		return total, dispatched
+	}
+
+	rename := func(title string, expectedVersion *int) int {
+		input := map[string]any{"uri": "element:test-23", "title": title}
+		if expectedVersion != nil {
+			input["expectedVersion"] = *expectedVersion
+		}
+		resp := executeGraphQL(t, `
+			mutation UpdateElementTitle($input: UpdateElementTitleInput!) {
+				updateElementTitle(input: $input) { uri }
+			}
+		`, map[string]any{"input": input})
+		return len(resp.Errors)
+	}
+
+	t.Run("committed mutations are relayed", func(t *testing.T) {
+		if errs := rename("Outbox title", nil); errs > 0 {
+			t.Fatal("Expected the rename to succeed")
+		}
+
+		total, dispatched := events()
+		deadline := time.Now().Add(10 * time.Second)
+		for dispatched < total && time.Now().Before(deadline) {
+			time.Sleep(100 * time.Millisecond)
+			total, dispatched = events()
+		}
+
+		if total != 1 || dispatched != 1 {
+			t.Errorf("Expected 1 dispatched event, got %d of %d", dispatched, total)
+		}
+	})
+
+	t.Run("rolled back mutations leave no event", func(t *testing.T) {
+		stale := 0
+		if errs := rename("Stale title", &stale); errs == 0 {
+			t.Fatal("Expected a version conflict")
+		}
+
+		if total, _ := events(); total != 1 {
+			t.Errorf("Expected no event for the rolled back rename, got %d events", total)
+		}
+	})
+}
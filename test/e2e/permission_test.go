@@ -0,0 +1,146 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// expectCode fails unless a response holds a single error with the given
+// code.
+func expectCode(t *testing.T, resp graphql.Response, code string) {
+	t.Helper()
+	if len(resp.Errors) != 1 {
+		t.Fatalf("Expected a single %s error, got %v", code, resp.Errors)
+	}
+	if got := resp.Errors[0].Extensions["code"]; got != code {
+		t.Errorf("Expected error code %s, got %v", code, got)
+	}
+}
+
+func TestWritePermission(t *testing.T) {
+	t.Run("readers can read", func(t *testing.T) {
+		resp := executeGraphQLAs(t, testReaderID, `query { element(uri: "element:test-1") { uri } }`, nil)
+		if len(resp.Errors) > 0 {
+			t.Fatalf("GraphQL errors: %v", resp.Errors)
+		}
+	})
+
+	t.Run("readers can't create elements", func(t *testing.T) {
+		resp := executeGraphQLAs(t, testReaderID, `
+			mutation Create($input: CreateElementInput!) { createElement(input: $input) { uri } }
+		`, map[string]any{"input": map[string]any{
+			"uri":         "element:test-reader-1",
+			"typeUri":     "type:test-1",
+			"spaceUri":    "space:test-1",
+			"title":       "Reader element",
+			"fieldValues": []map[string]any{{"fieldUri": "field:test-1", "value": "text"}, {"fieldUri": "field:test-3", "value": 1}},
+		}})
+		expectCode(t, resp, "FORBIDDEN")
+	})
+
+	t.Run("readers can't update elements", func(t *testing.T) {
+		resp := executeGraphQLAs(t, testReaderID, `
+			mutation Update($input: UpdateElementInput!) { updateElement(input: $input) { uri } }
+		`, map[string]any{"input": map[string]any{"uri": "element:test-1", "title": "Reader title"}})
+		expectCode(t, resp, "FORBIDDEN")
+
+		resp = executeGraphQLAs(t, testReaderID, `
+			mutation { updateElementTitle(input: { uri: "element:test-1", title: "Reader title" }) { uri } }
+		`, nil)
+		expectCode(t, resp, "FORBIDDEN")
+	})
+
+	t.Run("readers can't bulk write elements", func(t *testing.T) {
+		createTestElement(t, "element:test-reader-2", "Reader Element 2", "Reader text")
+
+		resp := executeGraphQLAs(t, testReaderID, `
+			mutation {
+				bulkUpdateElements(inputs: [{ uri: "element:test-reader-2", title: "Reader title" }]) { uri error { code } }
+				bulkDeleteElements(uris: ["element:test-reader-2"], atomic: true) { uri error { code } }
+			}
+		`, nil)
+		if len(resp.Errors) > 0 {
+			t.Fatalf("GraphQL errors: %v", resp.Errors)
+		}
+
+		data := struct {
+			BulkUpdateElements []bulkResult `json:"bulkUpdateElements"`
+			BulkDeleteElements []bulkResult `json:"bulkDeleteElements"`
+		}{}
+		if err := json.Unmarshal(resp.Data, &data); err != nil {
+			t.Fatalf("Failed to unmarshal data: %v", err)
+		}
+		for _, results := range [][]bulkResult{data.BulkUpdateElements, data.BulkDeleteElements} {
+			if len(results) != 1 || results[0].Error == nil || results[0].Error.Code != "FORBIDDEN" {
+				t.Errorf("Expected a FORBIDDEN result, got %+v", results)
+			}
+		}
+
+		var count int
+		testDB.QueryRow(t.Context(), `SELECT count(*) FROM elements WHERE uri = 'element:test-reader-2'`).Scan(&count)
+		if count != 1 {
+			t.Error("Expected the element to be kept")
+		}
+	})
+}
+
+func TestTypeOfOtherSpace(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UnixMilli()
+
+	queries := []string{
+		fmt.Sprintf(`INSERT INTO tenants (uri, name, status, creation_date) VALUES ('tenant:test-foreign', 'Foreign Tenant', 'active', %d)`, now),
+		fmt.Sprintf(`INSERT INTO spaces (uri, name, tenant_uri, creation_date) VALUES ('space:test-foreign', 'Foreign Space', 'tenant:test-foreign', %d)`, now),
+		fmt.Sprintf(`INSERT INTO types (uri, name, space_uri, creation_date, author) VALUES ('type:test-foreign', 'Foreign Type', 'space:test-foreign', %d, '%s')`, now, testUserID),
+		fmt.Sprintf(`INSERT INTO fields (uri, name, field_type, type_uri, creation_date, author, options, required) VALUES ('field:test-foreign', 'Foreign Sequence', 'text', 'type:test-foreign', %d, '%s', '{"autogen":"sequence","prefix":"F-","digits":3}', false)`, now, testUserID),
+	}
+	t.Cleanup(func() {
+		testDB.Exec(ctx, `DELETE FROM elements WHERE type_uri = 'type:test-foreign'`)
+		testDB.Exec(ctx, `DELETE FROM field_sequences WHERE field_uri = 'field:test-foreign'`)
+		testDB.Exec(ctx, `DELETE FROM fields WHERE uri = 'field:test-foreign'`)
+		testDB.Exec(ctx, `DELETE FROM types WHERE uri = 'type:test-foreign'`)
+		testDB.Exec(ctx, `DELETE FROM spaces WHERE uri = 'space:test-foreign'`)
+		testDB.Exec(ctx, `DELETE FROM tenants WHERE uri = 'tenant:test-foreign'`)
+	})
+	for _, q := range queries {
+		if _, err := testDB.Exec(ctx, q); err != nil {
+			t.Fatalf("Failed to execute query %q: %v", q, err)
+		}
+	}
+
+	t.Run("elements can't be created", func(t *testing.T) {
+		resp := executeGraphQL(t, `
+			mutation Create($input: CreateElementInput!) { createElement(input: $input) { uri } }
+		`, map[string]any{"input": map[string]any{
+			"uri":         "element:test-foreign",
+			"typeUri":     "type:test-foreign",
+			"spaceUri":    "space:test-1",
+			"title":       "Foreign element",
+			"fieldValues": []map[string]any{},
+		}})
+		expectCode(t, resp, "NOT_FOUND")
+	})
+
+	t.Run("elements can't be imported", func(t *testing.T) {
+		resp := executeUpload(t, `
+			mutation Import($input: ImportElementsInput!) { importElements(input: $input) { importedCount } }
+		`, map[string]any{"input": map[string]any{
+			"typeUri":  "type:test-foreign",
+			"spaceUri": "space:test-1",
+			"file":     nil,
+		}}, "variables.input.file", "title\nForeign element\n")
+		expectCode(t, resp, "NOT_FOUND")
+	})
+
+	var elements, sequences int
+	testDB.QueryRow(ctx, `SELECT count(*) FROM elements WHERE type_uri = 'type:test-foreign'`).Scan(&elements)
+	testDB.QueryRow(ctx, `SELECT count(*) FROM field_sequences WHERE field_uri = 'field:test-foreign'`).Scan(&sequences)
+	if elements != 0 || sequences != 0 {
+		t.Errorf("Expected no elements and no sequence numbers, got %d elements and %d sequences", elements, sequences)
+	}
+}
@@ -0,0 +1,139 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestElementLinks(t *testing.T) {
+	ctx := context.Background()
+
+	if _, err := testDB.Exec(ctx, fmt.Sprintf(
+		`INSERT INTO fields (uri, name, field_type, type_uri, creation_date, author, options, required) VALUES ('field:test-5', 'Test Relation Field', 'relation', 'type:test-1', %d, '%s', '{"targetTypeUri":"type:test-1","cardinality":"multiple"}', false)`,
+		time.Now().UnixMilli(), testUserID,
+	)); err != nil {
+		t.Fatalf("Failed to insert relation field: %v", err)
+	}
+	t.Cleanup(func() {
+		testDB.Exec(ctx, `DELETE FROM fields WHERE uri = 'field:test-5'`)
+	})
+
+	createTestElement(t, "element:test-9", "Test Element 9", "Linking text")
+
+	setLinks := `
+		mutation SetElementLinks($input: SetElementLinksInput!) {
+			setElementLinks(input: $input) {
+				version
+				links { field { uri } element { uri } }
+			}
+		}
+	`
+	resp := executeGraphQL(t, setLinks, map[string]any{"input": map[string]any{
+		"uri":        "element:test-9",
+		"fieldUri":   "field:test-5",
+		"targetUris": []string{"element:test-1", "element:test-2"},
+	}})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("GraphQL errors: %v", resp.Errors)
+	}
+
+	type link struct {
+		Field struct {
+			URI string `json:"uri"`
+		} `json:"field"`
+		Element struct {
+			URI string `json:"uri"`
+		} `json:"element"`
+	}
+
+	setData := struct {
+		SetElementLinks struct {
+			Version int     `json:"version"`
+			Links   []*link `json:"links"`
+		} `json:"setElementLinks"`
+	}{}
+	if err := json.Unmarshal(resp.Data, &setData); err != nil {
+		t.Fatalf("Failed to unmarshal data: %v", err)
+	}
+
+	links := setData.SetElementLinks.Links
+	if len(links) != 2 || links[0].Element.URI != "element:test-1" || links[1].Element.URI != "element:test-2" {
+		t.Fatalf("Expected links to element:test-1 and element:test-2 in order, got %+v", links)
+	}
+	if setData.SetElementLinks.Version != 2 {
+		t.Errorf("Expected version 2 after setting links, got %d", setData.SetElementLinks.Version)
+	}
+
+	// The linked element sees the link from the other side.
+	resp = executeGraphQL(t, `
+		query Element($uri: ID!, $fieldUri: ID) {
+			element(uri: $uri) {
+				backlinks(fieldUri: $fieldUri) { field { uri } element { uri } }
+			}
+		}
+	`, map[string]any{"uri": "element:test-1", "fieldUri": "field:test-5"})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("GraphQL errors: %v", resp.Errors)
+	}
+
+	backlinkData := struct {
+		Element struct {
+			Backlinks []*link `json:"backlinks"`
+		} `json:"element"`
+	}{}
+	if err := json.Unmarshal(resp.Data, &backlinkData); err != nil {
+		t.Fatalf("Failed to unmarshal data: %v", err)
+	}
+	if bl := backlinkData.Element.Backlinks; len(bl) != 1 || bl[0].Element.URI != "element:test-9" {
+		t.Errorf("Expected a single backlink from element:test-9, got %+v", bl)
+	}
+
+	// Elements can be filtered on the field values of the elements they link to.
+	resp = executeGraphQL(t, `
+		query Elements($filter: RelationFilter) {
+			elements(relationFilter: $filter) { edges { node { uri } } }
+		}
+	`, map[string]any{"filter": map[string]any{
+		"fieldUri": "field:test-5",
+		"fieldValueFilter": map[string]any{
+			"fieldUri":  "field:test-1",
+			"value":     "Hello World",
+			"valueType": "TEXT",
+		},
+	}})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("GraphQL errors: %v", resp.Errors)
+	}
+
+	listData := struct {
+		Elements struct {
+			Edges []struct {
+				Node struct {
+					URI string `json:"uri"`
+				} `json:"node"`
+			} `json:"edges"`
+		} `json:"elements"`
+	}{}
+	if err := json.Unmarshal(resp.Data, &listData); err != nil {
+		t.Fatalf("Failed to unmarshal data: %v", err)
+	}
+	if edges := listData.Elements.Edges; len(edges) != 1 || edges[0].Node.URI != "element:test-9" {
+		t.Errorf("Expected only element:test-9 to match the relation filter, got %+v", edges)
+	}
+
+	// Elements in spaces the user can't access can't be linked to.
+	resp = executeGraphQL(t, setLinks, map[string]any{"input": map[string]any{
+		"uri":        "element:test-9",
+		"fieldUri":   "field:test-5",
+		"targetUris": []string{"element:test-3"},
+	}})
+	if len(resp.Errors) != 1 {
+		t.Fatalf("Expected a single error linking an inaccessible element, got %v", resp.Errors)
+	}
+	if code := resp.Errors[0].Extensions["code"]; code != "NOT_FOUND" {
+		t.Errorf("Expected error code NOT_FOUND, got %v", code)
+	}
+}
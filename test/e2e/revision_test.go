@@ -0,0 +1,256 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type revisionsResponse struct {
+	Element struct {
+		Title     string `json:"title"`
+		Revisions struct {
+			Edges []struct {
+				Cursor string `json:"cursor"`
+				Node   struct {
+					ID          string `json:"id"`
+					Title       string `json:"title"`
+					FieldValues []struct {
+						URI   string `json:"uri"`
+						Value any    `json:"value"`
+					} `json:"fieldValues"`
+					Author struct {
+						URI string `json:"uri"`
+					} `json:"author"`
+				} `json:"node"`
+			} `json:"edges"`
+			PageInfo struct {
+				HasNextPage bool `json:"hasNextPage"`
+			} `json:"pageInfo"`
+		} `json:"revisions"`
+	} `json:"element"`
+}
+
+func queryRevisions(t *testing.T, uri string) revisionsResponse {
+	t.Helper()
+
+	query := `
+		query Revisions($uri: ID!) {
+			element(uri: $uri) {
+				title
+				revisions(first: 10) {
+					edges {
+						cursor
+						node {
+							id
+							title
+							fieldValues { uri value }
+							author { uri }
+						}
+					}
+					pageInfo { hasNextPage }
+				}
+			}
+		}
+	`
+
+	resp := executeGraphQL(t, query, map[string]any{"uri": uri})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("GraphQL errors: %v", resp.Errors)
+	}
+
+	var data revisionsResponse
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		t.Fatalf("Failed to unmarshal data: %v", err)
+	}
+	return data
+}
+
+func TestElementRevisions(t *testing.T) {
+	createTestElement(t, "element:test-5", "Test Element 5", "Revisioned text")
+
+	beforeUpdate := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	time.Sleep(5 * time.Millisecond)
+
+	resp := executeGraphQL(t, `
+		mutation UpdateElementTitle($input: UpdateElementTitleInput!) {
+			updateElementTitle(input: $input) { uri }
+		}
+	`, map[string]any{"input": map[string]any{"uri": "element:test-5", "title": "Revised Title"}})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("GraphQL errors: %v", resp.Errors)
+	}
+
+	data := queryRevisions(t, "element:test-5")
+	edges := data.Element.Revisions.Edges
+	if len(edges) < 2 {
+		t.Fatalf("Expected at least 2 revisions, got %d", len(edges))
+	}
+
+	latest, baseline := edges[0].Node, edges[len(edges)-1].Node
+	if latest.Title != "Revised Title" {
+		t.Errorf("Expected latest revision title 'Revised Title', got %q", latest.Title)
+	}
+	if latest.Author.URI != testUserID {
+		t.Errorf("Expected latest revision author %q, got %q", testUserID, latest.Author.URI)
+	}
+	if baseline.Title != "Test Element 5" {
+		t.Errorf("Expected baseline revision title 'Test Element 5', got %q", baseline.Title)
+	}
+	if len(baseline.FieldValues) != 1 || baseline.FieldValues[0].Value != "Revisioned text" {
+		t.Errorf("Expected baseline revision to capture field values, got %+v", baseline.FieldValues)
+	}
+
+	atResp := executeGraphQL(t, `
+		query ElementAt($uri: ID!, $timestamp: DateTime!) {
+			elementAt(uri: $uri, timestamp: $timestamp) { uri title }
+		}
+	`, map[string]any{"uri": "element:test-5", "timestamp": beforeUpdate})
+	if len(atResp.Errors) > 0 {
+		t.Fatalf("GraphQL errors: %v", atResp.Errors)
+	}
+
+	atData := struct {
+		ElementAt struct {
+			Title string `json:"title"`
+		} `json:"elementAt"`
+	}{}
+	if err := json.Unmarshal(atResp.Data, &atData); err != nil {
+		t.Fatalf("Failed to unmarshal data: %v", err)
+	}
+	if atData.ElementAt.Title != "Test Element 5" {
+		t.Errorf("Expected title before update 'Test Element 5', got %q", atData.ElementAt.Title)
+	}
+
+	revertResp := executeGraphQL(t, `
+		mutation RevertElement($uri: ID!, $revisionId: ID!) {
+			revertElement(uri: $uri, revisionId: $revisionId) { uri title }
+		}
+	`, map[string]any{"uri": "element:test-5", "revisionId": baseline.ID})
+	if len(revertResp.Errors) > 0 {
+		t.Fatalf("GraphQL errors: %v", revertResp.Errors)
+	}
+
+	reverted := queryRevisions(t, "element:test-5")
+	if reverted.Element.Title != "Test Element 5" {
+		t.Errorf("Expected reverted title 'Test Element 5', got %q", reverted.Element.Title)
+	}
+	if len(reverted.Element.Revisions.Edges) != len(edges)+1 {
+		t.Errorf("Expected revert to add a revision, got %d revisions, had %d",
+			len(reverted.Element.Revisions.Edges), len(edges))
+	}
+}
+
+func TestRevertChecksFieldValues(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UnixMilli()
+
+	fields := []string{
+		fmt.Sprintf(`INSERT INTO fields (uri, name, field_type, type_uri, creation_date, author, options, required, constraints) VALUES ('field:test-revert-sku', 'Test Revert SKU', 'text', 'type:test-1', %d, '%s', null, false, '{"unique":"type"}')`, now, testUserID),
+		fmt.Sprintf(`INSERT INTO fields (uri, name, field_type, type_uri, creation_date, author, options, required) VALUES ('field:test-revert-kind', 'Test Revert Kind', 'text', 'type:test-1', %d, '%s', null, false)`, now, testUserID),
+	}
+	t.Cleanup(func() {
+		testDB.Exec(ctx, `DELETE FROM elements WHERE uri IN ('element:test-revert-1', 'element:test-revert-2')`)
+		testDB.Exec(ctx, `DELETE FROM fields WHERE uri IN ('field:test-revert-sku', 'field:test-revert-kind')`)
+	})
+	for _, q := range fields {
+		if _, err := testDB.Exec(ctx, q); err != nil {
+			t.Fatalf("Failed to insert field: %v", err)
+		}
+	}
+
+	value := func(uri string, v any) map[string]any {
+		return map[string]any{"fieldUri": uri, "value": v}
+	}
+	create := func(t *testing.T, uri, sku string) {
+		t.Helper()
+		resp := executeGraphQL(t, `
+			mutation Create($input: CreateElementInput!) { createElement(input: $input) { uri } }
+		`, map[string]any{"input": map[string]any{
+			"uri":      uri,
+			"typeUri":  "type:test-1",
+			"spaceUri": "space:test-1",
+			"title":    "Reverted Element",
+			"fieldValues": []any{
+				value("field:test-1", "text"), value("field:test-3", 1),
+				value("field:test-revert-sku", sku), value("field:test-revert-kind", "text"),
+			},
+		}})
+		if len(resp.Errors) > 0 {
+			t.Fatalf("GraphQL errors: %v", resp.Errors)
+		}
+	}
+	update := func(t *testing.T, fieldValues ...any) {
+		t.Helper()
+		resp := executeGraphQL(t, `
+			mutation Update($input: UpdateElementInput!) { updateElement(input: $input) { uri } }
+		`, map[string]any{"input": map[string]any{"uri": "element:test-revert-1", "fieldValues": fieldValues}})
+		if len(resp.Errors) > 0 {
+			t.Fatalf("GraphQL errors: %v", resp.Errors)
+		}
+	}
+	// revert reverts element:test-revert-1 to a revision, expecting it to fail
+	// and returning the constraint each field was rejected for.
+	revert := func(t *testing.T, revisionID string) map[string]string {
+		t.Helper()
+		resp := executeGraphQL(t, `
+			mutation Revert($uri: ID!, $revisionId: ID!) { revertElement(uri: $uri, revisionId: $revisionId) { uri } }
+		`, map[string]any{"uri": "element:test-revert-1", "revisionId": revisionID})
+		if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != "VALIDATION" {
+			t.Fatalf("Expected a VALIDATION error, got %v", resp.Errors)
+		}
+
+		raw, _ := json.Marshal(resp.Errors[0].Extensions["fieldErrors"])
+		var errs []struct {
+			FieldURI   string `json:"fieldUri"`
+			Constraint string `json:"constraint"`
+		}
+		if err := json.Unmarshal(raw, &errs); err != nil {
+			t.Fatalf("Failed to unmarshal field errors: %v", err)
+		}
+		constraints := make(map[string]string, len(errs))
+		for _, e := range errs {
+			constraints[e.FieldURI] = e.Constraint
+		}
+		return constraints
+	}
+
+	create(t, "element:test-revert-1", "SKU-1")
+	edges := queryRevisions(t, "element:test-revert-1").Element.Revisions.Edges
+	if len(edges) != 1 {
+		t.Fatalf("Expected the revision of the creation, got %d revisions", len(edges))
+	}
+	first := edges[0].Node.ID
+
+	t.Run("rejects duplicate unique values", func(t *testing.T) {
+		update(t, value("field:test-revert-sku", "SKU-2"))
+		create(t, "element:test-revert-2", "SKU-1")
+		t.Cleanup(func() {
+			testDB.Exec(ctx, `DELETE FROM elements WHERE uri = 'element:test-revert-2'`)
+		})
+
+		if constraints := revert(t, first); len(constraints) != 1 || constraints["field:test-revert-sku"] != "unique" {
+			t.Errorf("Expected a unique error for field:test-revert-sku, got %v", constraints)
+		}
+	})
+
+	t.Run("rejects values of fields that changed type", func(t *testing.T) {
+		update(t, value("field:test-revert-kind", nil))
+		if _, err := testDB.Exec(ctx, `UPDATE fields SET field_type = 'number' WHERE uri = 'field:test-revert-kind'`); err != nil {
+			t.Fatalf("Failed to change field type: %v", err)
+		}
+
+		if constraints := revert(t, first); len(constraints) != 1 || constraints["field:test-revert-kind"] != "type" {
+			t.Errorf("Expected a type error for field:test-revert-kind, got %v", constraints)
+		}
+
+		// the element is left as it was and can still be read
+		resp := executeGraphQL(t, `query { element(uri: "element:test-revert-1") { fieldValues { field { uri } value } } }`, nil)
+		if len(resp.Errors) > 0 {
+			t.Fatalf("GraphQL errors: %v", resp.Errors)
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRollupFields(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UnixMilli()
+
+	fields := []string{
+		fmt.Sprintf(`INSERT INTO fields (uri, name, field_type, type_uri, creation_date, author, options, required) VALUES ('field:test-7', 'Test Rollup Relation', 'relation', 'type:test-1', %d, '%s', '{"targetTypeUri":"type:test-1","cardinality":"multiple"}', false)`, now, testUserID),
+		fmt.Sprintf(`INSERT INTO fields (uri, name, field_type, type_uri, creation_date, author, options, required) VALUES ('field:test-8', 'Test Rollup Field', 'rollup', 'type:test-1', %d, '%s', '{"relationFieldUri":"field:test-7","targetFieldUri":"field:test-3","function":"sum"}', false)`, now, testUserID),
+	}
+	t.Cleanup(func() {
+		testDB.Exec(ctx, `DELETE FROM fields WHERE uri IN ('field:test-7', 'field:test-8')`)
+	})
+	for _, q := range fields {
+		if _, err := testDB.Exec(ctx, q); err != nil {
+			t.Fatalf("Failed to insert field: %v", err)
+		}
+	}
+
+	createTestElement(t, "element:test-10", "Test Element 10", "Rollup text")
+
+	setLinks := func(targets ...string) any {
+		t.Helper()
+
+		if targets == nil {
+			targets = []string{}
+		}
+		resp := executeGraphQL(t, `
+			mutation SetElementLinks($input: SetElementLinksInput!) {
+				setElementLinks(input: $input) {
+					fieldValues {
+						field { uri resultType }
+						value
+						typedValue { ... on NumberValue { number } }
+					}
+				}
+			}
+		`, map[string]any{"input": map[string]any{
+			"uri":        "element:test-10",
+			"fieldUri":   "field:test-7",
+			"targetUris": targets,
+		}})
+		if len(resp.Errors) > 0 {
+			t.Fatalf("GraphQL errors: %v", resp.Errors)
+		}
+
+		data := struct {
+			SetElementLinks struct {
+				FieldValues []struct {
+					Field struct {
+						URI        string  `json:"uri"`
+						ResultType *string `json:"resultType"`
+					} `json:"field"`
+					Value      any            `json:"value"`
+					TypedValue map[string]any `json:"typedValue"`
+				} `json:"fieldValues"`
+			} `json:"setElementLinks"`
+		}{}
+		if err := json.Unmarshal(resp.Data, &data); err != nil {
+			t.Fatalf("Failed to unmarshal data: %v", err)
+		}
+
+		for _, fv := range data.SetElementLinks.FieldValues {
+			if fv.Field.URI != "field:test-8" {
+				continue
+			}
+			if fv.Field.ResultType == nil || *fv.Field.ResultType != "NUMBER" {
+				t.Errorf("Expected result type NUMBER, got %v", fv.Field.ResultType)
+			}
+			if fv.TypedValue["number"] != fv.Value {
+				t.Errorf("Expected typed number %v, got %v", fv.Value, fv.TypedValue)
+			}
+			return fv.Value
+		}
+		return nil
+	}
+
+	// element:test-1 and element:test-2 have numbers 42.5 and 100.
+	if total := setLinks("element:test-1", "element:test-2"); total != 142.5 {
+		t.Errorf("Expected rollup 142.5, got %v", total)
+	}
+	if total := setLinks("element:test-2"); total != 100.0 {
+		t.Errorf("Expected rollup 100 after unlinking element:test-1, got %v", total)
+	}
+	if total := setLinks(); total != nil {
+		t.Errorf("Expected no rollup value without linked elements, got %v", total)
+	}
+}
+
+func TestRollupsOfHiddenElements(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UnixMilli()
+
+	// element:test-owner counts the elements linking to it, it is in
+	// space:test-2 the test user can't see.
+	queries := []string{
+		fmt.Sprintf(`INSERT INTO types (uri, name, space_uri, creation_date, author) VALUES ('type:test-owner', 'Test Owner Type', 'space:test-2', %d, '%s')`, now, testUserID),
+		fmt.Sprintf(`INSERT INTO fields (uri, name, field_type, type_uri, creation_date, author, options, required) VALUES ('field:test-owner-relation', 'Test Owner Relation', 'relation', 'type:test-1', %d, '%s', '{"targetTypeUri":"type:test-owner","cardinality":"multiple"}', false)`, now, testUserID),
+		fmt.Sprintf(`INSERT INTO fields (uri, name, field_type, type_uri, creation_date, author, options, required) VALUES ('field:test-owner-count', 'Test Owner Count', 'rollup', 'type:test-owner', %d, '%s', '{"relationFieldUri":"field:test-owner-relation","targetFieldUri":"field:test-3","function":"count"}', false)`, now, testUserID),
+		fmt.Sprintf(`INSERT INTO elements (uri, title, type_uri, space_uri, creation_date, author) VALUES ('element:test-owner', 'Test Owner', 'type:test-owner', 'space:test-2', %d, '%s')`, now, testUserID),
+	}
+	t.Cleanup(func() {
+		testDB.Exec(ctx, `DELETE FROM elements WHERE uri = 'element:test-owner'`)
+		testDB.Exec(ctx, `DELETE FROM fields WHERE uri IN ('field:test-owner-relation', 'field:test-owner-count')`)
+		testDB.Exec(ctx, `DELETE FROM types WHERE uri = 'type:test-owner'`)
+	})
+	for _, q := range queries {
+		if _, err := testDB.Exec(ctx, q); err != nil {
+			t.Fatalf("Failed to execute query %q: %v", q, err)
+		}
+	}
+
+	createTestElement(t, "element:test-linking", "Test Linking Element", "Linking text")
+
+	link := func() {
+		t.Helper()
+		queries := []string{
+			fmt.Sprintf(`INSERT INTO element_links (source_element_uri, field_uri, target_element_uri, position, creation_date) VALUES ('element:test-linking', 'field:test-owner-relation', 'element:test-owner', 0, %d)`, now),
+			fmt.Sprintf(`INSERT INTO element_field_values (uri, element_uri, field_uri, value_number, creation_date, updated_date) VALUES ('efv:element:test-owner:field:test-owner-count', 'element:test-owner', 'field:test-owner-count', 1, %d, %d) ON CONFLICT (element_uri, field_uri) DO UPDATE SET value_number = 1`, now, now),
+		}
+		for _, q := range queries {
+			if _, err := testDB.Exec(ctx, q); err != nil {
+				t.Fatalf("Failed to execute query %q: %v", q, err)
+			}
+		}
+	}
+	count := func() float64 {
+		t.Helper()
+		var count float64
+		if err := testDB.QueryRow(ctx,
+			`SELECT value_number FROM element_field_values WHERE element_uri = 'element:test-owner' AND field_uri = 'field:test-owner-count'`,
+		).Scan(&count); err != nil {
+			t.Fatalf("Failed to read the rollup: %v", err)
+		}
+		return count
+	}
+
+	t.Run("unlinking refreshes the hidden owner", func(t *testing.T) {
+		link()
+
+		resp := executeGraphQL(t, `
+			mutation { setElementLinks(input: { uri: "element:test-linking", fieldUri: "field:test-owner-relation", targetUris: [] }) { uri } }
+		`, nil)
+		if len(resp.Errors) > 0 {
+			t.Fatalf("GraphQL errors: %v", resp.Errors)
+		}
+		if got := count(); got != 0 {
+			t.Errorf("Expected the count of the hidden owner to be 0, got %v", got)
+		}
+	})
+
+	t.Run("deleting refreshes the hidden owner", func(t *testing.T) {
+		link()
+
+		resp := executeGraphQL(t, `
+			mutation { bulkDeleteElements(uris: ["element:test-linking"]) { uri error { code } } }
+		`, nil)
+		if len(resp.Errors) > 0 {
+			t.Fatalf("GraphQL errors: %v", resp.Errors)
+		}
+		if got := count(); got != 0 {
+			t.Errorf("Expected the count of the hidden owner to be 0, got %v", got)
+		}
+	})
+}
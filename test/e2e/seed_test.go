@@ -0,0 +1,85 @@
+package e2e
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bamdadam/backend/src/seed"
+)
+
+func TestSeed(t *testing.T) {
+	ctx := context.Background()
+
+	cleanup := func(prefix string) {
+		like := "%:" + prefix + "-%"
+		for _, q := range []string{
+			`DELETE FROM elements WHERE uri LIKE $1`,
+			`DELETE FROM fields WHERE uri LIKE $1`,
+			`DELETE FROM types WHERE uri LIKE $1`,
+			`DELETE FROM user_space_permissions WHERE user_uri LIKE $1`,
+			`DELETE FROM user_spaces WHERE user_uri LIKE $1`,
+			`DELETE FROM user_tenants WHERE user_uri LIKE $1`,
+			`DELETE FROM spaces WHERE uri LIKE $1`,
+			`DELETE FROM tenants WHERE uri LIKE $1`,
+			`DELETE FROM users WHERE uri LIKE $1`,
+		} {
+			testDB.Exec(ctx, q, like)
+		}
+	}
+
+	newSpec := func(prefix string) *seed.Spec {
+		spec := seed.DefaultSpec()
+		spec.Prefix = prefix
+		spec.Users = 3
+		spec.ElementsPerType = 50
+		return spec
+	}
+
+	for _, prefix := range []string{"test-seed-a", "test-seed-b"} {
+		t.Cleanup(func() { cleanup(prefix) })
+		if _, err := seed.Run(ctx, testDB, newSpec(prefix)); err != nil {
+			t.Fatalf("Failed to seed: %v", err)
+		}
+	}
+
+	t.Run("rows are written", func(t *testing.T) {
+		var elements, values, links int
+		err := testDB.QueryRow(ctx, `
+			SELECT
+				(SELECT count(*) FROM elements WHERE uri LIKE 'element:test-seed-a-%'),
+				(SELECT count(*) FROM element_field_values WHERE element_uri LIKE 'element:test-seed-a-%'),
+				(SELECT count(*) FROM element_links WHERE source_element_uri LIKE 'element:test-seed-a-%')
+		`).Scan(&elements, &values, &links)
+		if err != nil {
+			t.Fatalf("Failed to count rows: %v", err)
+		}
+		if elements != 4*50 {
+			t.Errorf("Expected %d elements, got %d", 4*50, elements)
+		}
+		if values == 0 || links == 0 {
+			t.Errorf("Expected field values and links, got %d and %d", values, links)
+		}
+	})
+
+	t.Run("runs with the same seed generate the same data", func(t *testing.T) {
+		titles := func(prefix string) string {
+			var s string
+			err := testDB.QueryRow(ctx, `SELECT string_agg(title, '|' ORDER BY uri) FROM elements WHERE uri LIKE 'element:' || $1 || '-%'`, prefix).Scan(&s)
+			if err != nil {
+				t.Fatalf("Failed to list titles: %v", err)
+			}
+			return s
+		}
+		if a, b := titles("test-seed-a"), titles("test-seed-b"); a != b {
+			t.Error("Expected the same titles for both runs")
+		}
+	})
+
+	t.Run("prefixes can't be reused", func(t *testing.T) {
+		_, err := seed.Run(ctx, testDB, newSpec("test-seed-a"))
+		if err == nil || !strings.Contains(err.Error(), "exists") {
+			t.Errorf("Expected an error for an existing prefix, got %v", err)
+		}
+	})
+}
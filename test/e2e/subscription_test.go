@@ -0,0 +1,355 @@
+package e2e
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/bamdadam/backend/src/middleware"
+	"github.com/bamdadam/backend/src/server"
+	"github.com/gorilla/websocket"
+)
+
+type elementChangedResponse struct {
+	ElementChanged struct {
+		ID      string `json:"id"`
+		Element struct {
+			URI   string `json:"uri"`
+			Title string `json:"title"`
+		} `json:"element"`
+		Title *struct {
+			Before string `json:"before"`
+			After  string `json:"after"`
+		} `json:"title"`
+		FieldValues []struct {
+			URI string `json:"uri"`
+		} `json:"fieldValues"`
+		Actor struct {
+			URI string `json:"uri"`
+		} `json:"actor"`
+		Timestamp string `json:"timestamp"`
+	} `json:"elementChanged"`
+}
+
+const elementChangedSubscription = `
+	subscription ElementChanged($uri: ID!) {
+		elementChanged(uri: $uri) {
+			id
+			element { uri title }
+			title { before after }
+			fieldValues { uri }
+			actor { uri }
+			timestamp
+		}
+	}
+`
+
+// updateUntilReceived keeps renaming the element until the subscription yields
+// a payload, since there is no signal telling us when the server has
+// registered the subscriber.
+func updateUntilReceived[T any](t *testing.T, uri string, next func(any) error) T {
+	t.Helper()
+
+	received := make(chan T, 1)
+	errs := make(chan error, 1)
+	go func() {
+		var resp T
+		if err := next(&resp); err != nil {
+			errs <- err
+			return
+		}
+		received <- resp
+	}()
+
+	mutation := `
+		mutation UpdateElementTitle($input: UpdateElementTitleInput!) {
+			updateElementTitle(input: $input) { uri }
+		}
+	`
+
+	timeout := time.After(5 * time.Second)
+	for {
+		resp := executeGraphQL(t, mutation, map[string]any{
+			"input": map[string]any{"uri": uri, "title": fmt.Sprintf("Subscription Title %d", time.Now().UnixNano())},
+		})
+		if len(resp.Errors) > 0 {
+			t.Fatalf("GraphQL errors: %v", resp.Errors)
+		}
+
+		select {
+		case resp := <-received:
+			return resp
+		case err := <-errs:
+			t.Fatalf("Subscription failed: %v", err)
+		case <-timeout:
+			t.Fatal("Timed out waiting for subscription payload")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func TestElementUpdatedSubscription(t *testing.T) {
+	c := client.New(middleware.Auth(server.NewGraphQLHandler(testDB, server.Options{})))
+
+	sub := c.WebsocketWithPayload(`
+		subscription ElementUpdated($uri: ID!) {
+			elementUpdated(uri: $uri) { uri title version }
+		}
+	`, map[string]any{middleware.AuthHeader: testUserID}, client.Var("uri", "element:test-4"))
+	defer sub.Close()
+
+	resp := updateUntilReceived[struct {
+		ElementUpdated struct {
+			URI     string `json:"uri"`
+			Title   string `json:"title"`
+			Version int    `json:"version"`
+		} `json:"elementUpdated"`
+	}](t, "element:test-4", sub.Next)
+	elem := resp.ElementUpdated
+
+	if elem.URI != "element:test-4" {
+		t.Errorf("Expected element URI 'element:test-4', got %q", elem.URI)
+	}
+	if !strings.HasPrefix(elem.Title, "Subscription Title ") {
+		t.Errorf("Expected the updated title, got %q", elem.Title)
+	}
+	if elem.Version == 0 {
+		t.Error("Expected the version of the updated element")
+	}
+}
+
+func TestElementChangedSubscription(t *testing.T) {
+	c := client.New(middleware.Auth(server.NewGraphQLHandler(testDB, server.Options{})))
+
+	sub := c.WebsocketWithPayload(elementChangedSubscription,
+		map[string]any{middleware.AuthHeader: testUserID},
+		client.Var("uri", "element:test-4"))
+	defer sub.Close()
+
+	resp := updateUntilReceived[elementChangedResponse](t, "element:test-4", sub.Next)
+	change := resp.ElementChanged
+
+	if change.Element.URI != "element:test-4" {
+		t.Errorf("Expected element URI 'element:test-4', got %q", change.Element.URI)
+	}
+
+	if change.Title == nil {
+		t.Fatal("Expected title change, got nil")
+	}
+
+	if change.Title.Before == change.Title.After {
+		t.Errorf("Expected title before and after to differ, both are %q", change.Title.After)
+	}
+
+	if change.Title.After != change.Element.Title {
+		t.Errorf("Expected title after %q to match element title %q", change.Title.After, change.Element.Title)
+	}
+
+	if len(change.FieldValues) != 0 {
+		t.Errorf("Expected no field value changes, got %d", len(change.FieldValues))
+	}
+
+	if change.Actor.URI != testUserID {
+		t.Errorf("Expected actor %q, got %q", testUserID, change.Actor.URI)
+	}
+
+	if change.Timestamp == "" {
+		t.Error("Expected timestamp to be non-empty")
+	}
+
+	if !strings.HasPrefix(change.ID, "event:") {
+		t.Errorf("Expected an event ID, got %q", change.ID)
+	}
+}
+
+func TestElementChangedSubscriptionGraphQLTransportWS(t *testing.T) {
+	conn := dialGraphQLTransportWS(t, map[string]any{middleware.AuthHeader: testUserID})
+	defer conn.Close()
+
+	var ack transportWSMessage
+	if err := conn.ReadJSON(&ack); err != nil {
+		t.Fatalf("Failed to read connection_ack: %v", err)
+	}
+	if ack.Type != "connection_ack" {
+		t.Fatalf("Expected connection_ack, got %q", ack.Type)
+	}
+
+	payload, _ := json.Marshal(graphql.RawParams{
+		Query:     elementChangedSubscription,
+		Variables: map[string]any{"uri": "element:test-4"},
+	})
+	if err := conn.WriteJSON(transportWSMessage{ID: "1", Type: "subscribe", Payload: payload}); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	resp := updateUntilReceived[elementChangedResponse](t, "element:test-4", func(v any) error {
+		for {
+			var msg transportWSMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				return err
+			}
+
+			switch msg.Type {
+			case "ping", "pong":
+				continue
+			case "next":
+				var result graphql.Response
+				if err := json.Unmarshal(msg.Payload, &result); err != nil {
+					return err
+				}
+				if len(result.Errors) > 0 {
+					return result.Errors
+				}
+				return json.Unmarshal(result.Data, v)
+			default:
+				return fmt.Errorf("unexpected message %q: %s", msg.Type, msg.Payload)
+			}
+		}
+	})
+
+	if resp.ElementChanged.Element.URI != "element:test-4" {
+		t.Errorf("Expected element URI 'element:test-4', got %q", resp.ElementChanged.Element.URI)
+	}
+
+	if resp.ElementChanged.Actor.URI != testUserID {
+		t.Errorf("Expected actor %q, got %q", testUserID, resp.ElementChanged.Actor.URI)
+	}
+}
+
+func TestGraphQLTransportWSMissingAuth(t *testing.T) {
+	conn := dialGraphQLTransportWS(t, nil)
+	defer conn.Close()
+
+	var msg transportWSMessage
+	if err := conn.ReadJSON(&msg); err == nil && msg.Type == "connection_ack" {
+		t.Error("Expected connection without X-User-ID to be rejected")
+	}
+}
+
+func TestElementChangedSubscriptionSSE(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resp := postSSE(t, ctx, testUserID)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected Content-Type text/event-stream, got %q", ct)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	update := updateUntilReceived[elementChangedResponse](t, "element:test-4", func(v any) error {
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return err
+			}
+
+			data, ok := strings.CutPrefix(strings.TrimSpace(line), "data: ")
+			if !ok {
+				continue
+			}
+
+			var result graphql.Response
+			if err := json.Unmarshal([]byte(data), &result); err != nil {
+				return err
+			}
+			if len(result.Errors) > 0 {
+				return result.Errors
+			}
+			return json.Unmarshal(result.Data, v)
+		}
+	})
+
+	if update.ElementChanged.Element.URI != "element:test-4" {
+		t.Errorf("Expected element URI 'element:test-4', got %q", update.ElementChanged.Element.URI)
+	}
+
+	if update.ElementChanged.Actor.URI != testUserID {
+		t.Errorf("Expected actor %q, got %q", testUserID, update.ElementChanged.Actor.URI)
+	}
+}
+
+func TestSSEMissingAuthHeader(t *testing.T) {
+	resp := postSSE(t, context.Background(), "")
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", resp.StatusCode)
+	}
+}
+
+type transportWSMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// dialGraphQLTransportWS opens a graphql-transport-ws connection to the test
+// server and sends connection_init with the given payload.
+func dialGraphQLTransportWS(t *testing.T, initPayload map[string]any) *websocket.Conn {
+	t.Helper()
+
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}}
+	url := strings.Replace(testServer.URL, "http://", "ws://", 1) + "/graphql"
+
+	conn, resp, err := dialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial websocket: %v", err)
+	}
+	resp.Body.Close()
+
+	if conn.Subprotocol() != "graphql-transport-ws" {
+		t.Fatalf("Expected graphql-transport-ws subprotocol, got %q", conn.Subprotocol())
+	}
+
+	init := transportWSMessage{Type: "connection_init"}
+	if initPayload != nil {
+		init.Payload, _ = json.Marshal(initPayload)
+	}
+	if err := conn.WriteJSON(init); err != nil {
+		t.Fatalf("Failed to send connection_init: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	return conn
+}
+
+// postSSE starts an elementChanged subscription over Server-Sent Events.
+func postSSE(t *testing.T, ctx context.Context, userID string) *http.Response {
+	t.Helper()
+
+	body, _ := json.Marshal(graphql.RawParams{
+		Query:     elementChangedSubscription,
+		Variables: map[string]any{"uri": "element:test-4"},
+	})
+
+	req, err := http.NewRequestWithContext(ctx, "POST", testServer.URL+"/graphql", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	if userID != "" {
+		req.Header.Set(middleware.AuthHeader, userID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to execute request: %v", err)
+	}
+	return resp
+}
@@ -0,0 +1,186 @@
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+func typeSchemaRequest(t *testing.T, method, tenantURI string, body any) *http.Response {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Failed to marshal request: %v", err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+
+	req, err := http.NewRequest(method, testServer.URL+"/tenants/"+tenantURI+"/graphql", reader)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-ID", testUserID)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to execute request: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestTypeSchemaSDL(t *testing.T) {
+	resp := typeSchemaRequest(t, http.MethodGet, "tenant:test-1", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	sdl, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read schema: %v", err)
+	}
+
+	for _, want := range []string{
+		"type TestType {",
+		"testSelectField: TestTypeTestSelectField",
+		"testNumberField_gt: Float",
+		"enum TestTypeTestSelectField {",
+		"testTypes(filter: TestTypeFilter, first: Int, after: ID): [TestType!]!",
+	} {
+		if !strings.Contains(string(sdl), want) {
+			t.Errorf("Expected schema to contain %q, got:\n%s", want, sdl)
+		}
+	}
+}
+
+func TestTypeSchemaQuery(t *testing.T) {
+	resp := typeSchemaRequest(t, http.MethodPost, "tenant:test-1", graphql.RawParams{
+		Query: `
+			query Filtered($min: Float) {
+				testTypes(filter: {testSelectField: OPTION1, testNumberField_gt: $min}) {
+					uri
+					testSelectField
+					testNumberField
+				}
+			}
+		`,
+		Variables: map[string]any{"min": 100},
+	})
+
+	var gqlResp graphql.Response
+	if err := json.NewDecoder(resp.Body).Decode(&gqlResp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(gqlResp.Errors) > 0 {
+		t.Fatalf("GraphQL errors: %v", gqlResp.Errors)
+	}
+
+	data := struct {
+		TestTypes []struct {
+			URI             string  `json:"uri"`
+			TestSelectField string  `json:"testSelectField"`
+			TestNumberField float64 `json:"testNumberField"`
+		} `json:"testTypes"`
+	}{}
+	if err := json.Unmarshal(gqlResp.Data, &data); err != nil {
+		t.Fatalf("Failed to unmarshal data: %v", err)
+	}
+
+	if len(data.TestTypes) != 1 {
+		t.Fatalf("Expected 1 element, got %d", len(data.TestTypes))
+	}
+	elem := data.TestTypes[0]
+	if elem.URI != "element:test-4" || elem.TestSelectField != "OPTION1" || elem.TestNumberField != 555 {
+		t.Errorf("Unexpected element %+v", elem)
+	}
+}
+
+func TestTypeSchemaMergesFields(t *testing.T) {
+	resp := typeSchemaRequest(t, http.MethodPost, "tenant:test-1", graphql.RawParams{
+		Query: `
+			query {
+				a: testTypes(filter: {testSelectField: OPTION1}) { uri }
+				a: testTypes(filter: {testSelectField: OPTION1}) { testNumberField }
+			}
+		`,
+	})
+
+	var gqlResp graphql.Response
+	if err := json.NewDecoder(resp.Body).Decode(&gqlResp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(gqlResp.Errors) > 0 {
+		t.Fatalf("GraphQL errors: %v", gqlResp.Errors)
+	}
+
+	data := struct {
+		A []struct {
+			URI             string  `json:"uri"`
+			TestNumberField float64 `json:"testNumberField"`
+		} `json:"a"`
+	}{}
+	if err := json.Unmarshal(gqlResp.Data, &data); err != nil {
+		t.Fatalf("Failed to unmarshal data: %v", err)
+	}
+	if len(data.A) != 1 || data.A[0].URI != "element:test-4" || data.A[0].TestNumberField != 555 {
+		t.Errorf("Expected the selections of both fields, got %+v", data.A)
+	}
+}
+
+func TestTypeSchemaFollowsFieldChanges(t *testing.T) {
+	ctx := context.Background()
+
+	sdl := func() string {
+		t.Helper()
+		resp := typeSchemaRequest(t, http.MethodGet, "tenant:test-1", nil)
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("Failed to read schema: %v", err)
+		}
+		return string(body)
+	}
+
+	const want = "testSchemaField: String"
+	if strings.Contains(sdl(), want) {
+		t.Fatalf("Expected no %q before the field is added", want)
+	}
+
+	if _, err := testDB.Exec(ctx, fmt.Sprintf(
+		`INSERT INTO fields (uri, name, field_type, type_uri, creation_date, author, options, required) VALUES ('field:test-schema', 'Test Schema Field', 'text', 'type:test-1', %d, '%s', null, false)`,
+		time.Now().UnixMilli(), testUserID,
+	)); err != nil {
+		t.Fatalf("Failed to insert field: %v", err)
+	}
+	t.Cleanup(func() {
+		testDB.Exec(ctx, `DELETE FROM fields WHERE uri = 'field:test-schema'`)
+	})
+	if !strings.Contains(sdl(), want) {
+		t.Errorf("Expected %q once the field is added", want)
+	}
+
+	if _, err := testDB.Exec(ctx, `UPDATE fields SET name = 'Test Renamed Field' WHERE uri = 'field:test-schema'`); err != nil {
+		t.Fatalf("Failed to rename field: %v", err)
+	}
+	if got := sdl(); strings.Contains(got, want) || !strings.Contains(got, "testRenamedField: String") {
+		t.Errorf("Expected the schema to follow the renamed field, got:\n%s", got)
+	}
+}
+
+func TestTypeSchemaRequiresTenantMembership(t *testing.T) {
+	resp := typeSchemaRequest(t, http.MethodGet, "tenant:acme", nil)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,64 @@
+package e2e
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestElementFieldTypedValue(t *testing.T) {
+	resp := executeGraphQL(t, `
+		query Element($uri: ID!) {
+			element(uri: $uri) {
+				fieldValues {
+					field { uri }
+					typedValue {
+						__typename
+						... on TextValue { text }
+						... on NumberValue { number }
+						... on SelectValue { option }
+					}
+				}
+			}
+		}
+	`, map[string]any{"uri": "element:test-1"})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("GraphQL errors: %v", resp.Errors)
+	}
+
+	data := struct {
+		Element struct {
+			FieldValues []struct {
+				Field struct {
+					URI string `json:"uri"`
+				} `json:"field"`
+				TypedValue map[string]any `json:"typedValue"`
+			} `json:"fieldValues"`
+		} `json:"element"`
+	}{}
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		t.Fatalf("Failed to unmarshal data: %v", err)
+	}
+
+	expected := map[string]map[string]any{
+		"field:test-1": {"__typename": "TextValue", "text": "Hello World"},
+		"field:test-2": {"__typename": "SelectValue", "option": "option1"},
+		"field:test-3": {"__typename": "NumberValue", "number": 42.5},
+	}
+
+	if len(data.Element.FieldValues) != len(expected) {
+		t.Fatalf("Expected %d field values, got %d", len(expected), len(data.Element.FieldValues))
+	}
+
+	for _, fv := range data.Element.FieldValues {
+		want, ok := expected[fv.Field.URI]
+		if !ok {
+			t.Errorf("Unexpected field value for %s", fv.Field.URI)
+			continue
+		}
+		for k, v := range want {
+			if fv.TypedValue[k] != v {
+				t.Errorf("Expected %s %s to be %v, got %v", fv.Field.URI, k, v, fv.TypedValue[k])
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+type updateWhereResult struct {
+	AffectedCount int `json:"affectedCount"`
+	Sample        []struct {
+		URI string `json:"uri"`
+	} `json:"sample"`
+}
+
+func TestUpdateElementsWhere(t *testing.T) {
+	ctx := context.Background()
+
+	createTestElement(t, "element:test-18", "Test Element 18", "Where text")
+	createTestElement(t, "element:test-19", "Test Element 19", "Where text")
+
+	updateWhere := func(t *testing.T, spaceURI string, dryRun bool) (*updateWhereResult, gqlerror.List) {
+		t.Helper()
+
+		resp := executeGraphQL(t, `
+			mutation UpdateWhere($spaceUri: ID!, $filter: ElementFilter, $set: [FieldValueInput!]!, $dryRun: Boolean) {
+				updateElementsWhere(spaceUri: $spaceUri, typeUri: "type:test-1", filter: $filter, set: $set, dryRun: $dryRun) {
+					affectedCount
+					sample { uri }
+				}
+			}
+		`, map[string]any{
+			"spaceUri": spaceURI,
+			"dryRun":   dryRun,
+			"filter": map[string]any{"fieldValueFilter": map[string]any{
+				"fieldUri":  "field:test-1",
+				"value":     "Where text",
+				"valueType": "TEXT",
+			}},
+			"set": []any{map[string]any{"fieldUri": "field:test-2", "value": "option2"}},
+		})
+
+		if len(resp.Errors) > 0 {
+			return nil, resp.Errors
+		}
+
+		data := struct {
+			UpdateElementsWhere updateWhereResult `json:"updateElementsWhere"`
+		}{}
+		if err := json.Unmarshal(resp.Data, &data); err != nil {
+			t.Fatalf("Failed to unmarshal data: %v", err)
+		}
+		result := data.UpdateElementsWhere
+		return &result, nil
+	}
+
+	updated := func() int {
+		var count int
+		testDB.QueryRow(ctx, `SELECT count(*) FROM element_field_values
+			WHERE element_uri IN ('element:test-18', 'element:test-19') AND field_uri = 'field:test-2' AND value_text = 'option2'`).Scan(&count)
+		return count
+	}
+
+	t.Run("dry runs report the matches without writing", func(t *testing.T) {
+		result, errs := updateWhere(t, "space:test-1", true)
+		if len(errs) > 0 {
+			t.Fatalf("GraphQL errors: %v", errs)
+		}
+		if result.AffectedCount != 2 || len(result.Sample) != 2 {
+			t.Errorf("Expected 2 affected elements in the sample, got %+v", result)
+		}
+		if got := updated(); got != 0 {
+			t.Errorf("Expected nothing to be written, got %d updated values", got)
+		}
+	})
+
+	t.Run("updates every matching element", func(t *testing.T) {
+		result, errs := updateWhere(t, "space:test-1", false)
+		if len(errs) > 0 {
+			t.Fatalf("GraphQL errors: %v", errs)
+		}
+		if result.AffectedCount != 2 || len(result.Sample) != 0 {
+			t.Errorf("Expected 2 affected elements and no sample, got %+v", result)
+		}
+		if got := updated(); got != 2 {
+			t.Errorf("Expected 2 updated values, got %d", got)
+		}
+	})
+
+	t.Run("spaces without access are rejected", func(t *testing.T) {
+		if _, errs := updateWhere(t, "space:test-2", false); len(errs) == 0 {
+			t.Error("Expected an error for a space the user has no access to")
+		}
+	})
+}
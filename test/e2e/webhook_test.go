@@ -0,0 +1,230 @@
+package e2e
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/bamdadam/backend/src/middleware"
+	"github.com/bamdadam/backend/src/server"
+	"github.com/bamdadam/backend/src/service"
+)
+
+type webhookCall struct {
+	header http.Header
+	body   []byte
+}
+
+type webhookDeliveriesResponse struct {
+	WebhookDeliveries struct {
+		Edges []struct {
+			Node struct {
+				ID             string `json:"id"`
+				EventID        string `json:"eventId"`
+				Event          string `json:"event"`
+				Status         string `json:"status"`
+				ResponseStatus *int   `json:"responseStatus"`
+			} `json:"node"`
+		} `json:"edges"`
+	} `json:"webhookDeliveries"`
+}
+
+func TestWebhooks(t *testing.T) {
+	calls := make(chan webhookCall, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		calls <- webhookCall{header: r.Header, body: body}
+	}))
+	defer receiver.Close()
+
+	createTestElement(t, "element:test-22", "Test Element 22", "Webhook text")
+
+	resp := executeGraphQL(t, `
+		mutation CreateWebhook($url: String!) {
+			createWebhook(spaceUri: "space:test-1", typeUri: "type:test-1", url: $url, events: [ELEMENT_UPDATED], secret: "test-secret") {
+				uri
+				events
+			}
+		}
+	`, map[string]any{"url": receiver.URL})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("GraphQL errors: %v", resp.Errors)
+	}
+
+	created := struct {
+		CreateWebhook struct {
+			URI    string   `json:"uri"`
+			Events []string `json:"events"`
+		} `json:"createWebhook"`
+	}{}
+	if err := json.Unmarshal(resp.Data, &created); err != nil {
+		t.Fatalf("Failed to unmarshal data: %v", err)
+	}
+	webhookURI := created.CreateWebhook.URI
+
+	receive := func(t *testing.T) webhookCall {
+		t.Helper()
+		select {
+		case call := <-calls:
+			return call
+		case <-time.After(10 * time.Second):
+			t.Fatal("Timed out waiting for the webhook to be called")
+			return webhookCall{}
+		}
+	}
+
+	deliveries := func(t *testing.T) webhookDeliveriesResponse {
+		t.Helper()
+		resp := executeGraphQL(t, `
+			query Deliveries($uri: ID!) {
+				webhookDeliveries(webhookUri: $uri) {
+					edges { node { id eventId event status responseStatus } }
+				}
+			}
+		`, map[string]any{"uri": webhookURI})
+		if len(resp.Errors) > 0 {
+			t.Fatalf("GraphQL errors: %v", resp.Errors)
+		}
+		var data webhookDeliveriesResponse
+		if err := json.Unmarshal(resp.Data, &data); err != nil {
+			t.Fatalf("Failed to unmarshal data: %v", err)
+		}
+		return data
+	}
+
+	var eventID string
+
+	t.Run("updates are delivered signed", func(t *testing.T) {
+		resp := executeGraphQL(t, `mutation { updateElementTitle(input: { uri: "element:test-22", title: "Webhook title" }) { uri } }`, nil)
+		if len(resp.Errors) > 0 {
+			t.Fatalf("GraphQL errors: %v", resp.Errors)
+		}
+
+		call := receive(t)
+		timestamp, _ := strconv.ParseInt(call.header.Get(service.WebhookTimestampHeader), 10, 64)
+		if got, want := call.header.Get(service.WebhookSignatureHeader), service.SignWebhookPayload("test-secret", timestamp, call.body); got != want {
+			t.Errorf("Expected signature %s, got %s", want, got)
+		}
+		if got := call.header.Get(service.WebhookEventHeader); got != "element.updated" {
+			t.Errorf("Expected event element.updated, got %s", got)
+		}
+
+		payload := struct {
+			ID      string `json:"id"`
+			Element struct {
+				URI   string `json:"uri"`
+				Title string `json:"title"`
+			} `json:"element"`
+		}{}
+		if err := json.Unmarshal(call.body, &payload); err != nil {
+			t.Fatalf("Failed to unmarshal payload: %v", err)
+		}
+		if payload.Element.URI != "element:test-22" || payload.Element.Title != "Webhook title" {
+			t.Errorf("Unexpected payload: %s", call.body)
+		}
+		eventID = payload.ID
+	})
+
+	t.Run("deliveries are logged", func(t *testing.T) {
+		var data webhookDeliveriesResponse
+		deadline := time.Now().Add(10 * time.Second)
+		for time.Now().Before(deadline) {
+			data = deliveries(t)
+			if len(data.WebhookDeliveries.Edges) > 0 && data.WebhookDeliveries.Edges[0].Node.Status != "PENDING" {
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		if len(data.WebhookDeliveries.Edges) != 1 {
+			t.Fatalf("Expected 1 delivery, got %d", len(data.WebhookDeliveries.Edges))
+		}
+		node := data.WebhookDeliveries.Edges[0].Node
+		if node.Status != "SUCCEEDED" || node.ResponseStatus == nil || *node.ResponseStatus != http.StatusOK {
+			t.Errorf("Expected a successful delivery, got %+v", node)
+		}
+		if node.EventID != eventID || node.Event != "ELEMENT_UPDATED" {
+			t.Errorf("Expected event %s, got %+v", eventID, node)
+		}
+	})
+
+	t.Run("deliveries can be sent again", func(t *testing.T) {
+		id := deliveries(t).WebhookDeliveries.Edges[0].Node.ID
+		resp := executeGraphQL(t, `mutation Redeliver($id: ID!) { redeliverWebhookDelivery(id: $id) { id status } }`, map[string]any{"id": id})
+		if len(resp.Errors) > 0 {
+			t.Fatalf("GraphQL errors: %v", resp.Errors)
+		}
+
+		call := receive(t)
+		if got := call.header.Get(service.WebhookEventIDHeader); got != eventID {
+			t.Errorf("Expected event ID %s, got %s", eventID, got)
+		}
+	})
+
+	t.Run("spaces without write permission are rejected", func(t *testing.T) {
+		resp := executeGraphQL(t, `
+			mutation {
+				createWebhook(spaceUri: "space:test-2", url: "https://example.com", events: [ELEMENT_CREATED], secret: "test-secret") { uri }
+			}
+		`, nil)
+		if len(resp.Errors) == 0 {
+			t.Error("Expected an error for a space the user has no access to")
+		}
+	})
+
+	t.Run("webhooks can be deleted", func(t *testing.T) {
+		resp := executeGraphQL(t, `mutation Delete($uri: ID!) { deleteWebhook(uri: $uri) }`, map[string]any{"uri": webhookURI})
+		if len(resp.Errors) > 0 {
+			t.Fatalf("GraphQL errors: %v", resp.Errors)
+		}
+
+		resp = executeGraphQL(t, `query { webhooks(spaceUri: "space:test-1") { uri } }`, nil)
+		if len(resp.Errors) > 0 {
+			t.Fatalf("GraphQL errors: %v", resp.Errors)
+		}
+		data := struct {
+			Webhooks []struct {
+				URI string `json:"uri"`
+			} `json:"webhooks"`
+		}{}
+		if err := json.Unmarshal(resp.Data, &data); err != nil {
+			t.Fatalf("Failed to unmarshal data: %v", err)
+		}
+		for _, w := range data.Webhooks {
+			if w.URI == webhookURI {
+				t.Error("Expected the webhook to be deleted")
+			}
+		}
+	})
+}
+
+func TestWebhookPrivateAddresses(t *testing.T) {
+	// unlike the test server, private networks are refused by default
+	c := client.New(middleware.Auth(server.NewGraphQLHandler(testDB, server.Options{})))
+
+	for _, url := range []string{
+		"http://127.0.0.1:5432/",
+		"http://localhost:8080/graphql",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.1/",
+		"http://[::1]/",
+	} {
+		t.Run(url, func(t *testing.T) {
+			var resp struct{}
+			err := c.Post(`
+				mutation CreateWebhook($url: String!) {
+					createWebhook(spaceUri: "space:test-1", url: $url, events: [ELEMENT_CREATED], secret: "test-secret") { uri }
+				}
+			`, &resp, client.Var("url", url), client.AddHeader(middleware.AuthHeader, testUserID))
+			if err == nil || !strings.Contains(err.Error(), "VALIDATION") {
+				t.Errorf("Expected a validation error, got %v", err)
+			}
+		})
+	}
+}